@@ -0,0 +1,566 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/olekukonko/tablewriter"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/prana/sqlexec"
+	"github.com/phogolabs/prana/sqlmigr"
+	"github.com/phogolabs/prana/sqlmodel"
+	"github.com/urfave/cli"
+)
+
+const consoleHelp = `\r <routine> [args]  run a routine with given positional or key=value arguments
+\d [table]           list the tables or describe given table
+\m                   show the migration status
+\f <format>          set the output format (table, csv, tsv, json, jsonl, markdown, vertical)
+\h [number]          show the history or run its entry with given number
+\?                   show this help
+\q                   quit the console`
+
+// SQLConsole provides an interactive console to the database.
+type SQLConsole struct {
+	db           *sqlx.DB
+	schema       sqlmodel.SchemaProvider
+	formatter    *sqlexec.Formatter
+	routineDir   string
+	migrationDir string
+	historyFile  string
+	history      []string
+	interactive  bool
+	writer       io.Writer
+	errWriter    io.Writer
+}
+
+// CreateCommand creates a cli.Command that can be used by cli.App.
+func (m *SQLConsole) CreateCommand() cli.Command {
+	return cli.Command{
+		Name:        "console",
+		Usage:       "Start an interactive SQL console for the database",
+		Description: "Start an interactive SQL console for the database. The statements end with semicolon and can span multiple lines. Type \\? for the list of commands",
+		Before:      m.before,
+		After:       m.after,
+		Action:      m.run,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:   "routine-dir",
+				Usage:  "path to the directory that contain the SQL routines",
+				EnvVar: "PRANA_ROUTINE_DIR",
+				Value:  "./database/routine",
+			},
+			cli.StringFlag{
+				Name:   "migration-dir",
+				Usage:  "path to the directory that contain the migrations",
+				EnvVar: "PRANA_MIGRATION_DIR",
+				Value:  "./database/migration",
+			},
+			cli.StringFlag{
+				Name:   "history-file",
+				Usage:  "path to the file that keeps the history of the console (default: $HOME/.prana_history)",
+				EnvVar: "PRANA_HISTORY_FILE",
+			},
+			cli.StringFlag{
+				Name:  "format, f",
+				Usage: "output format (table, csv, tsv, json, jsonl, markdown, vertical)",
+				Value: sqlexec.FormatTable,
+			},
+		},
+	}
+}
+
+func (m *SQLConsole) before(ctx *cli.Context) error {
+	var err error
+
+	if m.routineDir, err = filepath.Abs(ctx.String("routine-dir")); err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	if m.migrationDir, err = filepath.Abs(ctx.String("migration-dir")); err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	m.historyFile = ctx.String("history-file")
+
+	if m.historyFile == "" {
+		m.historyFile = filepath.Join(os.Getenv("HOME"), ".prana_history")
+	}
+
+	m.formatter = &sqlexec.Formatter{}
+
+	if err = m.format(ctx.String("format")); err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	if m.db, err = open(ctx); err != nil {
+		return err
+	}
+
+	model := &SQLModel{}
+
+	if m.schema, err = model.provider(m.db); err != nil {
+		m.db.Close()
+		return err
+	}
+
+	m.writer = os.Stdout
+	m.errWriter = os.Stderr
+
+	if info, err := os.Stdin.Stat(); err == nil {
+		m.interactive = info.Mode()&os.ModeCharDevice != 0
+	}
+
+	return nil
+}
+
+func (m *SQLConsole) after(ctx *cli.Context) error {
+	if m.db == nil {
+		return nil
+	}
+
+	if err := m.db.Close(); err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeCommand)
+	}
+
+	return nil
+}
+
+func (m *SQLConsole) run(ctx *cli.Context) error {
+	m.load()
+
+	if m.interactive {
+		fmt.Fprintf(m.writer, "Connected to %s. Type \\? for help.\n", m.db.DriverName())
+	}
+
+	var (
+		input     = m.input()
+		statement = []string{}
+	)
+
+	for {
+		prompt := "prana> "
+
+		if len(statement) > 0 {
+			prompt = "    -> "
+		}
+
+		line, err := input(prompt)
+
+		if err == errInterrupt {
+			// the interrupt discards the statement that is being typed
+			statement = []string{}
+			continue
+		}
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return cli.NewExitError(err.Error(), ErrCodeCommand)
+		}
+
+		line = strings.TrimSpace(line)
+
+		if len(statement) == 0 {
+			if line == "" {
+				continue
+			}
+
+			if strings.HasPrefix(line, "\\") {
+				if quit := m.command(line, true); quit {
+					return nil
+				}
+				continue
+			}
+		}
+
+		statement = append(statement, line)
+
+		if strings.HasSuffix(line, ";") {
+			m.statement(strings.Join(statement, "\n"), true)
+			statement = []string{}
+		}
+	}
+
+	// the input might end without semicolon
+	if len(statement) > 0 {
+		m.statement(strings.Join(statement, "\n"), true)
+	}
+
+	return nil
+}
+
+// input returns a function that reads the next line of the console. The
+// lines of a terminal can be edited and the history can be recalled with
+// the arrow keys.
+func (m *SQLConsole) input() func(prompt string) (string, error) {
+	reader := bufio.NewReader(os.Stdin)
+
+	if m.interactive {
+		fd := int(os.Stdin.Fd())
+
+		// the line editing is not supported by all platforms
+		if restore, err := makeRaw(fd); err == nil {
+			restore()
+
+			editor := &lineEditor{
+				fd:     fd,
+				reader: reader,
+				writer: m.writer,
+				history: func() []string {
+					return m.history
+				},
+			}
+
+			return editor.ReadLine
+		}
+	}
+
+	scanner := bufio.NewScanner(reader)
+
+	return func(prompt string) (string, error) {
+		m.prompt(prompt)
+
+		if scanner.Scan() {
+			return scanner.Text(), nil
+		}
+
+		if err := scanner.Err(); err != nil {
+			return "", err
+		}
+
+		return "", io.EOF
+	}
+}
+
+func (m *SQLConsole) prompt(text string) {
+	if m.interactive {
+		fmt.Fprint(m.writer, text)
+	}
+}
+
+// command executes a meta command. It returns true if the console should
+// quit.
+func (m *SQLConsole) command(line string, record bool) bool {
+	args := fields(line)
+	name, args := args[0], args[1:]
+
+	if record && name != "\\h" {
+		m.record(line)
+	}
+
+	var err error
+
+	switch name {
+	case "\\q":
+		return true
+	case "\\?":
+		fmt.Fprintln(m.writer, consoleHelp)
+	case "\\r":
+		err = m.routine(args)
+	case "\\d":
+		err = m.describe(args)
+	case "\\m":
+		err = m.migrations()
+	case "\\f":
+		if len(args) != 1 {
+			err = fmt.Errorf("\\f expects a format")
+		} else {
+			err = m.format(args[0])
+		}
+	case "\\h":
+		err = m.replay(args)
+	default:
+		err = fmt.Errorf("unknown command '%s'. Type \\? for help", name)
+	}
+
+	if err != nil {
+		fmt.Fprintf(m.errWriter, "ERROR: %v\n", err)
+	}
+
+	return false
+}
+
+func (m *SQLConsole) statement(query string, record bool) {
+	if record {
+		m.record(query)
+	}
+
+	if err := m.execute(query); err != nil {
+		fmt.Fprintf(m.errWriter, "ERROR: %v\n", err)
+	}
+}
+
+func (m *SQLConsole) execute(query string) error {
+	routine := &sqlexec.Routine{Query: query}
+
+	if !routine.IsQuery() {
+		result, err := m.db.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		return m.formatter.WriteResult(m.writer, result)
+	}
+
+	rows, err := m.db.Queryx(query)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+	return m.formatter.Write(m.writer, rows)
+}
+
+func (m *SQLConsole) routine(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("\\r expects a routine name")
+	}
+
+	name, values := args[0], args[1:]
+
+	runner := &sqlexec.Runner{
+		FileSystem: parcello.Dir(m.routineDir),
+		DB:         m.db,
+	}
+
+	routine, err := runner.Routine(name)
+	if err != nil {
+		return err
+	}
+
+	// the database infers the types of the params that are not declared
+	if routine, err = sqlexec.DescribeParams(m.db, routine); err != nil {
+		return err
+	}
+
+	named, ok, err := namedParams(routine, values)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		arg, err := routine.ParseNamedParams(named)
+		if err != nil {
+			return err
+		}
+
+		if !routine.IsQuery() {
+			result, err := runner.ExecNamed(name, arg)
+			if err != nil {
+				return err
+			}
+
+			return m.formatter.WriteResult(m.writer, result)
+		}
+
+		rows, err := runner.RunNamed(name, arg)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+		return m.formatter.Write(m.writer, rows)
+	}
+
+	params, err := routine.ParseParams(values)
+	if err != nil {
+		return err
+	}
+
+	if !routine.IsQuery() {
+		result, err := runner.Exec(name, params...)
+		if err != nil {
+			return err
+		}
+
+		return m.formatter.WriteResult(m.writer, result)
+	}
+
+	rows, err := runner.Run(name, params...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+	return m.formatter.Write(m.writer, rows)
+}
+
+func (m *SQLConsole) describe(args []string) error {
+	if len(args) == 0 {
+		tables, err := m.schema.Tables("")
+		if err != nil {
+			return err
+		}
+
+		table := tablewriter.NewWriter(m.writer)
+		table.SetHeader([]string{"Table"})
+
+		for _, name := range tables {
+			table.Append([]string{name})
+		}
+
+		table.Render()
+		return nil
+	}
+
+	schema, err := m.schema.Schema("", args...)
+	if err != nil {
+		return err
+	}
+
+	for _, definition := range schema.Tables {
+		fmt.Fprintf(m.writer, "Table '%s'\n", definition.Name)
+
+		table := tablewriter.NewWriter(m.writer)
+		table.SetHeader([]string{"Column", "Type", "Nullable", "Primary Key", "Go Type"})
+
+		for _, column := range definition.Columns {
+			table.Append([]string{
+				column.Name,
+				column.Type.DBType(),
+				strconv.FormatBool(column.Type.IsNullable),
+				strconv.FormatBool(column.Type.IsPrimaryKey),
+				column.ScanType,
+			})
+		}
+
+		table.Render()
+	}
+
+	return nil
+}
+
+func (m *SQLConsole) migrations() error {
+	executor := &sqlmigr.Executor{
+		Provider: &sqlmigr.Provider{
+			FileSystem: parcello.Dir(m.migrationDir),
+			DB:         m.db,
+		},
+	}
+
+	migrations, err := executor.Migrations()
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = fmt.Errorf("Directory '%s' does not exist", m.migrationDir)
+		}
+		return err
+	}
+
+	sqlmigr.Ftable(m.writer, migrations)
+	return nil
+}
+
+func (m *SQLConsole) format(name string) error {
+	switch name {
+	case sqlexec.FormatTable, sqlexec.FormatCSV, sqlexec.FormatTSV, sqlexec.FormatJSON,
+		sqlexec.FormatJSONL, sqlexec.FormatMarkdown, sqlexec.FormatVertical:
+		m.formatter.Format = name
+		return nil
+	default:
+		return fmt.Errorf("Unsupported format '%s'", name)
+	}
+}
+
+func (m *SQLConsole) replay(args []string) error {
+	if len(args) == 0 {
+		for index, entry := range m.history {
+			fmt.Fprintf(m.writer, "%5d  %s\n", index+1, strings.Replace(entry, "\n", " ", -1))
+		}
+		return nil
+	}
+
+	index, err := strconv.Atoi(args[0])
+	if err != nil || index < 1 || index > len(m.history) {
+		return fmt.Errorf("history entry '%s' not found", args[0])
+	}
+
+	entry := m.history[index-1]
+	fmt.Fprintln(m.writer, entry)
+
+	m.record(entry)
+
+	if strings.HasPrefix(entry, "\\") {
+		m.command(entry, false)
+		return nil
+	}
+
+	m.statement(entry, false)
+	return nil
+}
+
+// load loads the history from the history file
+func (m *SQLConsole) load() {
+	data, err := ioutil.ReadFile(m.historyFile)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range strings.Split(string(data), "\x00\n") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			m.history = append(m.history, entry)
+		}
+	}
+}
+
+// record appends the entry to the history and the history file
+func (m *SQLConsole) record(entry string) {
+	m.history = append(m.history, entry)
+
+	file, err := os.OpenFile(m.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+
+	// the entries are separated by NUL because they can span multiple lines
+	fmt.Fprintf(file, "%s\x00\n", entry)
+	file.Close()
+}
+
+// fields splits the line by white space. The quoted values can contain white
+// space.
+func fields(line string) []string {
+	var (
+		result  = []string{}
+		current = &bytes.Buffer{}
+		quote   rune
+		found   bool
+	)
+
+	for _, char := range line {
+		switch {
+		case quote != 0 && char == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(char)
+		case char == '\'' || char == '"':
+			quote = char
+			found = true
+		case char == ' ' || char == '\t':
+			if found {
+				result = append(result, current.String())
+				current.Reset()
+				found = false
+			}
+		default:
+			current.WriteRune(char)
+			found = true
+		}
+	}
+
+	if found {
+		result = append(result, current.String())
+	}
+
+	return result
+}
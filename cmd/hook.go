@@ -23,6 +23,8 @@ const (
 	ErrCodeCommand = 104
 	// ErrCodeSchema when the SQL schema operation fails.
 	ErrCodeSchema = 105
+	// ErrCodeSeed when the seed operation fails.
+	ErrCodeSeed = 106
 )
 
 type logHandler struct {
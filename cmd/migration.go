@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,6 +14,12 @@ import (
 	"github.com/urfave/cli"
 )
 
+var moduleFlag = cli.StringFlag{
+	Name:   "module, m",
+	Usage:  "name of the module whose migrations are in the sub-directory of the migration directory",
+	EnvVar: "PRANA_MIGRATION_MODULE",
+}
+
 // SQLMigration provides a subcommands to work with SQL migrations.
 type SQLMigration struct {
 	executor *sqlmigr.Executor
@@ -43,6 +50,9 @@ func (m *SQLMigration) CreateCommand() cli.Command {
 				Usage:       "Setup the migration for the current project",
 				Description: "Configure the current project by creating database directory hierarchy and initial migration",
 				Action:      m.setup,
+				Flags: []cli.Flag{
+					moduleFlag,
+				},
 			},
 			{
 				Name:        "create",
@@ -50,6 +60,9 @@ func (m *SQLMigration) CreateCommand() cli.Command {
 				Description: "Create a new migration file for the given name, and the current timestamp as the version in database/migration directory",
 				ArgsUsage:   "[name]",
 				Action:      m.create,
+				Flags: []cli.Flag{
+					moduleFlag,
+				},
 			},
 			{
 				Name:   "run",
@@ -61,6 +74,20 @@ func (m *SQLMigration) CreateCommand() cli.Command {
 						Usage: "Number of migrations to be executed. Negative number will run all",
 						Value: -1,
 					},
+					moduleFlag,
+					cli.StringSliceFlag{
+						Name:  "tenant-schema, s",
+						Usage: "PostgreSQL schema of a tenant that should be migrated",
+					},
+					cli.StringFlag{
+						Name:  "tenant-query, q",
+						Usage: "SQL query that returns the PostgreSQL schemas of the tenants that should be migrated",
+					},
+					cli.IntFlag{
+						Name:  "concurrency",
+						Usage: "Number of tenant schemas migrated at the same time",
+						Value: 4,
+					},
 				},
 			},
 			{
@@ -73,17 +100,24 @@ func (m *SQLMigration) CreateCommand() cli.Command {
 						Usage: "Number of migrations to be reverted. Negative number will revert all",
 						Value: -1,
 					},
+					moduleFlag,
 				},
 			},
 			{
 				Name:   "reset",
 				Usage:  "Revert and re-run all migrations",
 				Action: m.reset,
+				Flags: []cli.Flag{
+					moduleFlag,
+				},
 			},
 			{
 				Name:   "status",
 				Usage:  "Show all migrations, marking those that have been applied",
 				Action: m.status,
+				Flags: []cli.Flag{
+					moduleFlag,
+				},
 			},
 		},
 	}
@@ -128,7 +162,18 @@ func (m *SQLMigration) after(ctx *cli.Context) error {
 }
 
 func (m *SQLMigration) setup(ctx *cli.Context) error {
-	if err := m.executor.Setup(); err != nil {
+	if name := ctx.String("module"); name != "" {
+		if err := m.setupModule(name); err != nil {
+			return cli.NewExitError(err.Error(), ErrCodeMigration)
+		}
+	}
+
+	executor, err := m.executorOf(ctx)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeMigration)
+	}
+
+	if err := executor.Setup(); err != nil {
 		if os.IsExist(err) {
 			return nil
 		}
@@ -136,10 +181,22 @@ func (m *SQLMigration) setup(ctx *cli.Context) error {
 		return cli.NewExitError(err.Error(), ErrCodeMigration)
 	}
 
-	log.Infof("Setup project directory at: '%s'", m.dir)
+	log.Infof("Setup project directory at: '%s'", m.dirOf(ctx))
 	return nil
 }
 
+// setupModule writes the definition of the module unless it exists
+func (m *SQLMigration) setupModule(name string) error {
+	if sqlmigr.IsModule(parcello.Dir(m.dir), name) {
+		return nil
+	}
+
+	return sqlmigr.WriteModule(&sqlmigr.Module{
+		Name:       name,
+		FileSystem: parcello.Dir(filepath.Join(m.dir, name)),
+	})
+}
+
 func (m *SQLMigration) create(ctx *cli.Context) error {
 	args := ctx.Args()
 
@@ -147,21 +204,96 @@ func (m *SQLMigration) create(ctx *cli.Context) error {
 		return cli.NewExitError("Create command expects a single argument", ErrCodeMigration)
 	}
 
-	item, err := m.executor.Create(args[0])
+	executor, err := m.executorOf(ctx)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeMigration)
+	}
+
+	item, err := executor.Create(args[0])
 	if err != nil {
 		return cli.NewExitError(err.Error(), ErrCodeMigration)
 	}
 
-	log.Infof("Created migration at: '%s'", filepath.Join(m.dir, item.Filenames()[0]))
+	log.Infof("Created migration at: '%s'", filepath.Join(m.dirOf(ctx), item.Filenames()[0]))
 	return nil
 }
 
 func (m *SQLMigration) run(ctx *cli.Context) error {
 	count := ctx.Int("count")
 
+	if len(ctx.StringSlice("tenant-schema")) > 0 || ctx.String("tenant-query") != "" {
+		return m.runTenants(ctx)
+	}
+
+	if name := ctx.String("module"); name != "" {
+		modules, err := m.modules(name)
+		if err != nil {
+			return cli.NewExitError(err.Error(), ErrCodeMigration)
+		}
+
+		if _, err = modules.Run(name, count); err != nil {
+			err = m.errf(ctx, err)
+			return cli.NewExitError(err.Error(), ErrCodeMigration)
+		}
+
+		return nil
+	}
+
 	_, err := m.executor.Run(count)
 	if err != nil {
-		err = m.errf(err)
+		err = m.errf(ctx, err)
+		return cli.NewExitError(err.Error(), ErrCodeMigration)
+	}
+
+	// the count limits the project migrations only
+	if count >= 0 {
+		return nil
+	}
+
+	modules, err := m.modules("")
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeMigration)
+	}
+
+	if _, err = modules.RunAll(); err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeMigration)
+	}
+
+	return nil
+}
+
+func (m *SQLMigration) runTenants(ctx *cli.Context) error {
+	if ctx.String("module") != "" {
+		return cli.NewExitError("The module and tenant flags cannot be used together", ErrCodeArg)
+	}
+
+	if m.db.DriverName() != "postgres" {
+		return cli.NewExitError("Tenant schemas are supported only by PostgreSQL", ErrCodeArg)
+	}
+
+	schemas := ctx.StringSlice("tenant-schema")
+
+	if query := ctx.String("tenant-query"); query != "" {
+		names, err := sqlmigr.Schemas(m.db, query)
+		if err != nil {
+			return cli.NewExitError(err.Error(), ErrCodeMigration)
+		}
+
+		schemas = append(schemas, names...)
+	}
+
+	executor := &sqlmigr.TenantExecutor{
+		Logger:      log.Log,
+		FileSystem:  parcello.Dir(m.dir),
+		Connector:   sqlmigr.SearchPath(ctx.GlobalString("database-url")),
+		Concurrency: ctx.Int("concurrency"),
+	}
+
+	results := executor.Run(schemas, ctx.Int("count"))
+	sqlmigr.Ftenants(os.Stdout, results)
+
+	if err := results.Err(); err != nil {
+		log.Infof("Run the migration for the failed schemas again to resume: %s", strings.Join(results.Failed(), ", "))
 		return cli.NewExitError(err.Error(), ErrCodeMigration)
 	}
 
@@ -171,9 +303,13 @@ func (m *SQLMigration) run(ctx *cli.Context) error {
 func (m *SQLMigration) revert(ctx *cli.Context) error {
 	count := ctx.Int("count")
 
-	_, err := m.executor.Revert(count)
+	executor, err := m.executorOf(ctx)
 	if err != nil {
-		err = m.errf(err)
+		return cli.NewExitError(err.Error(), ErrCodeMigration)
+	}
+
+	if _, err = executor.Revert(count); err != nil {
+		err = m.errf(ctx, err)
 		return cli.NewExitError(err.Error(), ErrCodeMigration)
 	}
 
@@ -181,15 +317,20 @@ func (m *SQLMigration) revert(ctx *cli.Context) error {
 }
 
 func (m *SQLMigration) reset(ctx *cli.Context) error {
-	_, err := m.executor.RevertAll()
+	executor, err := m.executorOf(ctx)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeMigration)
+	}
+
+	_, err = executor.RevertAll()
 	if err != nil {
-		err = m.errf(err)
+		err = m.errf(ctx, err)
 		return cli.NewExitError(err.Error(), ErrCodeMigration)
 	}
 
-	_, err = m.executor.RunAll()
+	_, err = executor.RunAll()
 	if err != nil {
-		err = m.errf(err)
+		err = m.errf(ctx, err)
 		return cli.NewExitError(err.Error(), ErrCodeMigration)
 	}
 
@@ -197,7 +338,47 @@ func (m *SQLMigration) reset(ctx *cli.Context) error {
 }
 
 func (m *SQLMigration) status(ctx *cli.Context) error {
-	migrations, err := m.executor.Migrations()
+	executor, err := m.executorOf(ctx)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeMigration)
+	}
+
+	if err := m.print(ctx, executor); err != nil {
+		return err
+	}
+
+	if ctx.String("module") != "" {
+		return nil
+	}
+
+	modules, err := m.modules("")
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeMigration)
+	}
+
+	ordered, err := modules.Order()
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeMigration)
+	}
+
+	for _, module := range ordered {
+		executor, err := modules.Executor(module.Name)
+		if err != nil {
+			return cli.NewExitError(err.Error(), ErrCodeMigration)
+		}
+
+		log.Infof("Migrations of module '%s'", module.Name)
+
+		if err := m.print(ctx, executor); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *SQLMigration) print(ctx *cli.Context, executor *sqlmigr.Executor) error {
+	migrations, err := executor.Migrations()
 	if err != nil {
 		return err
 	}
@@ -211,9 +392,72 @@ func (m *SQLMigration) status(ctx *cli.Context) error {
 	return nil
 }
 
-func (m *SQLMigration) errf(err error) error {
+func (m *SQLMigration) executorOf(ctx *cli.Context) (*sqlmigr.Executor, error) {
+	name := ctx.String("module")
+
+	if name == "" {
+		return m.executor, nil
+	}
+
+	modules, err := m.modules(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return modules.Executor(name)
+}
+
+// modules returns the executor of the modules in the migration directory. The
+// module with given name is included even if its directory has not been setup.
+func (m *SQLMigration) modules(name string) (*sqlmigr.ModuleExecutor, error) {
+	executor := &sqlmigr.ModuleExecutor{
+		Logger: log.Log,
+		DB:     m.db,
+	}
+
+	infos, err := ioutil.ReadDir(m.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	found := false
+
+	for _, info := range infos {
+		// the plain directories are part of the project migrations
+		if !info.IsDir() || !sqlmigr.IsModule(parcello.Dir(m.dir), info.Name()) {
+			continue
+		}
+
+		module, err := sqlmigr.ReadModule(info.Name(), parcello.Dir(filepath.Join(m.dir, info.Name())))
+		if err != nil {
+			return nil, err
+		}
+
+		found = found || module.Name == name
+		executor.Modules = append(executor.Modules, module)
+	}
+
+	// the module directory has not been setup yet
+	if name != "" && !found {
+		executor.Modules = append(executor.Modules, &sqlmigr.Module{
+			Name:       name,
+			FileSystem: parcello.Dir(filepath.Join(m.dir, name)),
+		})
+	}
+
+	return executor, nil
+}
+
+func (m *SQLMigration) dirOf(ctx *cli.Context) string {
+	if name := ctx.String("module"); name != "" {
+		return filepath.Join(m.dir, name)
+	}
+	return m.dir
+}
+
+func (m *SQLMigration) errf(ctx *cli.Context, err error) error {
 	if os.IsNotExist(err) {
-		err = fmt.Errorf("Directory '%s' does not exist", m.dir)
+		err = fmt.Errorf("Directory '%s' does not exist", m.dirOf(ctx))
 	}
 	return err
 }
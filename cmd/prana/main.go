@@ -38,11 +38,15 @@ func main() {
 	migration := &cmd.SQLMigration{}
 	routine := &cmd.SQLRoutine{}
 	model := &cmd.SQLModel{}
+	seed := &cmd.SQLSeed{}
+	console := &cmd.SQLConsole{}
 
 	commands := []cli.Command{
 		migration.CreateCommand(),
 		routine.CreateCommand(),
 		model.CreateCommand(),
+		seed.CreateCommand(),
+		console.CreateCommand(),
 	}
 
 	app := &cli.App{
@@ -1,18 +1,29 @@
 package cmd
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 
 	"github.com/apex/log"
 	"github.com/jmoiron/sqlx"
-	"github.com/olekukonko/tablewriter"
 	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/prana"
 	"github.com/phogolabs/prana/sqlexec"
+	"github.com/phogolabs/prana/sqlfixture"
+	"github.com/phogolabs/prana/sqlmodel"
+	"github.com/phogolabs/prana/sqltest"
 	"github.com/urfave/cli"
 )
 
+var namedParam = regexp.MustCompile(`^(\w+)=(.*)$`)
+
 // SQLRoutine provides a subcommands to work with SQL scripts and their
 // statements.
 type SQLRoutine struct {
@@ -80,6 +91,166 @@ func (m *SQLRoutine) CreateCommand() cli.Command {
 					},
 				},
 			},
+			{
+				Name:        "generate",
+				Usage:       "Generate a typed Golang function for each SQL command",
+				Description: "Generate a typed Golang function for each SQL command by inspecting its parameters and result columns",
+				Action:      m.generate,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "schema-name, s",
+						Usage: "name of the database schema",
+						Value: "",
+					},
+					cli.StringFlag{
+						Name:  "package-dir, p",
+						Usage: "path to the model package, where the source code will be generated",
+						Value: "./database/model",
+					},
+					cli.BoolTFlag{
+						Name:  "include-docs, i",
+						Usage: "include API documentation in generated source code",
+					},
+				},
+			},
+			{
+				Name:        "list",
+				Usage:       "List the SQL commands and their active variant for each driver",
+				Description: "List the SQL commands with their file, driver and annotations. The driver specific commands override the generic ones",
+				ArgsUsage:   "[pattern]",
+				Action:      m.list,
+				Flags: []cli.Flag{
+					cli.StringSliceFlag{
+						Name:  "driver",
+						Usage: "name of the driver whose commands are listed",
+					},
+					cli.StringFlag{
+						Name:  "format, f",
+						Usage: "output format (table, json)",
+						Value: "table",
+					},
+				},
+			},
+			{
+				Name:        "show",
+				Usage:       "Show the SQL commands for the current driver",
+				Description: "Show the final SQL of the commands whose name matches the pattern as it is executed by the current driver",
+				ArgsUsage:   "[pattern]",
+				Action:      m.show,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "format, f",
+						Usage: "output format (sql, json)",
+						Value: "sql",
+					},
+				},
+			},
+			{
+				Name:        "bench",
+				Usage:       "Benchmark a SQL command and report its throughput and latency percentiles",
+				Description: "Run a SQL command repeatedly with prepared statements and report its throughput and p50/p95/p99 latency. The result can be saved and compared against a baseline",
+				ArgsUsage:   "[name]",
+				Action:      m.bench,
+				Flags: []cli.Flag{
+					cli.StringSliceFlag{
+						Name:  "param, p",
+						Usage: "Parameters for the command. Use key=value for commands with named parameters and a type prefix, such as int:42, for typed values",
+					},
+					cli.StringFlag{
+						Name:  "param-file",
+						Usage: "path to a CSV file whose header contains the parameter names and whose rows are used in turn",
+					},
+					cli.IntFlag{
+						Name:  "iterations, n",
+						Usage: "number of executions (default: 100 if the duration is not provided)",
+					},
+					cli.DurationFlag{
+						Name:  "duration",
+						Usage: "duration of the benchmark, such as 30s",
+					},
+					cli.IntFlag{
+						Name:  "concurrency, c",
+						Usage: "number of concurrent executions",
+						Value: 1,
+					},
+					cli.StringFlag{
+						Name:  "baseline, b",
+						Usage: "path to a saved result that the benchmark is compared to",
+					},
+					cli.Float64Flag{
+						Name:  "tolerance",
+						Usage: "fraction by which the result can be worse than the baseline",
+						Value: 0.1,
+					},
+					cli.StringFlag{
+						Name:  "save, s",
+						Usage: "path to the file where the result is saved as JSON",
+					},
+				},
+			},
+			{
+				Name:        "explain",
+				Usage:       "Explain the query plan of a SQL command for given arguments",
+				Description: "Explain the query plan of a SQL command by using the EXPLAIN syntax of the driver and report the sequential scans of large tables",
+				ArgsUsage:   "[name]",
+				Action:      m.explain,
+				Flags: []cli.Flag{
+					cli.StringSliceFlag{
+						Name:  "param, p",
+						Usage: "Parameters for the command. Use key=value for commands with named parameters and a type prefix, such as int:42, for typed values",
+					},
+					cli.BoolFlag{
+						Name:  "analyze, a",
+						Usage: "execute the command in a rolled back transaction and report the actual rows and timing",
+					},
+					cli.Int64Flag{
+						Name:  "threshold, t",
+						Usage: "number of rows above which a sequential scan is reported",
+						Value: sqlexec.DefaultScanThreshold,
+					},
+					cli.BoolFlag{
+						Name:  "count",
+						Usage: "count the rows of the scanned SQLite tables that have no statistics",
+					},
+				},
+			},
+			{
+				Name:        "test",
+				Usage:       "Test the SQL commands against golden files",
+				Description: "Run the SQL commands of the test specs with their fixtures and parameters, and compare the result rows with the golden JSON files",
+				ArgsUsage:   "[spec...]",
+				Action:      m.test,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:   "test-dir",
+						Usage:  "path to the directory that contain the test specs and the golden files",
+						EnvVar: "PRANA_TEST_DIR",
+						Value:  "./database/test",
+					},
+					cli.StringFlag{
+						Name:   "fixture-dir",
+						Usage:  "path to the directory that contain the fixtures",
+						EnvVar: "PRANA_FIXTURE_DIR",
+						Value:  "./database/fixture",
+					},
+					cli.BoolFlag{
+						Name:  "update, u",
+						Usage: "write the actual results to the golden files",
+					},
+				},
+			},
+			{
+				Name:        "lint",
+				Usage:       "Validate the SQL commands of all drivers",
+				Description: "Report duplicate, empty, shadowed and malformed SQL commands with their file and line",
+				Action:      m.lint,
+				Flags: []cli.Flag{
+					cli.BoolFlag{
+						Name:  "prepare",
+						Usage: "prepare the commands against the database to detect invalid statements",
+					},
+				},
+			},
 			{
 				Name:        "run",
 				Usage:       "Run a SQL command for given arguments",
@@ -89,7 +260,21 @@ func (m *SQLRoutine) CreateCommand() cli.Command {
 				Flags: []cli.Flag{
 					cli.StringSliceFlag{
 						Name:  "param, p",
-						Usage: "Parameters for the command",
+						Usage: "Parameters for the command. Use key=value for commands with named parameters and a type prefix, such as int:42, for typed values",
+					},
+					cli.StringFlag{
+						Name:  "format, f",
+						Usage: "output format (table, csv, tsv, json, jsonl, markdown, vertical)",
+						Value: sqlexec.FormatTable,
+					},
+					cli.StringFlag{
+						Name:  "binary",
+						Usage: "encoding of the binary values (hex, base64)",
+						Value: sqlexec.BinaryHex,
+					},
+					cli.StringFlag{
+						Name:  "output, o",
+						Usage: "path to the file where the output is written",
 					},
 				},
 			},
@@ -127,9 +312,8 @@ func (m *SQLRoutine) create(ctx *cli.Context) error {
 	return nil
 }
 
-func (m *SQLRoutine) run(ctx *cli.Context) error {
+func (m *SQLRoutine) run(ctx *cli.Context) (err error) {
 	args := ctx.Args()
-	params := params(ctx.StringSlice("param"))
 
 	if len(args) != 1 {
 		return cli.NewExitError("Run command expects a single argument", ErrCodeCommand)
@@ -139,6 +323,11 @@ func (m *SQLRoutine) run(ctx *cli.Context) error {
 
 	log.Infof("Running command '%s' from '%s'", name, m.dir)
 
+	formatter, err := m.formatter(ctx)
+	if err != nil {
+		return err
+	}
+
 	db, err := open(ctx)
 	if err != nil {
 		return err
@@ -155,49 +344,604 @@ func (m *SQLRoutine) run(ctx *cli.Context) error {
 		DB:         db,
 	}
 
+	routine, err := runner.Routine(name)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeCommand)
+	}
+
+	// the database infers the types of the params that are not declared
+	if routine, err = sqlexec.DescribeParams(db, routine); err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeCommand)
+	}
+
+	var (
+		params []sqlexec.Param
+		arg    map[string]sqlexec.Param
+	)
+
+	values, named, err := namedParams(routine, ctx.StringSlice("param"))
+
+	switch {
+	case err != nil:
+	case named:
+		arg, err = routine.ParseNamedParams(values)
+	default:
+		params, err = routine.ParseParams(ctx.StringSlice("param"))
+	}
+
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	if !routine.IsQuery() {
+		var result sql.Result
+
+		if named {
+			result, err = runner.ExecNamed(name, arg)
+		} else {
+			result, err = runner.Exec(name, params...)
+		}
+
+		if err != nil {
+			return cli.NewExitError(err.Error(), ErrCodeCommand)
+		}
+
+		err = m.output(ctx, func(w io.Writer) error {
+			return formatter.WriteResult(w, result)
+		})
+
+		if err != nil {
+			return cli.NewExitError(err.Error(), ErrCodeCommand)
+		}
+
+		return nil
+	}
+
 	var rows *sqlx.Rows
-	rows, err = runner.Run(name, params...)
+
+	if named {
+		rows, err = runner.RunNamed(name, arg)
+	} else {
+		rows, err = runner.Run(name, params...)
+	}
+
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeCommand)
+	}
+
+	defer rows.Close()
+
+	err = m.output(ctx, func(w io.Writer) error {
+		return formatter.Write(w, rows)
+	})
+
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeCommand)
+	}
+
+	return nil
+}
+
+func (m *SQLRoutine) bench(ctx *cli.Context) (err error) {
+	args := ctx.Args()
+
+	if len(args) != 1 {
+		return cli.NewExitError("Bench command expects a single argument", ErrCodeCommand)
+	}
+
+	name := args[0]
+
+	var baseline *sqlexec.BenchmarkResult
+
+	if path := ctx.String("baseline"); path != "" {
+		baseline = &sqlexec.BenchmarkResult{}
+
+		if err := readJSON(path, baseline); err != nil {
+			return cli.NewExitError(err.Error(), ErrCodeArg)
+		}
+	}
+
+	db, err := open(ctx)
+	if err != nil {
+		return err
+	}
+
+	provider := &sqlexec.Provider{
+		DriverName: db.DriverName(),
+		Mapper:     db.Mapper,
+	}
+
+	if err = provider.ReadDir(parcello.Dir(m.dir)); err != nil {
+		db.Close()
+		return cli.NewExitError(err.Error(), ErrCodeCommand)
+	}
+
+	gateway := &sqlexec.Gateway{
+		Provider: provider,
+		DB:       db,
+	}
+
+	defer func() {
+		if ioErr := gateway.Close(); err == nil {
+			err = ioErr
+		}
+
+		if ioErr := db.Close(); err == nil {
+			err = ioErr
+		}
+	}()
+
+	routine, err := provider.Routine(name)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeCommand)
+	}
+
+	// the database infers the types of the params that are not declared
+	if routine, err = sqlexec.DescribeParams(db, routine); err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeCommand)
+	}
+
+	params, err := m.paramSets(ctx, routine)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	benchmark := &sqlexec.Benchmark{
+		Gateway:     gateway,
+		Routine:     name,
+		Iterations:  ctx.Int("iterations"),
+		Duration:    ctx.Duration("duration"),
+		Concurrency: ctx.Int("concurrency"),
+		Params:      params,
+	}
+
+	log.Infof("Benchmarking command '%s' from '%s'", name, m.dir)
+
+	result, err := benchmark.Run(context.Background())
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeCommand)
+	}
+
+	sqlexec.Fbench(os.Stdout, result, baseline)
+
+	if path := ctx.String("save"); path != "" {
+		if err = writeJSON(path, result); err != nil {
+			return cli.NewExitError(err.Error(), ErrCodeCommand)
+		}
+
+		log.Infof("Saved the result at '%s'", path)
+	}
+
+	if baseline == nil {
+		return nil
+	}
+
+	regressions := result.Regressions(baseline, ctx.Float64("tolerance"))
+
+	for _, regression := range regressions {
+		log.Warn(regression)
+	}
+
+	if len(regressions) > 0 {
+		msg := fmt.Sprintf("Found %d regressions against '%s'", len(regressions), ctx.String("baseline"))
+		return cli.NewExitError(msg, ErrCodeCommand)
+	}
+
+	return nil
+}
+
+func (m *SQLRoutine) paramSets(ctx *cli.Context, routine *sqlexec.Routine) ([]sqlexec.ParamSet, error) {
+	if path := ctx.String("param-file"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		defer file.Close()
+		return sqlexec.ReadParamSets(file, routine)
+	}
+
+	values := ctx.StringSlice("param")
+
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	set := sqlexec.ParamSet{}
+
+	named, ok, err := namedParams(routine, values)
+
+	switch {
+	case err != nil:
+	case ok:
+		set.Arg, err = routine.ParseNamedParams(named)
+	default:
+		set.Args, err = routine.ParseParams(values)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []sqlexec.ParamSet{set}, nil
+}
+
+func (m *SQLRoutine) explain(ctx *cli.Context) (err error) {
+	args := ctx.Args()
+
+	if len(args) != 1 {
+		return cli.NewExitError("Explain command expects a single argument", ErrCodeCommand)
+	}
+
+	name := args[0]
+
+	db, err := open(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if ioErr := db.Close(); err == nil {
+			err = ioErr
+		}
+	}()
+
+	runner := &sqlexec.Runner{
+		FileSystem: parcello.Dir(m.dir),
+		DB:         db,
+	}
+
+	routine, err := runner.Routine(name)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeCommand)
+	}
+
+	// the database infers the types of the params that are not declared
+	if routine, err = sqlexec.DescribeParams(db, routine); err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeCommand)
+	}
+
+	explainer := &sqlexec.Explainer{
+		FileSystem: parcello.Dir(m.dir),
+		DB:         db,
+		Analyze:    ctx.Bool("analyze"),
+		Threshold:  ctx.Int64("threshold"),
+		Count:      ctx.Bool("count"),
+	}
+
+	var plan *sqlexec.Plan
+
+	values, named, err := namedParams(routine, ctx.StringSlice("param"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	if named {
+		arg, perr := routine.ParseNamedParams(values)
+		if perr != nil {
+			return cli.NewExitError(perr.Error(), ErrCodeArg)
+		}
+
+		plan, err = explainer.ExplainNamed(name, arg)
+	} else {
+		params, perr := routine.ParseParams(ctx.StringSlice("param"))
+		if perr != nil {
+			return cli.NewExitError(perr.Error(), ErrCodeArg)
+		}
+
+		plan, err = explainer.Explain(name, params...)
+	}
 
 	if err != nil {
 		return cli.NewExitError(err.Error(), ErrCodeCommand)
 	}
 
-	if err := m.print(rows); err != nil {
+	sqlexec.Fplan(os.Stdout, plan)
+
+	if warnings := plan.Warnings(); len(warnings) > 0 {
+		log.Warnf("Found %d sequential scans of large tables", len(warnings))
+	}
+
+	return nil
+}
+
+func (m *SQLRoutine) test(ctx *cli.Context) (err error) {
+	testDir, err := filepath.Abs(ctx.String("test-dir"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	fixtureDir, err := filepath.Abs(ctx.String("fixture-dir"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	db, err := open(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if ioErr := db.Close(); err == nil {
+			err = ioErr
+		}
+	}()
+
+	harness := &sqltest.Harness{
+		FileSystem: parcello.Dir(testDir),
+		Runner: &sqlexec.Runner{
+			FileSystem: parcello.Dir(m.dir),
+			DB:         db,
+		},
+		Loader: &sqlfixture.Loader{
+			FileSystem: parcello.Dir(fixtureDir),
+			DB:         db,
+		},
+		Update: ctx.Bool("update"),
+	}
+
+	results, err := harness.Run(ctx.Args()...)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = fmt.Errorf("Directory '%s' does not exist", testDir)
+		}
 		return cli.NewExitError(err.Error(), ErrCodeCommand)
 	}
 
+	sqltest.Ftable(os.Stdout, results)
+	sqltest.Ffailures(os.Stdout, results)
+
+	failed := 0
+
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return cli.NewExitError(fmt.Sprintf("Found %d failed tests in '%s'", failed, testDir), ErrCodeCommand)
+	}
+
+	if harness.Update {
+		log.Infof("Updated %d golden files in '%s'", len(results), testDir)
+		return nil
+	}
+
+	log.Infof("Passed %d tests in '%s'", len(results), testDir)
+	return nil
+}
+
+func (m *SQLRoutine) list(ctx *cli.Context) error {
+	drivers := ctx.StringSlice("driver")
+
+	if len(drivers) == 0 {
+		drivers = sqlexec.Drivers
+	}
+
+	variants, err := m.variants(ctx, drivers...)
+	if err != nil {
+		return err
+	}
+
+	switch format := ctx.String("format"); format {
+	case "table":
+		sqlexec.Ftable(os.Stdout, variants)
+	case "json":
+		err = sqlexec.Fjson(os.Stdout, variants)
+	default:
+		err = fmt.Errorf("Unsupported format '%s'", format)
+	}
+
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
 	return nil
 }
 
-func (m *SQLRoutine) print(rows *sqlx.Rows) error {
-	table := tablewriter.NewWriter(os.Stdout)
+func (m *SQLRoutine) show(ctx *cli.Context) error {
+	driver, _, err := prana.ParseURL(ctx.GlobalString("database-url"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
 
-	columns, err := rows.Columns()
+	variants, err := m.variants(ctx, driver)
 	if err != nil {
 		return err
 	}
 
-	table.SetHeader(columns)
+	if pattern := ctx.Args().First(); len(variants) == 0 && pattern != "" {
+		return cli.NewExitError(fmt.Sprintf("No commands match '%s'", pattern), ErrCodeCommand)
+	}
+
+	switch format := ctx.String("format"); format {
+	case "sql":
+		sqlexec.Fscript(os.Stdout, variants)
+	case "json":
+		err = sqlexec.Fjson(os.Stdout, variants)
+	default:
+		err = fmt.Errorf("Unsupported format '%s'", format)
+	}
+
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	return nil
+}
+
+func (m *SQLRoutine) variants(ctx *cli.Context, drivers ...string) ([]*sqlexec.Variant, error) {
+	args := ctx.Args()
+
+	if len(args) > 1 {
+		return nil, cli.NewExitError("The command expects a single pattern argument", ErrCodeCommand)
+	}
+
+	variants, err := sqlexec.Variants(parcello.Dir(m.dir), args.First(), drivers...)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = fmt.Errorf("Directory '%s' does not exist", m.dir)
+		}
+		return nil, cli.NewExitError(err.Error(), ErrCodeCommand)
+	}
+
+	return variants, nil
+}
+
+func (m *SQLRoutine) lint(ctx *cli.Context) error {
+	linter := &sqlexec.Linter{
+		FileSystem: parcello.Dir(m.dir),
+	}
 
-	for rows.Next() {
-		record, err := rows.SliceScan()
+	if ctx.Bool("prepare") {
+		db, err := open(ctx)
 		if err != nil {
 			return err
 		}
 
-		row := []string{}
+		defer db.Close()
+		linter.DB = db
+	}
+
+	issues, err := linter.Lint()
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = fmt.Errorf("Directory '%s' does not exist", m.dir)
+		}
+		return cli.NewExitError(err.Error(), ErrCodeCommand)
+	}
+
+	failed := 0
+
+	for _, issue := range issues {
+		issue.File = filepath.Join(m.dir, issue.File)
+
+		if issue.Severity == sqlexec.SeverityError {
+			failed++
+		}
+	}
+
+	sqlexec.Flint(os.Stdout, issues)
+
+	if failed > 0 {
+		return cli.NewExitError(fmt.Sprintf("Found %d errors in '%s'", failed, m.dir), ErrCodeCommand)
+	}
+
+	log.Infof("Found %d issues in '%s'", len(issues), m.dir)
+	return nil
+}
+
+func (m *SQLRoutine) generate(ctx *cli.Context) (err error) {
+	db, err := open(ctx)
+	if err != nil {
+		return err
+	}
 
-		for _, column := range record {
-			if data, ok := column.([]byte); ok {
-				column = string(data)
-			}
-			row = append(row, fmt.Sprintf("%v", column))
+	defer func() {
+		if ioErr := db.Close(); err == nil {
+			err = ioErr
 		}
+	}()
+
+	model := &SQLModel{}
+
+	schema, err := model.provider(db)
+	if err != nil {
+		return err
+	}
+
+	provider := &sqlexec.Provider{
+		DriverName: db.DriverName(),
+	}
+
+	if err = provider.ReadDir(parcello.Dir(m.dir)); err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeCommand)
+	}
+
+	dir, err := filepath.Abs(ctx.String("package-dir"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
 
-		table.Append(row)
+	executor := &sqlmodel.Executor{
+		Provider: schema,
+		Inspector: &sqlmodel.RoutineInspector{
+			DB: db,
+		},
+		RoutineGenerator: &sqlmodel.RoutineGenerator{
+			TagBuilder: sqlmodel.CompositeTagBuilder{
+				sqlmodel.SQLXTagBuilder{},
+			},
+			Config: &sqlmodel.ModelGeneratorConfig{
+				InlcudeDoc: ctx.BoolT("include-docs"),
+			},
+		},
+	}
+
+	spec := &sqlmodel.Spec{
+		Name:       filepath.Base(dir),
+		FileSystem: parcello.Dir(dir),
+		Schema:     ctx.String("schema-name"),
+	}
+
+	path, err := executor.CreateRoutine(spec, provider.Routines())
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeCommand)
+	}
+
+	if path != "" {
+		log.Infof("Generated the SQL command functions at: '%s'", filepath.Join(dir, path))
+	}
+
+	return nil
+}
+
+func (m *SQLRoutine) formatter(ctx *cli.Context) (*sqlexec.Formatter, error) {
+	formatter := &sqlexec.Formatter{
+		Format: ctx.String("format"),
+		Binary: ctx.String("binary"),
+	}
+
+	switch formatter.Format {
+	case sqlexec.FormatTable, sqlexec.FormatCSV, sqlexec.FormatTSV, sqlexec.FormatJSON,
+		sqlexec.FormatJSONL, sqlexec.FormatMarkdown, sqlexec.FormatVertical:
+	default:
+		return nil, cli.NewExitError(fmt.Sprintf("Unsupported format '%s'", formatter.Format), ErrCodeArg)
+	}
+
+	switch formatter.Binary {
+	case sqlexec.BinaryHex, sqlexec.BinaryBase64:
+	default:
+		return nil, cli.NewExitError(fmt.Sprintf("Unsupported binary encoding '%s'", formatter.Binary), ErrCodeArg)
+	}
+
+	return formatter, nil
+}
+
+// output writes to the output file if it is provided or to the stdout
+func (m *SQLRoutine) output(ctx *cli.Context, write func(w io.Writer) error) error {
+	path := ctx.String("output")
+
+	if path == "" {
+		return write(os.Stdout)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
 	}
 
-	table.Render()
+	log.Infof("Wrote the output to '%s'", path)
 	return nil
 }
 
@@ -216,10 +960,42 @@ func (m *SQLRoutine) sync(ctx *cli.Context) error {
 	return model.after(ctx)
 }
 
-func params(args []string) []interface{} {
-	result := []interface{}{}
+// namedParams returns the parameters as map if the routine declares named
+// parameters. The parameters of such routine should be in key=value format,
+// while the values of the other routines are positional as they are.
+func namedParams(routine *sqlexec.Routine, args []string) (map[string]string, bool, error) {
+	if !routine.IsNamed() && !routine.IsTemplate() {
+		return nil, false, nil
+	}
+
+	result := make(map[string]string, len(args))
+
 	for _, arg := range args {
-		result = append(result, arg)
+		match := namedParam.FindStringSubmatch(arg)
+		if match == nil {
+			return nil, true, fmt.Errorf("routine '%s' has named parameters, but '%s' is not in key=value format", routine.Name, arg)
+		}
+
+		result[match[1]] = match[2]
 	}
-	return result
+
+	return result, true, nil
+}
+
+func readJSON(path string, value interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, value)
+}
+
+func writeJSON(path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(data, '\n'), 0600)
 }
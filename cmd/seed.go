@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/jmoiron/sqlx"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/prana/sqlseed"
+	"github.com/urfave/cli"
+)
+
+var envFlag = cli.StringFlag{
+	Name:   "env, e",
+	Usage:  "environment whose 'seed_<env>' routines are applied in addition to the 'seed' routines",
+	EnvVar: "PRANA_SEED_ENV",
+}
+
+// SQLSeed provides a subcommands to work with SQL seeds.
+type SQLSeed struct {
+	executor *sqlseed.Executor
+	db       *sqlx.DB
+	dir      string
+}
+
+// CreateCommand creates a cli.Command that can be used by cli.App.
+func (m *SQLSeed) CreateCommand() cli.Command {
+	return cli.Command{
+		Name:         "seed",
+		Usage:        "A group of commands for applying seed data",
+		Description:  "A group of commands for applying seed data",
+		BashComplete: cli.DefaultAppComplete,
+		Before:       m.before,
+		After:        m.after,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:   "seed-dir, d",
+				Usage:  "path to the directory that contain the seeds",
+				EnvVar: "PRANA_SEED_DIR",
+				Value:  "./database/seed",
+			},
+		},
+		Subcommands: []cli.Command{
+			{
+				Name:   "run",
+				Usage:  "Run the pending and changed seeds",
+				Action: m.run,
+				Flags: []cli.Flag{
+					envFlag,
+					cli.BoolFlag{
+						Name:  "force, f",
+						Usage: "run the seeds that have already been applied",
+					},
+				},
+			},
+			{
+				Name:   "status",
+				Usage:  "Show all seeds, indicating which are applied",
+				Action: m.status,
+				Flags: []cli.Flag{
+					envFlag,
+				},
+			},
+		},
+	}
+}
+
+func (m *SQLSeed) before(ctx *cli.Context) error {
+	db, err := open(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.dir, err = filepath.Abs(ctx.String("seed-dir"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	m.db = db
+	m.executor = &sqlseed.Executor{
+		Logger: log.Log,
+		Provider: &sqlseed.Provider{
+			FileSystem: parcello.Dir(m.dir),
+			DB:         db,
+		},
+		Runner: &sqlseed.Runner{
+			DB: db,
+		},
+	}
+
+	return nil
+}
+
+func (m *SQLSeed) after(ctx *cli.Context) error {
+	if err := m.db.Close(); err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeSeed)
+	}
+
+	return nil
+}
+
+func (m *SQLSeed) run(ctx *cli.Context) error {
+	count, err := m.executor.Run(ctx.String("env"), ctx.Bool("force"))
+	if err != nil {
+		err = m.errf(err)
+		return cli.NewExitError(err.Error(), ErrCodeSeed)
+	}
+
+	log.Infof("Applied %d seeds", count)
+	return nil
+}
+
+func (m *SQLSeed) status(ctx *cli.Context) error {
+	seeds, err := m.executor.Seeds(ctx.String("env"))
+	if err != nil {
+		err = m.errf(err)
+		return cli.NewExitError(err.Error(), ErrCodeSeed)
+	}
+
+	if strings.EqualFold("json", ctx.GlobalString("log-format")) {
+		sqlseed.Flog(log.Log, seeds)
+		return nil
+	}
+
+	sqlseed.Ftable(os.Stdout, seeds)
+	return nil
+}
+
+func (m *SQLSeed) errf(err error) error {
+	if os.IsNotExist(err) {
+		err = fmt.Errorf("Directory '%s' does not exist", m.dir)
+	}
+	return err
+}
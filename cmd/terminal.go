@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// errInterrupt is returned when the line is interrupted with Ctrl-C
+var errInterrupt = fmt.Errorf("interrupt")
+
+// lineEditor reads the lines of a terminal. The cursor is moved with the
+// left and right arrow keys and the history is recalled with the up and down
+// arrow keys.
+type lineEditor struct {
+	fd      int
+	reader  *bufio.Reader
+	writer  io.Writer
+	history func() []string
+}
+
+// ReadLine reads a line with given prompt. It returns io.EOF when Ctrl-D is
+// pressed on an empty line.
+func (e *lineEditor) ReadLine(prompt string) (string, error) {
+	restore, err := makeRaw(e.fd)
+	if err != nil {
+		return "", err
+	}
+
+	defer restore()
+	return e.edit(prompt)
+}
+
+func (e *lineEditor) edit(prompt string) (string, error) {
+	var (
+		line    = []rune{}
+		cursor  = 0
+		history = e.history()
+		index   = len(history)
+		pending = []rune{}
+	)
+
+	recall := func(next int) {
+		if next < 0 || next > len(history) || next == index {
+			return
+		}
+
+		// the edited line is kept while the history is browsed
+		if index == len(history) {
+			pending = line
+		}
+
+		index = next
+
+		if index == len(history) {
+			line = append([]rune{}, pending...)
+		} else {
+			line = []rune(strings.Replace(history[index], "\n", " ", -1))
+		}
+
+		cursor = len(line)
+	}
+
+	for {
+		e.redraw(prompt, line, cursor)
+
+		char, _, err := e.reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch char {
+		case '\r', '\n':
+			fmt.Fprint(e.writer, "\r\n")
+			return string(line), nil
+		case 3: // Ctrl-C
+			fmt.Fprint(e.writer, "^C\r\n")
+			return "", errInterrupt
+		case 4: // Ctrl-D
+			if len(line) == 0 {
+				fmt.Fprint(e.writer, "\r\n")
+				return "", io.EOF
+			}
+
+			if cursor < len(line) {
+				line = append(line[:cursor], line[cursor+1:]...)
+			}
+		case 1: // Ctrl-A
+			cursor = 0
+		case 5: // Ctrl-E
+			cursor = len(line)
+		case 2: // Ctrl-B
+			if cursor > 0 {
+				cursor--
+			}
+		case 6: // Ctrl-F
+			if cursor < len(line) {
+				cursor++
+			}
+		case 11: // Ctrl-K
+			line = line[:cursor]
+		case 21: // Ctrl-U
+			line = line[cursor:]
+			cursor = 0
+		case 127, 8: // Backspace
+			if cursor > 0 {
+				line = append(line[:cursor-1], line[cursor:]...)
+				cursor--
+			}
+		case 27: // Escape sequence
+			switch e.escape() {
+			case "A":
+				recall(index - 1)
+			case "B":
+				recall(index + 1)
+			case "C":
+				if cursor < len(line) {
+					cursor++
+				}
+			case "D":
+				if cursor > 0 {
+					cursor--
+				}
+			case "H", "1~", "7~":
+				cursor = 0
+			case "F", "4~", "8~":
+				cursor = len(line)
+			case "3~":
+				if cursor < len(line) {
+					line = append(line[:cursor], line[cursor+1:]...)
+				}
+			}
+		default:
+			if !unicode.IsPrint(char) && char != '\t' {
+				continue
+			}
+
+			line = append(line, 0)
+			copy(line[cursor+1:], line[cursor:])
+			line[cursor] = char
+			cursor++
+		}
+	}
+}
+
+// escape reads the rest of an escape sequence such as ESC [ A
+func (e *lineEditor) escape() string {
+	kind, _, err := e.reader.ReadRune()
+	if err != nil || (kind != '[' && kind != 'O') {
+		return ""
+	}
+
+	sequence := []rune{}
+
+	for {
+		char, _, err := e.reader.ReadRune()
+		if err != nil {
+			return ""
+		}
+
+		sequence = append(sequence, char)
+
+		// the sequence ends with a letter or tilde
+		if unicode.IsLetter(char) || char == '~' {
+			return string(sequence)
+		}
+	}
+}
+
+// redraw writes the line and moves the cursor to its position
+func (e *lineEditor) redraw(prompt string, line []rune, cursor int) {
+	fmt.Fprintf(e.writer, "\r%s%s\x1b[K", prompt, string(line))
+
+	if back := len(line) - cursor; back > 0 {
+		fmt.Fprintf(e.writer, "\x1b[%dD", back)
+	}
+}
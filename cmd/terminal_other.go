@@ -0,0 +1,12 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package cmd
+
+import "fmt"
+
+// makeRaw is not supported on this platform, so the console reads the lines
+// without editing
+func makeRaw(fd int) (func() error, error) {
+	return nil, fmt.Errorf("raw terminal mode is not supported")
+}
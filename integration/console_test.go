@@ -0,0 +1,141 @@
+package integration_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Console", func() {
+	var (
+		cmd     *exec.Cmd
+		input   *bytes.Buffer
+		history string
+	)
+
+	BeforeEach(func() {
+		dir, err := ioutil.TempDir("", "gom")
+		Expect(err).To(BeNil())
+
+		args := []string{"--database-url", "sqlite3://gom.db"}
+
+		Setup(args, dir)
+
+		script := &bytes.Buffer{}
+		fmt.Fprintln(script, "-- name: show-migration")
+		fmt.Fprintln(script, "SELECT id FROM migrations WHERE id = ?;")
+
+		Expect(os.MkdirAll(filepath.Join(dir, "/database/routine"), 0700)).To(Succeed())
+		path := filepath.Join(dir, "/database/routine/routine.sql")
+		Expect(ioutil.WriteFile(path, script.Bytes(), 0700)).To(Succeed())
+
+		history = filepath.Join(dir, "history")
+		input = &bytes.Buffer{}
+
+		cmd = exec.Command(gomPath, append(args, "console", "--history-file", history)...)
+		cmd.Dir = dir
+		cmd.Stdin = input
+	})
+
+	It("executes multi-line statements", func() {
+		fmt.Fprintln(input, "SELECT id")
+		fmt.Fprintln(input, "FROM migrations;")
+
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		Expect(session.Out).To(gbytes.Say("| 00060524000000 |"))
+	})
+
+	It("executes statements that do not return rows", func() {
+		fmt.Fprintln(input, "DELETE FROM migrations;")
+
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		Expect(session.Out).To(gbytes.Say("ROWS AFFECTED"))
+	})
+
+	It("runs the routines", func() {
+		fmt.Fprintln(input, `\f csv`)
+		fmt.Fprintln(input, `\r show-migration 00060524000000`)
+
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		Expect(session.Out).To(gbytes.Say("id\n00060524000000"))
+	})
+
+	It("describes the tables", func() {
+		fmt.Fprintln(input, `\d`)
+		fmt.Fprintln(input, `\d migrations`)
+
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		Expect(session.Out).To(gbytes.Say(`\| migrations \|`))
+		Expect(session.Out).To(gbytes.Say("Table 'migrations'"))
+		Expect(session.Out).To(gbytes.Say(`\| description\s+\| TEXT\s+\| false\s+\| false`))
+	})
+
+	It("shows the migration status", func() {
+		fmt.Fprintln(input, `\m`)
+
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		Expect(session.Out).To(gbytes.Say("00060524000000"))
+		Expect(session.Out).To(gbytes.Say("executed"))
+	})
+
+	It("keeps the history", func() {
+		fmt.Fprintln(input, "SELECT 1 AS one;")
+		fmt.Fprintln(input, `\h`)
+		fmt.Fprintln(input, `\h 1`)
+		fmt.Fprintln(input, `\q`)
+		fmt.Fprintln(input, "SELECT 2 AS two;")
+
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		Expect(session.Out).To(gbytes.Say(`\| ONE \|`))
+		Expect(session.Out).To(gbytes.Say(`1  SELECT 1 AS one;`))
+		Expect(session.Out).To(gbytes.Say(`\| ONE \|`))
+		Expect(session.Out).NotTo(gbytes.Say("TWO"))
+
+		data, err := ioutil.ReadFile(history)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("SELECT 1 AS one;"))
+		Expect(string(data)).To(ContainSubstring(`\q`))
+	})
+
+	Context("when the statement fails", func() {
+		It("reports the error and continues", func() {
+			fmt.Fprintln(input, "SELECT * FROM unknown;")
+			fmt.Fprintln(input, `\unknown`)
+			fmt.Fprintln(input, "SELECT 1 AS one;")
+
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			Expect(session.Err).To(gbytes.Say("ERROR: no such table: unknown"))
+			Expect(session.Err).To(gbytes.Say(`ERROR: unknown command '\\unknown'`))
+			Expect(session.Out).To(gbytes.Say(`\| ONE \|`))
+		})
+	})
+})
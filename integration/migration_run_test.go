@@ -11,6 +11,7 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 	"github.com/onsi/gomega/gexec"
 )
 
@@ -81,6 +82,69 @@ var _ = Describe("Migration Run", func() {
 		})
 	})
 
+	Context("when the module is provided", func() {
+		JustBeforeEach(func() {
+			for _, module := range []string{"accounts", "billing"} {
+				setup := exec.Command(gomPath, "--database-url", "sqlite3://gom.db", "migration", "setup", "--module", module)
+				setup.Dir = cmd.Dir
+
+				session, err := gexec.Start(setup, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(0))
+			}
+
+			path := filepath.Join(cmd.Dir, "/database/migration/billing/module.json")
+			Expect(ioutil.WriteFile(path, []byte(`{"depends": ["accounts"]}`), 0700)).To(Succeed())
+		})
+
+		It("runs the module and its dependencies successfully", func() {
+			cmd.Args = append(cmd.Args, "--module", "billing")
+
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			for _, table := range []string{"accounts_migrations", "billing_migrations"} {
+				row := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", table))
+
+				count := 0
+				Expect(row.Scan(&count)).To(Succeed())
+				Expect(count).To(Equal(1))
+			}
+
+			row := db.QueryRow("SELECT COUNT(*) FROM migrations")
+
+			count := 0
+			Expect(row.Scan(&count)).To(Succeed())
+			Expect(count).To(Equal(1))
+		})
+
+		It("runs all modules after the project migrations", func() {
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			for _, table := range []string{"migrations", "accounts_migrations", "billing_migrations"} {
+				row := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", table))
+
+				count := 0
+				Expect(row.Scan(&count)).To(Succeed())
+				Expect(count).NotTo(BeZero())
+			}
+		})
+	})
+
+	Context("when the tenant schemas are provided", func() {
+		It("returns an error for non PostgreSQL database", func() {
+			cmd.Args = append(cmd.Args, "--tenant-schema", "tenant_a")
+
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(101))
+			Expect(session.Err).To(gbytes.Say("Tenant schemas are supported only by PostgreSQL"))
+		})
+	})
+
 	Context("when the database is not available", func() {
 		It("returns an error", func() {
 			Expect(os.Remove(filepath.Join(cmd.Dir, "gom.db"))).To(Succeed())
@@ -0,0 +1,84 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Routine Bench", func() {
+	var cmd *exec.Cmd
+
+	BeforeEach(func() {
+		dir, err := ioutil.TempDir("", "gom")
+		Expect(err).To(BeNil())
+
+		args := []string{"--database-url", "sqlite3://gom.db"}
+
+		Setup(args, dir)
+
+		routineDir := filepath.Join(dir, "/database/routine")
+		Expect(os.MkdirAll(routineDir, 0700)).To(Succeed())
+
+		script := []byte("-- name: show-migration\nSELECT * FROM migrations WHERE id = :id;\n")
+		Expect(ioutil.WriteFile(filepath.Join(routineDir, "routine.sql"), script, 0700)).To(Succeed())
+
+		params := []byte("id\n00060524000000\nunknown\n")
+		Expect(ioutil.WriteFile(filepath.Join(dir, "params.csv"), params, 0700)).To(Succeed())
+
+		cmd = exec.Command(gomPath, append(args, "routine", "bench")...)
+		cmd.Dir = dir
+	})
+
+	It("benchmarks the command", func() {
+		cmd.Args = append(cmd.Args, "--iterations", "10", "--param-file", "params.csv", "--save", "result.json", "show-migration")
+
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		Expect(session.Out).To(gbytes.Say(`ITERATIONS\s+10`))
+		Expect(session.Out).To(gbytes.Say(`THROUGHPUT`))
+		Expect(session.Out).To(gbytes.Say(`P95`))
+
+		data, err := ioutil.ReadFile(filepath.Join(cmd.Dir, "result.json"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring(`"routine": "show-migration"`))
+	})
+
+	Context("when the result is worse than the baseline", func() {
+		BeforeEach(func() {
+			baseline := []byte(`{"routine": "show-migration", "iterations": 10, "p50": 1, "p95": 1, "p99": 1}`)
+			Expect(ioutil.WriteFile(filepath.Join(cmd.Dir, "baseline.json"), baseline, 0700)).To(Succeed())
+		})
+
+		It("reports the regressions", func() {
+			cmd.Args = append(cmd.Args, "--iterations", "10", "--param", "id=00060524000000", "--baseline", "baseline.json", "show-migration")
+
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(104))
+
+			Expect(session.Out).To(gbytes.Say("BASELINE"))
+			Expect(session.Err).To(gbytes.Say("p50 latency"))
+			Expect(session.Err).To(gbytes.Say("Found 3 regressions against 'baseline.json'"))
+		})
+	})
+
+	Context("when the command does not exist", func() {
+		It("returns an error", func() {
+			cmd.Args = append(cmd.Args, "unknown")
+
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(104))
+			Expect(session.Err).To(gbytes.Say("query 'unknown' not found"))
+		})
+	})
+})
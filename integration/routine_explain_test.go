@@ -0,0 +1,82 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Routine Explain", func() {
+	var cmd *exec.Cmd
+
+	BeforeEach(func() {
+		dir, err := ioutil.TempDir("", "gom")
+		Expect(err).To(BeNil())
+
+		args := []string{"--database-url", "sqlite3://gom.db"}
+
+		Setup(args, dir)
+
+		routineDir := filepath.Join(dir, "/database/routine")
+		Expect(os.MkdirAll(routineDir, 0700)).To(Succeed())
+
+		script := []byte("-- name: show-migrations\nSELECT * FROM migrations WHERE description = ?;\n")
+		Expect(ioutil.WriteFile(filepath.Join(routineDir, "routine.sql"), script, 0700)).To(Succeed())
+
+		cmd = exec.Command(gomPath, append(args, "routine", "explain")...)
+		cmd.Dir = dir
+	})
+
+	It("prints the query plan", func() {
+		cmd.Args = append(cmd.Args, "--param", "setup", "show-migrations")
+
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		Expect(session.Out).To(gbytes.Say(`show-migrations \(sqlite3\)`))
+		Expect(session.Out).To(gbytes.Say(`└── SCAN migrations\n`))
+	})
+
+	Context("when the rows are counted", func() {
+		It("prints the number of rows", func() {
+			cmd.Args = append(cmd.Args, "--count", "--param", "setup", "show-migrations")
+
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			Expect(session.Out).To(gbytes.Say(`└── SCAN migrations \(rows=1\)`))
+		})
+	})
+
+	Context("when the table is larger than the threshold", func() {
+		It("reports the sequential scan", func() {
+			cmd.Args = append(cmd.Args, "--threshold", "1", "--count", "--param", "setup", "show-migrations")
+
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			Expect(session.Out).To(gbytes.Say("WARNING: sequential scan on table 'migrations' with 1 rows"))
+			Expect(session.Err).To(gbytes.Say("Found 1 sequential scans of large tables"))
+		})
+	})
+
+	Context("when the command does not exist", func() {
+		It("returns an error", func() {
+			cmd.Args = append(cmd.Args, "unknown")
+
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(104))
+			Expect(session.Err).To(gbytes.Say("query 'unknown' not found"))
+		})
+	})
+})
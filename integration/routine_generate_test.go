@@ -0,0 +1,81 @@
+package integration_test
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Routine Generate", func() {
+	var (
+		cmd *exec.Cmd
+		dir string
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "gom")
+		Expect(err).To(BeNil())
+
+		db, err := sql.Open("sqlite3", filepath.Join(dir, "gom.db"))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = db.Exec("CREATE TABLE users (id INT PRIMARY KEY NOT NULL, name TEXT NOT NULL)")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db.Close()).To(Succeed())
+
+		script := &bytes.Buffer{}
+		fmt.Fprintln(script, "-- name: select-user")
+		fmt.Fprintln(script, "SELECT * FROM users WHERE id = ?;")
+
+		Expect(os.MkdirAll(filepath.Join(dir, "/database/routine"), 0700)).To(Succeed())
+		path := filepath.Join(dir, "/database/routine/routine.sql")
+		Expect(ioutil.WriteFile(path, script.Bytes(), 0700)).To(Succeed())
+
+		cmd = exec.Command(gomPath, "--database-url", "sqlite3://gom.db", "routine", "generate")
+		cmd.Dir = dir
+	})
+
+	It("generates the routine functions successfully", func() {
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		Expect(session.Err).To(gbytes.Say("Generated the SQL command functions at"))
+
+		path := filepath.Join(dir, "/database/model/routine.go")
+		Expect(path).To(BeARegularFile())
+
+		data, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("func SelectUser(ctx context.Context, db sqlx.ExtContext, id int) (*User, error)"))
+	})
+
+	Context("when the routine is invalid", func() {
+		BeforeEach(func() {
+			script := &bytes.Buffer{}
+			fmt.Fprintln(script, "-- name: select-role")
+			fmt.Fprintln(script, "SELECT * FROM roles;")
+
+			path := filepath.Join(dir, "/database/routine/role.sql")
+			Expect(ioutil.WriteFile(path, script.Bytes(), 0700)).To(Succeed())
+		})
+
+		It("returns an error", func() {
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(104))
+			Expect(session.Err).To(gbytes.Say("routine 'select-role': no such table: roles"))
+		})
+	})
+})
@@ -0,0 +1,78 @@
+package integration_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Routine Lint", func() {
+	var (
+		cmd    *exec.Cmd
+		script *bytes.Buffer
+		dir    string
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "gom")
+		Expect(err).To(BeNil())
+
+		script = &bytes.Buffer{}
+		fmt.Fprintln(script, "-- name: show-users")
+		fmt.Fprintln(script, "SELECT * FROM users;")
+
+		cmd = exec.Command(gomPath, "--database-url", "sqlite3://gom.db", "routine", "lint")
+		cmd.Dir = dir
+	})
+
+	JustBeforeEach(func() {
+		Expect(os.MkdirAll(filepath.Join(dir, "/database/routine"), 0700)).To(Succeed())
+		path := filepath.Join(dir, "/database/routine/routine.sql")
+		Expect(ioutil.WriteFile(path, script.Bytes(), 0700)).To(Succeed())
+	})
+
+	It("lints the routines successfully", func() {
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+		Expect(session.Err).To(gbytes.Say("Found 0 issues"))
+	})
+
+	Context("when the routines are duplicated", func() {
+		BeforeEach(func() {
+			fmt.Fprintln(script, "-- name: show-users")
+			fmt.Fprintln(script, "SELECT id FROM users;")
+		})
+
+		It("reports the duplicates", func() {
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(104))
+			Expect(session.Out).To(gbytes.Say("routine.sql:3: error: routine 'show-users' is already defined at 'routine.sql:1'"))
+			Expect(session.Err).To(gbytes.Say("Found 1 errors"))
+		})
+	})
+
+	Context("when the statements are prepared", func() {
+		BeforeEach(func() {
+			cmd.Args = append(cmd.Args, "--prepare")
+		})
+
+		It("reports the invalid statements", func() {
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(104))
+			Expect(session.Out).To(gbytes.Say("routine 'show-users' cannot be prepared: no such table: users"))
+		})
+	})
+})
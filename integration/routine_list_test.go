@@ -0,0 +1,166 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Routine List", func() {
+	var (
+		cmd *exec.Cmd
+		dir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "gom")
+		Expect(err).To(BeNil())
+
+		routineDir := filepath.Join(dir, "/database/routine")
+		Expect(os.MkdirAll(routineDir, 0700)).To(Succeed())
+
+		generic := []byte("-- name: show-users\n-- tags: users\nSELECT * FROM users;\n\n-- name: delete-users\nDELETE FROM users;\n")
+		Expect(ioutil.WriteFile(filepath.Join(routineDir, "routine.sql"), generic, 0700)).To(Succeed())
+
+		specific := []byte("-- name: show-users\nSELECT * FROM public.users;\n")
+		Expect(ioutil.WriteFile(filepath.Join(routineDir, "routine_postgres.sql"), specific, 0700)).To(Succeed())
+
+		cmd = exec.Command(gomPath, "--database-url", "sqlite3://gom.db", "routine", "list")
+		cmd.Dir = dir
+	})
+
+	It("lists the active variants successfully", func() {
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		Expect(session.Out).To(gbytes.Say("NAME"))
+		Expect(session.Out).To(gbytes.Say(`delete-users\s+sqlite3\s+routine.sql:5`))
+		Expect(session.Out).To(gbytes.Say(`show-users\s+sqlite3\s+routine.sql:1\s+--\s+--\s+users`))
+		Expect(session.Out).To(gbytes.Say(`show-users\s+postgres\s+routine_postgres.sql:1`))
+		Expect(session.Out).To(gbytes.Say(`show-users\s+mysql\s+routine.sql:1`))
+	})
+
+	Context("when the pattern and the driver are provided", func() {
+		BeforeEach(func() {
+			cmd.Args = append(cmd.Args, "--driver", "postgres", "show-*")
+		})
+
+		It("lists the matching routines", func() {
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			Expect(session.Out).To(gbytes.Say(`show-users\s+postgres\s+routine_postgres.sql:1`))
+			Expect(session.Out).NotTo(gbytes.Say("delete-users"))
+			Expect(session.Out).NotTo(gbytes.Say("sqlite3"))
+		})
+	})
+
+	Context("when the format is json", func() {
+		BeforeEach(func() {
+			cmd.Args = append(cmd.Args, "--driver", "postgres", "--format", "json", "show-users")
+		})
+
+		It("lists the routines as json", func() {
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			Expect(session.Out).To(gbytes.Say(`"name": "show-users"`))
+			Expect(session.Out).To(gbytes.Say(`"driver": "postgres"`))
+			Expect(session.Out).To(gbytes.Say(`"file": "routine_postgres.sql"`))
+		})
+	})
+
+	Context("when the format is not supported", func() {
+		BeforeEach(func() {
+			cmd.Args = append(cmd.Args, "--format", "xml")
+		})
+
+		It("returns an error", func() {
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(101))
+			Expect(session.Err).To(gbytes.Say("Unsupported format 'xml'"))
+		})
+	})
+
+	Context("when the directory does not exist", func() {
+		BeforeEach(func() {
+			Expect(os.RemoveAll(filepath.Join(dir, "/database/routine"))).To(Succeed())
+		})
+
+		It("returns an error", func() {
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(104))
+			Expect(session.Err).To(gbytes.Say("does not exist"))
+		})
+	})
+})
+
+var _ = Describe("Routine Show", func() {
+	var cmd *exec.Cmd
+
+	BeforeEach(func() {
+		dir, err := ioutil.TempDir("", "gom")
+		Expect(err).To(BeNil())
+
+		routineDir := filepath.Join(dir, "/database/routine")
+		Expect(os.MkdirAll(routineDir, 0700)).To(Succeed())
+
+		generic := []byte("-- name: show-user\nSELECT * FROM users WHERE id = ?;\n\n-- name: delete-user\nDELETE FROM users WHERE id = ?;\n")
+		Expect(ioutil.WriteFile(filepath.Join(routineDir, "routine.sql"), generic, 0700)).To(Succeed())
+
+		cmd = exec.Command(gomPath, "--database-url", "postgres://localhost/prana", "routine", "show")
+		cmd.Dir = dir
+	})
+
+	It("shows the rebound routines of the current driver", func() {
+		cmd.Args = append(cmd.Args, "show-*")
+
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		Expect(session.Out).To(gbytes.Say(`-- name: show-user\nSELECT \* FROM users WHERE id = \$1;`))
+		Expect(session.Out).NotTo(gbytes.Say("delete-user"))
+	})
+
+	Context("when the format is json", func() {
+		BeforeEach(func() {
+			cmd.Args = append(cmd.Args, "--format", "json", "delete-user")
+		})
+
+		It("shows the routines as json", func() {
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			Expect(session.Out).To(gbytes.Say(`"name": "delete-user"`))
+			Expect(session.Out).To(gbytes.Say(`"driver": "postgres"`))
+			Expect(session.Out).To(gbytes.Say(`"query": "DELETE FROM users WHERE id = \$1;"`))
+		})
+	})
+
+	Context("when no routine matches the pattern", func() {
+		BeforeEach(func() {
+			cmd.Args = append(cmd.Args, "unknown-*")
+		})
+
+		It("returns an error", func() {
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(104))
+			Expect(session.Err).To(gbytes.Say("No commands match 'unknown-\\*'"))
+		})
+	})
+})
@@ -32,6 +32,22 @@ var _ = Describe("Script Run", func() {
 		script := &bytes.Buffer{}
 		fmt.Fprintln(script, "-- name: show-migrations")
 		fmt.Fprintln(script, "SELECT * FROM migrations;")
+		fmt.Fprintln(script)
+		fmt.Fprintln(script, "-- name: show-migration")
+		fmt.Fprintln(script, "SELECT * FROM migrations WHERE id = :id;")
+		fmt.Fprintln(script)
+		fmt.Fprintln(script, "-- name: delete-migration")
+		fmt.Fprintln(script, "DELETE FROM migrations WHERE id = ?;")
+		fmt.Fprintln(script)
+		fmt.Fprintln(script, "-- name: show-type")
+		fmt.Fprintln(script, "SELECT typeof(?) AS kind;")
+		fmt.Fprintln(script)
+		fmt.Fprintln(script, "-- name: show-value")
+		fmt.Fprintln(script, "SELECT ? AS value;")
+		fmt.Fprintln(script)
+		fmt.Fprintln(script, "-- name: show-declared-type")
+		fmt.Fprintln(script, "-- param: value int")
+		fmt.Fprintln(script, "SELECT typeof(:value) AS kind;")
 
 		Expect(os.MkdirAll(filepath.Join(dir, "/database/routine"), 0700)).To(Succeed())
 		path := filepath.Join(dir, "/database/routine/20060102150405.sql")
@@ -57,6 +73,164 @@ var _ = Describe("Script Run", func() {
 		Expect(session.Err).To(gbytes.Say("Running command 'show-migrations'"))
 	})
 
+	Context("when the command has named parameters", func() {
+		It("runs command successfully", func() {
+			cmd.Args = append(cmd.Args, "--param", "id=00060524000000", "show-migration")
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			Expect(session.Err).To(gbytes.Say("Running command 'show-migration'"))
+			Expect(session.Out).To(gbytes.Say("00060524000000"))
+		})
+
+		Context("when the parameter is missing", func() {
+			It("returns an error", func() {
+				cmd.Args = append(cmd.Args, "--param", "name=setup", "show-migration")
+				session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(104))
+				Expect(session.Err).To(gbytes.Say("could not find name id"))
+			})
+		})
+
+		Context("when the parameter is not in key=value format", func() {
+			It("returns an error", func() {
+				cmd.Args = append(cmd.Args, "--param", "00060524000000", "show-migration")
+				session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(101))
+				Expect(session.Err).To(gbytes.Say("routine 'show-migration' has named parameters, but '00060524000000' is not in key=value format"))
+			})
+		})
+	})
+
+	Context("when the positional parameter looks like key=value", func() {
+		It("passes it as it is", func() {
+			cmd.Args = append(cmd.Args, "--format", "csv", "--param", "a=b", "show-value")
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+			Expect(session.Out).To(gbytes.Say("value\na=b"))
+		})
+	})
+
+	Context("when the command does not return rows", func() {
+		It("prints the affected rows", func() {
+			cmd.Args = append(cmd.Args, "--param", "00060524000000", "delete-migration")
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			Expect(session.Out).To(gbytes.Say("ROWS AFFECTED"))
+			Expect(session.Out).To(gbytes.Say("| 1 "))
+		})
+	})
+
+	Context("when the parameter is typed", func() {
+		It("passes the typed value", func() {
+			cmd.Args = append(cmd.Args, "--format", "csv", "--param", "int:42", "show-type")
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+			Expect(session.Out).To(gbytes.Say("kind\ninteger"))
+		})
+
+		It("passes the null value", func() {
+			cmd.Args = append(cmd.Args, "--format", "csv", "--param", "null:", "show-type")
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+			Expect(session.Out).To(gbytes.Say("kind\nnull"))
+		})
+
+		It("passes the value as text by default", func() {
+			cmd.Args = append(cmd.Args, "--format", "csv", "--param", "42", "show-type")
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+			Expect(session.Out).To(gbytes.Say("kind\ntext"))
+		})
+
+		It("infers the type from the annotations", func() {
+			cmd.Args = append(cmd.Args, "--format", "csv", "--param", "value=42", "show-declared-type")
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+			Expect(session.Out).To(gbytes.Say("kind\ninteger"))
+		})
+
+		Context("when the value is not valid", func() {
+			It("returns an error", func() {
+				cmd.Args = append(cmd.Args, "--param", "int:forty", "show-type")
+				session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(101))
+				Expect(session.Err).To(gbytes.Say("param 'forty' is not a valid int"))
+			})
+		})
+	})
+
+	Context("when the format is csv", func() {
+		It("prints the rows as csv", func() {
+			cmd.Args = append(cmd.Args, "--format", "csv", "show-migrations")
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			Expect(session.Out).To(gbytes.Say("id,description,created_at"))
+			Expect(session.Out).To(gbytes.Say(`00060524000000,setup,\d{4}-\d{2}-\d{2}T`))
+		})
+	})
+
+	Context("when the format is json", func() {
+		It("prints the rows as json", func() {
+			cmd.Args = append(cmd.Args, "--format", "json", "show-migrations")
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			Expect(session.Out).To(gbytes.Say(`\[\n  {"id":"00060524000000","description":"setup","created_at":"`))
+		})
+	})
+
+	Context("when the format is vertical", func() {
+		It("prints each column on a separate line", func() {
+			cmd.Args = append(cmd.Args, "--format", "vertical", "show-migrations")
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			Expect(session.Out).To(gbytes.Say(`\*+ 1\. row \*+`))
+			Expect(session.Out).To(gbytes.Say(`         id: 00060524000000`))
+		})
+	})
+
+	Context("when the output file is provided", func() {
+		It("writes the rows to the file", func() {
+			path := filepath.Join(cmd.Dir, "migrations.jsonl")
+
+			cmd.Args = append(cmd.Args, "--format", "jsonl", "--output", path, "show-migrations")
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			data, err := ioutil.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(HavePrefix(`{"id":"00060524000000","description":"setup"`))
+		})
+	})
+
+	Context("when the format is not supported", func() {
+		It("returns an error", func() {
+			cmd.Args = append(cmd.Args, "--format", "xml", "show-migrations")
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(101))
+			Expect(session.Err).To(gbytes.Say("Unsupported format 'xml'"))
+		})
+	})
+
 	Context("when the database is not available", func() {
 		It("returns an error", func() {
 			Expect(os.Remove(filepath.Join(cmd.Dir, "gom.db"))).To(Succeed())
@@ -0,0 +1,120 @@
+package integration_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Routine Test", func() {
+	var (
+		cmd *exec.Cmd
+		dir string
+	)
+
+	write := func(path, content string) {
+		path = filepath.Join(dir, path)
+		Expect(os.MkdirAll(filepath.Dir(path), 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(content), 0700)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "gom")
+		Expect(err).To(BeNil())
+
+		args := []string{"--database-url", "sqlite3://gom.db"}
+
+		Setup(args, dir)
+
+		db, err := sqlx.Open("sqlite3", filepath.Join(dir, "gom.db"))
+		Expect(err).To(BeNil())
+
+		_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+		Expect(err).To(BeNil())
+		Expect(db.Close()).To(Succeed())
+
+		write("database/routine/routine.sql", "-- name: select-user\nSELECT id, name FROM users WHERE id = :id;\n")
+		write("database/fixture/users.yml", "users:\n  - id: 1\n    name: John\n")
+
+		spec := &bytes.Buffer{}
+		fmt.Fprintln(spec, "- name: user by id")
+		fmt.Fprintln(spec, "  routine: select-user")
+		fmt.Fprintln(spec, "  fixtures: [users.yml]")
+		fmt.Fprintln(spec, "  params: {id: 1}")
+
+		write("database/test/users.yml", spec.String())
+
+		cmd = exec.Command(gomPath, append(args, "routine", "test")...)
+		cmd.Dir = dir
+	})
+
+	It("creates the golden files", func() {
+		cmd.Args = append(cmd.Args, "-update")
+
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		Expect(session.Out).To(gbytes.Say(`users.yml\s+user by id\s+select-user\s+updated`))
+		Expect(session.Err).To(gbytes.Say("Updated 1 golden files"))
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "database/test/users/user-by-id.json"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring(`{"id":1,"name":"John"}`))
+	})
+
+	Context("when the golden file matches", func() {
+		BeforeEach(func() {
+			write("database/test/users/user-by-id.json", `[{"id": 1, "name": "John"}]`)
+		})
+
+		It("passes the tests", func() {
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			Expect(session.Out).To(gbytes.Say(`user by id\s+select-user\s+passed`))
+			Expect(session.Err).To(gbytes.Say("Passed 1 tests"))
+		})
+	})
+
+	Context("when the golden file does not match", func() {
+		BeforeEach(func() {
+			write("database/test/users/user-by-id.json", `[{"id": 1, "name": "Jack"}]`)
+		})
+
+		It("fails the tests", func() {
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(104))
+
+			Expect(session.Out).To(gbytes.Say(`user by id\s+select-user\s+failed`))
+			Expect(session.Out).To(gbytes.Say("--- FAIL: users.yml: user by id"))
+			Expect(session.Out).To(gbytes.Say("Jack"))
+			Expect(session.Out).To(gbytes.Say("John"))
+			Expect(session.Err).To(gbytes.Say("Found 1 failed tests"))
+		})
+	})
+
+	Context("when the test directory does not exist", func() {
+		It("returns an error", func() {
+			cmd.Args = append(cmd.Args, "--test-dir", "unknown")
+
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(104))
+			Expect(session.Err).To(gbytes.Say("Directory '.*unknown' does not exist"))
+		})
+	})
+})
@@ -0,0 +1,95 @@
+package integration_test
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Seed Run", func() {
+	var (
+		cmd *exec.Cmd
+		db  *sql.DB
+	)
+
+	count := func() int {
+		row := db.QueryRow("SELECT COUNT(*) FROM users")
+
+		count := 0
+		Expect(row.Scan(&count)).To(Succeed())
+		return count
+	}
+
+	BeforeEach(func() {
+		dir, err := ioutil.TempDir("", "gom")
+		Expect(err).To(BeNil())
+
+		seedDir := filepath.Join(dir, "/database/seed")
+		Expect(os.MkdirAll(seedDir, 0700)).To(Succeed())
+
+		script := &bytes.Buffer{}
+		fmt.Fprintln(script, "-- name: seed")
+		fmt.Fprintln(script, "DELETE FROM users;")
+		fmt.Fprintln(script, "-- name: seed_dev")
+		fmt.Fprintln(script, "INSERT INTO users VALUES (1), (2);")
+
+		path := filepath.Join(seedDir, "users.sql")
+		Expect(ioutil.WriteFile(path, script.Bytes(), 0700)).To(Succeed())
+
+		db, err = sql.Open("sqlite3", filepath.Join(dir, "gom.db"))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = db.Exec("CREATE TABLE users (id INT)")
+		Expect(err).NotTo(HaveOccurred())
+
+		cmd = exec.Command(gomPath, "--database-url", "sqlite3://gom.db", "seed", "run", "--env", "dev")
+		cmd.Dir = dir
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).To(Succeed())
+	})
+
+	It("runs the seeds successfully", func() {
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+		Expect(session.Err).To(gbytes.Say("Applied 1 seeds"))
+
+		Expect(count()).To(Equal(2))
+	})
+
+	It("skips the applied seeds", func() {
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		again := exec.Command(gomPath, cmd.Args[1:]...)
+		again.Dir = cmd.Dir
+
+		session, err = gexec.Start(again, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+		Expect(session.Err).To(gbytes.Say("Applied 0 seeds"))
+	})
+
+	Context("when the seed directory does not exist", func() {
+		It("returns an error", func() {
+			cmd.Args = append(cmd.Args[:3], "seed", "--seed-dir", "./seed", "run")
+
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(106))
+			Expect(session.Err).To(gbytes.Say("does not exist"))
+		})
+	})
+})
@@ -0,0 +1,45 @@
+package integration_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Seed Status", func() {
+	var cmd *exec.Cmd
+
+	BeforeEach(func() {
+		dir, err := ioutil.TempDir("", "gom")
+		Expect(err).To(BeNil())
+
+		seedDir := filepath.Join(dir, "/database/seed")
+		Expect(os.MkdirAll(seedDir, 0700)).To(Succeed())
+
+		script := &bytes.Buffer{}
+		fmt.Fprintln(script, "-- name: seed_dev")
+		fmt.Fprintln(script, "SELECT 1;")
+
+		path := filepath.Join(seedDir, "users.sql")
+		Expect(ioutil.WriteFile(path, script.Bytes(), 0700)).To(Succeed())
+
+		cmd = exec.Command(gomPath, "--database-url", "sqlite3://gom.db", "seed", "status", "--env", "dev")
+		cmd.Dir = dir
+	})
+
+	It("returns the seed status successfully", func() {
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		Expect(string(session.Out.Contents())).To(ContainSubstring("users"))
+		Expect(string(session.Out.Contents())).To(ContainSubstring("pending"))
+	})
+})
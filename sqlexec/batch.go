@@ -0,0 +1,524 @@
+package sqlexec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// DefaultBatchSize is the number of rows of a batch by default.
+const DefaultBatchSize = 1000
+
+var (
+	insertValues = regexp.MustCompile(`(?is)^\s*INSERT\s+INTO\s+([^\s(]+)\s*(?:\(([^)]*)\))?\s*VALUES\s*\(`)
+	copyLine     = regexp.MustCompile(`\bCOPY \S+, line (\d+)`)
+)
+
+// maxParams are the maximum number of parameters of a statement per driver
+var maxParams = map[string]int{
+	"sqlite3":  999,
+	"postgres": 65535,
+	"mysql":    65535,
+}
+
+// BatchError is the error of a failed batch. The rows of the batch are not
+// applied.
+type BatchError struct {
+	// Start is the index of the first row of the batch
+	Start int
+	// End is the index after the last row of the batch
+	End int
+	// Row is the index of the row that failed. It is -1 if the failed row
+	// is not known.
+	Row int
+	// Err is the underlying error
+	Err error
+}
+
+// Error returns the error message
+func (e *BatchError) Error() string {
+	if e.Row < 0 {
+		return fmt.Sprintf("batch of rows %d-%d failed: %v", e.Start, e.End-1, e.Err)
+	}
+
+	return fmt.Sprintf("batch of rows %d-%d failed at row %d: %v", e.Start, e.End-1, e.Row, e.Err)
+}
+
+// BatchErrors are the errors of the failed batches.
+type BatchErrors []*BatchError
+
+// Error returns the error message
+func (e BatchErrors) Error() string {
+	messages := make([]string, len(e))
+
+	for index, err := range e {
+		messages[index] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// BatchResult is the result of a batch execution.
+type BatchResult struct {
+	// RowsAffected is the number of rows affected by the applied batches
+	RowsAffected int64
+	// Batches is the number of executed batches
+	Batches int
+	// Errors are the errors of the failed batches
+	Errors BatchErrors
+}
+
+// Batch executes a routine for many argument sets. A routine that inserts a
+// single row is executed as COPY FROM on PostgreSQL and as INSERT of many
+// rows on the other drivers. Any other routine is executed for each argument
+// set in a transaction per batch.
+type Batch struct {
+	// Gateway provides the routines and the database.
+	Gateway *Gateway
+	// Routine is the name of the routine
+	Routine string
+	// Size is the number of rows of a batch. Defaults to DefaultBatchSize.
+	// The batches of INSERT statements are smaller if they would exceed the
+	// number of parameters supported by the driver.
+	Size int
+}
+
+// Exec executes the routine for given positional argument sets. The batches
+// are applied independently, so the failed batches are reported as
+// BatchErrors while the others are applied.
+func (b *Batch) Exec(ctx context.Context, args [][]Param) (*BatchResult, error) {
+	query, err := b.query()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.exec(ctx, query, args)
+}
+
+// ExecNamed executes a routine that has named parameters for given arguments.
+// Each argument can be a struct or map[string]interface{}. The struct fields
+// are mapped by the mapper of the database.
+func (b *Batch) ExecNamed(ctx context.Context, args []Param) (*BatchResult, error) {
+	query, err := b.query()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		rows     = make([][]Param, len(args))
+		compiled = query
+		// the rows are bound with question marks, which are rebound later
+		db = binder("", b.Gateway.DB.Mapper)
+	)
+
+	for index, arg := range args {
+		if compiled, rows[index], err = db.BindNamed(query, arg); err != nil {
+			return nil, fmt.Errorf("row %d: %v", index, err)
+		}
+	}
+
+	return b.exec(ctx, compiled, rows)
+}
+
+// query returns the query of the routine with question mark placeholders
+func (b *Batch) query() (string, error) {
+	// the templates are rejected as well as the unknown routines
+	if _, err := b.Gateway.Provider.Query(b.Routine); err != nil {
+		return "", err
+	}
+
+	routine, err := b.Gateway.Provider.Routine(b.Routine)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(strings.TrimSpace(routine.Query), "; \t\n"), nil
+}
+
+func (b *Batch) exec(ctx context.Context, query string, rows [][]Param) (*BatchResult, error) {
+	var (
+		insert  = parseInsert(query)
+		size    = b.size(insert)
+		execute = func(rows [][]Param) (int64, int, error) {
+			return b.transaction(ctx, query, rows)
+		}
+	)
+
+	switch {
+	case insert != nil && b.Gateway.DB.DriverName() == "postgres" && insert.copyable():
+		execute = func(rows [][]Param) (int64, int, error) {
+			return b.copy(ctx, insert, rows)
+		}
+	case insert != nil:
+		execute = func(rows [][]Param) (int64, int, error) {
+			return b.values(ctx, insert, rows)
+		}
+	}
+
+	result := &BatchResult{}
+
+	for start := 0; start < len(rows); start += size {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		end := start + size
+
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		affected, row, err := execute(rows[start:end])
+		result.Batches++
+
+		if err != nil {
+			// the rows of a single statement are inserted one by one to
+			// find the failed row
+			if row < 0 && insert != nil && ctx.Err() == nil {
+				row = b.locate(ctx, insert, rows[start:end])
+			}
+
+			if row >= 0 {
+				row += start
+			}
+
+			result.Errors = append(result.Errors, &BatchError{Start: start, End: end, Row: row, Err: err})
+			continue
+		}
+
+		result.RowsAffected += affected
+	}
+
+	if len(result.Errors) > 0 {
+		return result, result.Errors
+	}
+
+	return result, nil
+}
+
+func (b *Batch) size(insert *insertStatement) int {
+	size := b.Size
+
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+
+	if insert == nil || insert.params == 0 {
+		return size
+	}
+
+	// the rows of COPY are not sent as parameters
+	if b.Gateway.DB.DriverName() == "postgres" && insert.copyable() {
+		return size
+	}
+
+	if limit, ok := maxParams[b.Gateway.DB.DriverName()]; ok && size*insert.params > limit {
+		size = limit / insert.params
+	}
+
+	return size
+}
+
+// values inserts the rows with a single INSERT statement
+func (b *Batch) values(ctx context.Context, insert *insertStatement, rows [][]Param) (int64, int, error) {
+	query := &bytes.Buffer{}
+	query.WriteString(insert.prefix)
+
+	args := []Param{}
+
+	for index, row := range rows {
+		if index > 0 {
+			query.WriteString(", ")
+		}
+
+		query.WriteString(insert.tuple)
+		args = append(args, row...)
+	}
+
+	query.WriteString(insert.suffix)
+
+	result, err := b.Gateway.DB.ExecContext(ctx, b.Gateway.DB.Rebind(query.String()), args...)
+	if err != nil {
+		return 0, -1, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected, -1, err
+}
+
+// copy inserts the rows with COPY FROM in a transaction
+func (b *Batch) copy(ctx context.Context, insert *insertStatement, rows [][]Param) (int64, int, error) {
+	tx, err := b.Gateway.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, -1, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, insert.copy())
+	if err != nil {
+		tx.Rollback()
+		return 0, -1, err
+	}
+
+	for index, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			stmt.Close()
+			tx.Rollback()
+
+			// the error of a previous row can be reported later, since
+			// the rows are sent asynchronously
+			if line := copyRow(err); line >= 0 {
+				index = line
+			}
+
+			return 0, index, err
+		}
+	}
+
+	// the buffered rows are sent by the execution without arguments
+	result, err := stmt.ExecContext(ctx)
+	if err != nil {
+		stmt.Close()
+		tx.Rollback()
+		return 0, copyRow(err), err
+	}
+
+	if err := stmt.Close(); err != nil {
+		tx.Rollback()
+		return 0, -1, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, -1, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected, -1, err
+}
+
+// locate inserts the rows of a failed batch one by one in a transaction that
+// is rolled back. It returns the index of the first row that fails or -1 if
+// no row fails.
+func (b *Batch) locate(ctx context.Context, insert *insertStatement, rows [][]Param) int {
+	tx, err := b.Gateway.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return -1
+	}
+
+	defer tx.Rollback()
+
+	stmt, err := tx.PreparexContext(ctx, tx.Rebind(insert.prefix+insert.tuple+insert.suffix))
+	if err != nil {
+		return -1
+	}
+
+	defer stmt.Close()
+
+	for index, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return index
+		}
+	}
+
+	return -1
+}
+
+// copyRow returns the index of the row reported by a failed COPY FROM or -1
+// if the error does not report it
+func copyRow(err error) int {
+	if err, ok := err.(*pq.Error); ok {
+		if match := copyLine.FindStringSubmatch(err.Where); match != nil {
+			line, _ := strconv.Atoi(match[1])
+			return line - 1
+		}
+	}
+
+	return -1
+}
+
+// transaction executes the routine for each row in a transaction
+func (b *Batch) transaction(ctx context.Context, query string, rows [][]Param) (int64, int, error) {
+	tx, err := b.Gateway.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, -1, err
+	}
+
+	stmt, err := tx.PreparexContext(ctx, tx.Rebind(query))
+	if err != nil {
+		tx.Rollback()
+		return 0, -1, err
+	}
+
+	var total int64
+
+	for index, row := range rows {
+		result, err := stmt.ExecContext(ctx, row...)
+		if err != nil {
+			stmt.Close()
+			tx.Rollback()
+			return 0, index, err
+		}
+
+		// not all drivers report the affected rows
+		if affected, err := result.RowsAffected(); err == nil {
+			total += affected
+		}
+	}
+
+	if err := stmt.Close(); err != nil {
+		tx.Rollback()
+		return 0, -1, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, -1, err
+	}
+
+	return total, -1, nil
+}
+
+// insertStatement is an INSERT statement of a single row
+type insertStatement struct {
+	table   string
+	columns []string
+	prefix  string
+	tuple   string
+	suffix  string
+	params  int
+}
+
+// copyable returns true if the statement inserts only parameters into given
+// columns, so it can be executed as COPY FROM
+func (s *insertStatement) copyable() bool {
+	if len(s.columns) == 0 || strings.TrimSpace(s.suffix) != "" {
+		return false
+	}
+
+	values := strings.Split(strings.Trim(s.tuple, "()"), ",")
+
+	if len(values) != len(s.columns) {
+		return false
+	}
+
+	for _, value := range values {
+		if strings.TrimSpace(value) != "?" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// copy returns the COPY FROM statement of the table
+func (s *insertStatement) copy() string {
+	if parts := strings.SplitN(s.table, ".", 2); len(parts) == 2 {
+		return pq.CopyInSchema(parts[0], parts[1], s.columns...)
+	}
+
+	return pq.CopyIn(s.table, s.columns...)
+}
+
+// parseInsert parses an INSERT statement of a single row. It returns nil if
+// the statement cannot be executed for many rows.
+func parseInsert(query string) *insertStatement {
+	match := insertValues.FindStringSubmatchIndex(query)
+	if match == nil {
+		return nil
+	}
+
+	open := match[1] - 1
+	close := closing(query, open)
+
+	if close < 0 {
+		return nil
+	}
+
+	statement := &insertStatement{
+		table:  unquote(query[match[2]:match[3]]),
+		prefix: query[:open],
+		tuple:  query[open : close+1],
+		suffix: query[close+1:],
+	}
+
+	// the statement already inserts many rows
+	if strings.HasPrefix(strings.TrimSpace(statement.suffix), ",") {
+		return nil
+	}
+
+	if match[4] >= 0 {
+		for _, column := range strings.Split(query[match[4]:match[5]], ",") {
+			statement.columns = append(statement.columns, unquote(strings.TrimSpace(column)))
+		}
+	}
+
+	statement.params = placeholders(statement.tuple)
+
+	// every row would carry its own arguments of the suffix, while the
+	// placeholders other than question marks cannot be repeated per row
+	if statement.params == 0 || placeholders(statement.suffix) > 0 || dollarParam.MatchString(statement.tuple+statement.suffix) {
+		return nil
+	}
+
+	return statement
+}
+
+// placeholders returns the number of question marks outside of the quotes
+func placeholders(query string) int {
+	var (
+		count int
+		quote rune
+	)
+
+	for _, char := range query {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"' || char == '`':
+			quote = char
+		case char == '?':
+			count++
+		}
+	}
+
+	return count
+}
+
+// closing returns the position of the parenthesis that closes the one at
+// given position
+func closing(query string, open int) int {
+	var (
+		depth int
+		quote rune
+	)
+
+	for index, char := range query[open:] {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"' || char == '`':
+			quote = char
+		case char == '(':
+			depth++
+		case char == ')':
+			depth--
+
+			if depth == 0 {
+				return open + index
+			}
+		}
+	}
+
+	return -1
+}
+
+func unquote(name string) string {
+	return strings.NewReplacer(`"`, "", "`", "").Replace(name)
+}
@@ -0,0 +1,382 @@
+package sqlexec_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/prana/sqlexec"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var _ = Describe("Batch", func() {
+	var (
+		batch   *sqlexec.Batch
+		gateway *sqlexec.Gateway
+		db      *sqlx.DB
+	)
+
+	routines := func(driver string) *sqlexec.Provider {
+		script := &bytes.Buffer{}
+		fmt.Fprintln(script, "-- name: insert-user")
+		fmt.Fprintln(script, "INSERT INTO users (id, name) VALUES (?, ?);")
+		fmt.Fprintln(script)
+		fmt.Fprintln(script, "-- name: insert-named-user")
+		fmt.Fprintln(script, "INSERT INTO users (id, name) VALUES (:id, upper(:name))")
+		fmt.Fprintln(script)
+		fmt.Fprintln(script, "-- name: upsert-user")
+		fmt.Fprintln(script, "INSERT INTO users (id, name) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET name = ?")
+		fmt.Fprintln(script)
+		fmt.Fprintln(script, "-- name: insert-dollar-user")
+		fmt.Fprintln(script, "INSERT INTO users (id, name) VALUES ($1, $2)")
+		fmt.Fprintln(script)
+		fmt.Fprintln(script, "-- name: rename-user")
+		fmt.Fprintln(script, "UPDATE users SET name = ? WHERE id = ?")
+		fmt.Fprintln(script)
+		fmt.Fprintln(script, "-- name: select-users")
+		fmt.Fprintln(script, "SELECT * FROM users {{ if .id }}WHERE id = {{ bind .id }}{{ end }}")
+
+		provider := &sqlexec.Provider{DriverName: driver}
+		_, err := provider.ReadFrom(script)
+		Expect(err).To(BeNil())
+		return provider
+	}
+
+	rows := func(count int) [][]sqlexec.Param {
+		args := make([][]sqlexec.Param, count)
+
+		for index := range args {
+			args[index] = []sqlexec.Param{index + 1, fmt.Sprintf("user-%d", index+1)}
+		}
+
+		return args
+	}
+
+	Context("when the driver is sqlite3", func() {
+		BeforeEach(func() {
+			var err error
+
+			db, err = sqlx.Open("sqlite3", ":memory:")
+			Expect(err).To(BeNil())
+			db.SetMaxOpenConns(1)
+
+			_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+			Expect(err).To(BeNil())
+
+			gateway = &sqlexec.Gateway{
+				Provider: routines("sqlite3"),
+				DB:       db,
+			}
+
+			batch = &sqlexec.Batch{
+				Gateway: gateway,
+				Routine: "insert-user",
+				Size:    2,
+			}
+		})
+
+		AfterEach(func() {
+			Expect(gateway.Close()).To(Succeed())
+			Expect(db.Close()).To(Succeed())
+		})
+
+		count := func() int {
+			var count int
+			Expect(db.Get(&count, "SELECT count(*) FROM users")).To(Succeed())
+			return count
+		}
+
+		It("inserts the rows in batches", func() {
+			result, err := batch.Exec(context.Background(), rows(5))
+			Expect(err).To(BeNil())
+			Expect(result.RowsAffected).To(BeEquivalentTo(5))
+			Expect(result.Batches).To(Equal(3))
+			Expect(result.Errors).To(BeEmpty())
+			Expect(count()).To(Equal(5))
+		})
+
+		It("inserts the rows with named parameters", func() {
+			batch.Routine = "insert-named-user"
+
+			result, err := batch.ExecNamed(context.Background(), []sqlexec.Param{
+				map[string]interface{}{"id": 1, "name": "john"},
+				map[string]interface{}{"id": 2, "name": "jane"},
+				map[string]interface{}{"id": 3, "name": "jack"},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(result.RowsAffected).To(BeEquivalentTo(3))
+			Expect(result.Batches).To(Equal(2))
+
+			names := []string{}
+			Expect(db.Select(&names, "SELECT name FROM users ORDER BY id")).To(Succeed())
+			Expect(names).To(Equal([]string{"JOHN", "JANE", "JACK"}))
+		})
+
+		It("maps the struct fields with the mapper of the database", func() {
+			batch.Routine = "insert-named-user"
+			db.Mapper = reflectx.NewMapperFunc("json", strings.ToLower)
+
+			type user struct {
+				ID   int    `json:"id"`
+				Name string `json:"name"`
+			}
+
+			result, err := batch.ExecNamed(context.Background(), []sqlexec.Param{
+				&user{ID: 1, Name: "john"},
+				&user{ID: 2, Name: "jane"},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(result.RowsAffected).To(BeEquivalentTo(2))
+			Expect(count()).To(Equal(2))
+		})
+
+		It("limits the batches by the number of parameters of the driver", func() {
+			batch.Size = 0
+
+			result, err := batch.Exec(context.Background(), rows(1200))
+			Expect(err).To(BeNil())
+			Expect(result.RowsAffected).To(BeEquivalentTo(1200))
+			Expect(result.Batches).To(Equal(3))
+			Expect(count()).To(Equal(1200))
+		})
+
+		It("executes the other routines in transactions", func() {
+			_, err := batch.Exec(context.Background(), rows(3))
+			Expect(err).To(BeNil())
+
+			batch.Routine = "rename-user"
+
+			result, err := batch.Exec(context.Background(), [][]sqlexec.Param{
+				{"John", 1},
+				{"Jane", 2},
+				{"Jack", 3},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(result.RowsAffected).To(BeEquivalentTo(3))
+			Expect(result.Batches).To(Equal(2))
+
+			names := []string{}
+			Expect(db.Select(&names, "SELECT name FROM users ORDER BY id")).To(Succeed())
+			Expect(names).To(Equal([]string{"John", "Jane", "Jack"}))
+		})
+
+		It("executes the inserts that have parameters after the values in transactions", func() {
+			_, err := batch.Exec(context.Background(), rows(2))
+			Expect(err).To(BeNil())
+
+			batch.Routine = "upsert-user"
+
+			result, err := batch.Exec(context.Background(), [][]sqlexec.Param{
+				{1, "John", "John"},
+				{2, "Jane", "Jane"},
+				{3, "Jack", "Jack"},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(result.RowsAffected).To(BeEquivalentTo(3))
+			Expect(result.Errors).To(BeEmpty())
+
+			names := []string{}
+			Expect(db.Select(&names, "SELECT name FROM users ORDER BY id")).To(Succeed())
+			Expect(names).To(Equal([]string{"John", "Jane", "Jack"}))
+		})
+
+		It("executes the inserts that have no question marks in transactions", func() {
+			batch.Routine = "insert-dollar-user"
+
+			result, err := batch.Exec(context.Background(), rows(3))
+			Expect(err).To(BeNil())
+			Expect(result.RowsAffected).To(BeEquivalentTo(3))
+			Expect(result.Errors).To(BeEmpty())
+			Expect(count()).To(Equal(3))
+		})
+
+		It("does not execute anything when there are no rows", func() {
+			result, err := batch.Exec(context.Background(), [][]sqlexec.Param{})
+			Expect(err).To(BeNil())
+			Expect(result.Batches).To(BeZero())
+		})
+
+		Context("when a batch fails", func() {
+			It("reports the rows of the failed batch", func() {
+				args := rows(5)
+				args[2][0] = 1
+
+				result, err := batch.Exec(context.Background(), args)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(HavePrefix("batch of rows 2-3 failed at row 2: UNIQUE constraint failed"))
+
+				Expect(result.RowsAffected).To(BeEquivalentTo(3))
+				Expect(result.Batches).To(Equal(3))
+				Expect(result.Errors).To(HaveLen(1))
+				Expect(result.Errors[0].Start).To(Equal(2))
+				Expect(result.Errors[0].End).To(Equal(4))
+				Expect(result.Errors[0].Row).To(Equal(2))
+				Expect(count()).To(Equal(3))
+			})
+
+			It("reports the failed row of the transaction", func() {
+				_, err := db.Exec("CREATE TRIGGER reject BEFORE UPDATE ON users WHEN NEW.name = '' BEGIN SELECT RAISE(ABORT, 'empty name'); END")
+				Expect(err).To(BeNil())
+
+				_, err = batch.Exec(context.Background(), rows(4))
+				Expect(err).To(BeNil())
+
+				batch.Routine = "rename-user"
+
+				result, err := batch.Exec(context.Background(), [][]sqlexec.Param{
+					{"John", 1},
+					{"Jane", 2},
+					{"Jack", 3},
+					{"", 4},
+				})
+
+				Expect(err).To(MatchError("batch of rows 2-3 failed at row 3: empty name"))
+				Expect(result.RowsAffected).To(BeEquivalentTo(2))
+				Expect(result.Errors).To(HaveLen(1))
+				Expect(result.Errors[0].Row).To(Equal(3))
+
+				names := []string{}
+				Expect(db.Select(&names, "SELECT name FROM users ORDER BY id")).To(Succeed())
+				Expect(names).To(Equal([]string{"John", "Jane", "user-3", "user-4"}))
+			})
+		})
+
+		Context("when the routine is a template", func() {
+			It("returns an error", func() {
+				batch.Routine = "select-users"
+
+				result, err := batch.Exec(context.Background(), rows(1))
+				Expect(err).To(MatchError("routine 'select-users' is a template that should be rendered with named parameters"))
+				Expect(result).To(BeNil())
+			})
+		})
+
+		Context("when the routine does not exist", func() {
+			It("returns an error", func() {
+				batch.Routine = "unknown"
+
+				result, err := batch.Exec(context.Background(), rows(1))
+				Expect(err).To(MatchError("query 'unknown' not found"))
+				Expect(result).To(BeNil())
+			})
+		})
+	})
+
+	Context("when the driver is postgres", func() {
+		var mock sqlmock.Sqlmock
+
+		BeforeEach(func() {
+			conn, m, err := sqlmock.New()
+			Expect(err).To(BeNil())
+
+			mock = m
+			db = sqlx.NewDb(conn, "postgres")
+
+			gateway = &sqlexec.Gateway{
+				Provider: routines("postgres"),
+				DB:       db,
+			}
+
+			batch = &sqlexec.Batch{
+				Gateway: gateway,
+				Routine: "insert-user",
+			}
+		})
+
+		AfterEach(func() {
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("inserts the rows with copy", func() {
+			mock.ExpectBegin()
+			mock.ExpectPrepare(`COPY "users" \("id", "name"\) FROM STDIN`)
+			mock.ExpectExec(`COPY "users"`).WithArgs(1, "user-1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`COPY "users"`).WithArgs(2, "user-2").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`COPY "users"`).WillReturnResult(sqlmock.NewResult(0, 2))
+			mock.ExpectCommit()
+
+			result, err := batch.Exec(context.Background(), rows(2))
+			Expect(err).To(BeNil())
+			Expect(result.RowsAffected).To(BeEquivalentTo(2))
+			Expect(result.Batches).To(Equal(1))
+		})
+
+		It("reports the failed row of the copy", func() {
+			mock.ExpectBegin()
+			mock.ExpectPrepare(`COPY "users" \("id", "name"\) FROM STDIN`)
+			mock.ExpectExec(`COPY "users"`).WithArgs(1, "user-1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`COPY "users"`).WithArgs(2, "user-2").WillReturnError(fmt.Errorf("oh no"))
+			mock.ExpectRollback()
+
+			result, err := batch.Exec(context.Background(), rows(2))
+			Expect(err).To(MatchError("batch of rows 0-1 failed at row 1: oh no"))
+			Expect(result.RowsAffected).To(BeZero())
+		})
+
+		It("reports the row of the copy error", func() {
+			failure := &pq.Error{
+				Message: "duplicate key value violates unique constraint",
+				Where:   "COPY users, line 2",
+			}
+
+			mock.ExpectBegin()
+			mock.ExpectPrepare(`COPY "users" \("id", "name"\) FROM STDIN`)
+			mock.ExpectExec(`COPY "users"`).WithArgs(1, "user-1").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`COPY "users"`).WithArgs(2, "user-2").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`COPY "users"`).WillReturnError(failure)
+			mock.ExpectRollback()
+
+			result, err := batch.Exec(context.Background(), rows(2))
+			Expect(err).To(HaveOccurred())
+			Expect(result.Errors).To(HaveLen(1))
+			Expect(result.Errors[0].Row).To(Equal(1))
+		})
+
+		It("inserts the rows with expressions as multiple values", func() {
+			batch.Routine = "insert-named-user"
+
+			mock.ExpectExec(`INSERT INTO users \(id, name\) VALUES \(\$1, upper\(\$2\)\), \(\$3, upper\(\$4\)\)$`).
+				WithArgs(1, "john", 2, "jane").
+				WillReturnResult(sqlmock.NewResult(0, 2))
+
+			result, err := batch.ExecNamed(context.Background(), []sqlexec.Param{
+				map[string]interface{}{"id": 1, "name": "john"},
+				map[string]interface{}{"id": 2, "name": "jane"},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(result.RowsAffected).To(BeEquivalentTo(2))
+		})
+
+		It("inserts the rows of the failed values one by one to find the failed row", func() {
+			batch.Routine = "insert-named-user"
+
+			mock.ExpectExec(`INSERT INTO users \(id, name\) VALUES \(\$1, upper\(\$2\)\), \(\$3, upper\(\$4\)\)$`).
+				WithArgs(1, "john", 2, "jane").
+				WillReturnError(fmt.Errorf("oh no"))
+			mock.ExpectBegin()
+			mock.ExpectPrepare(`INSERT INTO users \(id, name\) VALUES \(\$1, upper\(\$2\)\)$`)
+			mock.ExpectExec(`INSERT INTO users`).WithArgs(1, "john").WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(`INSERT INTO users`).WithArgs(2, "jane").WillReturnError(fmt.Errorf("oh no"))
+			mock.ExpectRollback()
+
+			result, err := batch.ExecNamed(context.Background(), []sqlexec.Param{
+				map[string]interface{}{"id": 1, "name": "john"},
+				map[string]interface{}{"id": 2, "name": "jane"},
+			})
+
+			Expect(err).To(MatchError("batch of rows 0-1 failed at row 1: oh no"))
+			Expect(result.RowsAffected).To(BeZero())
+		})
+	})
+})
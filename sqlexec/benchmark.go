@@ -0,0 +1,318 @@
+package sqlexec
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBenchmarkIterations is the number of iterations of a benchmark that
+// has neither iterations nor duration.
+const DefaultBenchmarkIterations = 100
+
+// ParamSet is the set of parameters of a single routine execution.
+type ParamSet struct {
+	// Args are the positional parameters
+	Args []Param
+	// Arg are the named parameters. The routine is executed with named
+	// parameters if it is not nil.
+	Arg map[string]Param
+}
+
+// ReadParamSets reads the parameter sets of a routine from CSV. The first
+// record is a header that contains the names of the parameters. The values
+// of routines with positional parameters are passed in the order of the
+// columns. The values are parsed with ParseParam.
+func ReadParamSets(reader io.Reader, routine *Routine) ([]ParamSet, error) {
+	records, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("the parameters do not have a header")
+	}
+
+	header, sets := records[0], []ParamSet{}
+
+	for _, record := range records[1:] {
+		set := ParamSet{}
+
+		if routine.IsNamed() {
+			values := make(map[string]string, len(header))
+
+			for index, name := range header {
+				values[name] = record[index]
+			}
+
+			set.Arg, err = routine.ParseNamedParams(values)
+		} else {
+			set.Args, err = routine.ParseParams(record)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		sets = append(sets, set)
+	}
+
+	return sets, nil
+}
+
+// BenchmarkResult is the result of a benchmark. The latencies are
+// serialized in nanoseconds.
+type BenchmarkResult struct {
+	// Routine is the name of the benchmarked routine
+	Routine string `json:"routine"`
+	// Iterations is the number of executions
+	Iterations int `json:"iterations"`
+	// Concurrency is the number of concurrent executions
+	Concurrency int `json:"concurrency"`
+	// Elapsed is the duration of the benchmark
+	Elapsed time.Duration `json:"elapsed"`
+	// Throughput is the number of executions per second
+	Throughput float64 `json:"throughput"`
+	// Min is the minimum latency
+	Min time.Duration `json:"min"`
+	// Mean is the average latency
+	Mean time.Duration `json:"mean"`
+	// P50 is the median latency
+	P50 time.Duration `json:"p50"`
+	// P95 is the 95th percentile of the latency
+	P95 time.Duration `json:"p95"`
+	// P99 is the 99th percentile of the latency
+	P99 time.Duration `json:"p99"`
+	// Max is the maximum latency
+	Max time.Duration `json:"max"`
+}
+
+// Regressions returns the metrics that are worse than the baseline by more
+// than the tolerance, which is a fraction of the baseline value.
+func (r *BenchmarkResult) Regressions(baseline *BenchmarkResult, tolerance float64) []string {
+	regressions := []string{}
+
+	latencies := []struct {
+		name     string
+		current  time.Duration
+		baseline time.Duration
+	}{
+		{"p50", r.P50, baseline.P50},
+		{"p95", r.P95, baseline.P95},
+		{"p99", r.P99, baseline.P99},
+	}
+
+	for _, latency := range latencies {
+		if latency.baseline > 0 && float64(latency.current) > float64(latency.baseline)*(1+tolerance) {
+			change := 100 * (float64(latency.current)/float64(latency.baseline) - 1)
+			regressions = append(regressions, fmt.Sprintf("%s latency %v is %.1f%% slower than the baseline %v",
+				latency.name, latency.current, change, latency.baseline))
+		}
+	}
+
+	if baseline.Throughput > 0 && r.Throughput < baseline.Throughput*(1-tolerance) {
+		change := 100 * (1 - r.Throughput/baseline.Throughput)
+		regressions = append(regressions, fmt.Sprintf("throughput %.1f/s is %.1f%% lower than the baseline %.1f/s",
+			r.Throughput, change, baseline.Throughput))
+	}
+
+	return regressions
+}
+
+// Benchmark executes a routine repeatedly and measures its latency.
+type Benchmark struct {
+	// Gateway executes the routine with prepared statements.
+	Gateway *Gateway
+	// Routine is the name of the routine
+	Routine string
+	// Iterations is the number of executions
+	Iterations int
+	// Duration limits the time of the benchmark. The benchmark runs until
+	// the duration elapses if the iterations are not provided.
+	Duration time.Duration
+	// Concurrency is the number of concurrent executions. Defaults to 1.
+	Concurrency int
+	// Params are the parameter sets that the executions use in turn
+	Params []ParamSet
+}
+
+// Run runs the benchmark. It stops at the first failed execution.
+func (b *Benchmark) Run(ctx context.Context) (*BenchmarkResult, error) {
+	routine, err := b.Gateway.Provider.Routine(b.Routine)
+	if err != nil {
+		return nil, err
+	}
+
+	iterations, concurrency := b.Iterations, b.Concurrency
+
+	if iterations <= 0 && b.Duration <= 0 {
+		iterations = DefaultBenchmarkIterations
+	}
+
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	if b.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.Duration)
+		defer cancel()
+	}
+
+	var (
+		counter   int64
+		group     sync.WaitGroup
+		mu        sync.Mutex
+		failure   error
+		latencies = make([][]time.Duration, concurrency)
+	)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := time.Now()
+
+	for worker := 0; worker < concurrency; worker++ {
+		group.Add(1)
+
+		go func(worker int) {
+			defer group.Done()
+
+			for ctx.Err() == nil {
+				iteration := atomic.AddInt64(&counter, 1) - 1
+
+				if iterations > 0 && iteration >= int64(iterations) {
+					return
+				}
+
+				began := time.Now()
+
+				if err := b.execute(routine, b.params(iteration)); err != nil {
+					mu.Lock()
+					if failure == nil {
+						failure = err
+					}
+					mu.Unlock()
+
+					cancel()
+					return
+				}
+
+				latencies[worker] = append(latencies[worker], time.Since(began))
+			}
+		}(worker)
+	}
+
+	group.Wait()
+
+	if failure != nil {
+		return nil, failure
+	}
+
+	elapsed := time.Since(start)
+
+	all := []time.Duration{}
+	for _, items := range latencies {
+		all = append(all, items...)
+	}
+
+	return b.result(all, concurrency, elapsed), nil
+}
+
+func (b *Benchmark) params(iteration int64) ParamSet {
+	if len(b.Params) == 0 {
+		return ParamSet{}
+	}
+
+	return b.Params[iteration%int64(len(b.Params))]
+}
+
+func (b *Benchmark) execute(routine *Routine, set ParamSet) error {
+	if !routine.IsQuery() {
+		var err error
+
+		if set.Arg != nil {
+			_, err = b.Gateway.ExecNamed(routine.Name, set.Arg)
+		} else {
+			_, err = b.Gateway.Exec(routine.Name, set.Args...)
+		}
+
+		return err
+	}
+
+	var (
+		rows *Rows
+		err  error
+	)
+
+	if set.Arg != nil {
+		rows, err = b.Gateway.RunNamed(routine.Name, set.Arg)
+	} else {
+		rows, err = b.Gateway.Run(routine.Name, set.Args...)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// the rows are fetched in order to measure the whole execution
+	for rows.Next() {
+	}
+
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+
+	return rows.Close()
+}
+
+func (b *Benchmark) result(latencies []time.Duration, concurrency int, elapsed time.Duration) *BenchmarkResult {
+	result := &BenchmarkResult{
+		Routine:     b.Routine,
+		Iterations:  len(latencies),
+		Concurrency: concurrency,
+		Elapsed:     elapsed,
+	}
+
+	if len(latencies) == 0 {
+		return result
+	}
+
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+
+	var total time.Duration
+
+	for _, latency := range latencies {
+		total += latency
+	}
+
+	result.Throughput = float64(len(latencies)) / elapsed.Seconds()
+	result.Min = latencies[0]
+	result.Max = latencies[len(latencies)-1]
+	result.Mean = total / time.Duration(len(latencies))
+	result.P50 = percentile(latencies, 50)
+	result.P95 = percentile(latencies, 95)
+	result.P99 = percentile(latencies, 99)
+
+	return result
+}
+
+// percentile returns the nearest-rank percentile of the sorted latencies
+func percentile(latencies []time.Duration, rank float64) time.Duration {
+	index := int(math.Ceil(rank/100*float64(len(latencies)))) - 1
+
+	if index < 0 {
+		index = 0
+	}
+
+	return latencies[index]
+}
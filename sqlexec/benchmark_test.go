@@ -0,0 +1,274 @@
+package sqlexec_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/prana/sqlexec"
+)
+
+var _ = Describe("Benchmark", func() {
+	var (
+		benchmark *sqlexec.Benchmark
+		gateway   *sqlexec.Gateway
+	)
+
+	BeforeEach(func() {
+		db, err := sqlx.Open("sqlite3", ":memory:")
+		Expect(err).To(BeNil())
+		db.SetMaxOpenConns(1)
+
+		_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+		Expect(err).To(BeNil())
+
+		_, err = db.Exec("INSERT INTO users VALUES (1, 'John'), (2, 'Jane')")
+		Expect(err).To(BeNil())
+
+		script := &bytes.Buffer{}
+		fmt.Fprintln(script, "-- name: select-user")
+		fmt.Fprintln(script, "SELECT * FROM users WHERE id = ?")
+		fmt.Fprintln(script)
+		fmt.Fprintln(script, "-- name: select-named-user")
+		fmt.Fprintln(script, "SELECT * FROM users WHERE id = :id")
+		fmt.Fprintln(script)
+		fmt.Fprintln(script, "-- name: select-users")
+		fmt.Fprintln(script, "SELECT * FROM users WHERE id IN (?)")
+		fmt.Fprintln(script)
+		fmt.Fprintln(script, "-- name: touch-user")
+		fmt.Fprintln(script, "UPDATE users SET name = name WHERE id = ?")
+		fmt.Fprintln(script)
+		fmt.Fprintln(script, "-- name: select-unknown")
+		fmt.Fprintln(script, "SELECT * FROM unknown")
+
+		provider := &sqlexec.Provider{DriverName: "sqlite3"}
+		_, err = provider.ReadFrom(script)
+		Expect(err).To(BeNil())
+
+		gateway = &sqlexec.Gateway{
+			Provider: provider,
+			DB:       db,
+		}
+
+		benchmark = &sqlexec.Benchmark{
+			Gateway:    gateway,
+			Routine:    "select-user",
+			Iterations: 20,
+			Params: []sqlexec.ParamSet{
+				{Args: []sqlexec.Param{1}},
+				{Args: []sqlexec.Param{2}},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(gateway.Close()).To(Succeed())
+		Expect(gateway.DB.Close()).To(Succeed())
+	})
+
+	It("runs the routine for given iterations", func() {
+		result, err := benchmark.Run(context.Background())
+		Expect(err).To(BeNil())
+		Expect(result.Routine).To(Equal("select-user"))
+		Expect(result.Iterations).To(Equal(20))
+		Expect(result.Concurrency).To(Equal(1))
+		Expect(result.Throughput).To(BeNumerically(">", 0))
+		Expect(result.Min).To(BeNumerically("<=", result.P50))
+		Expect(result.P50).To(BeNumerically("<=", result.P95))
+		Expect(result.P95).To(BeNumerically("<=", result.P99))
+		Expect(result.P99).To(BeNumerically("<=", result.Max))
+
+		Expect(gateway.Stats().Misses).To(BeEquivalentTo(1))
+	})
+
+	It("runs the routine concurrently", func() {
+		benchmark.Concurrency = 4
+
+		result, err := benchmark.Run(context.Background())
+		Expect(err).To(BeNil())
+		Expect(result.Iterations).To(Equal(20))
+		Expect(result.Concurrency).To(Equal(4))
+	})
+
+	It("runs the routine for given duration", func() {
+		benchmark.Iterations = 0
+		benchmark.Duration = 50 * time.Millisecond
+
+		result, err := benchmark.Run(context.Background())
+		Expect(err).To(BeNil())
+		Expect(result.Iterations).To(BeNumerically(">", 0))
+		Expect(result.Elapsed).To(BeNumerically(">=", 50*time.Millisecond))
+	})
+
+	It("runs the named routines", func() {
+		benchmark.Routine = "select-named-user"
+		benchmark.Params = []sqlexec.ParamSet{
+			{Arg: map[string]sqlexec.Param{"id": 1}},
+		}
+
+		result, err := benchmark.Run(context.Background())
+		Expect(err).To(BeNil())
+		Expect(result.Iterations).To(Equal(20))
+	})
+
+	It("runs the routines that have slice parameters", func() {
+		benchmark.Routine = "select-users"
+		benchmark.Params = []sqlexec.ParamSet{
+			{Args: []sqlexec.Param{[]int64{1, 2}}},
+		}
+
+		result, err := benchmark.Run(context.Background())
+		Expect(err).To(BeNil())
+		Expect(result.Iterations).To(Equal(20))
+	})
+
+	It("runs the routines that do not return rows", func() {
+		benchmark.Routine = "touch-user"
+
+		result, err := benchmark.Run(context.Background())
+		Expect(err).To(BeNil())
+		Expect(result.Iterations).To(Equal(20))
+	})
+
+	Context("when neither iterations nor duration are provided", func() {
+		It("uses the default iterations", func() {
+			benchmark.Iterations = 0
+
+			result, err := benchmark.Run(context.Background())
+			Expect(err).To(BeNil())
+			Expect(result.Iterations).To(Equal(sqlexec.DefaultBenchmarkIterations))
+		})
+	})
+
+	Context("when the routine fails", func() {
+		It("returns the error", func() {
+			benchmark.Routine = "select-unknown"
+			benchmark.Concurrency = 2
+
+			_, err := benchmark.Run(context.Background())
+			Expect(err).To(MatchError("no such table: unknown"))
+		})
+	})
+
+	Context("when the routine does not exist", func() {
+		It("returns an error", func() {
+			benchmark.Routine = "unknown"
+
+			_, err := benchmark.Run(context.Background())
+			Expect(err).To(MatchError("query 'unknown' not found"))
+		})
+	})
+
+	Describe("ReadParamSets", func() {
+		It("reads the positional params in the order of the columns", func() {
+			routine := &sqlexec.Routine{
+				Name:   "select-user",
+				Query:  "SELECT * FROM users WHERE id = ? AND name = ?",
+				Params: []sqlexec.RoutineParam{{Name: "id", Type: "int"}},
+			}
+
+			sets, err := sqlexec.ReadParamSets(strings.NewReader("id,name\n1,John\n2,null:\n"), routine)
+			Expect(err).To(BeNil())
+			Expect(sets).To(Equal([]sqlexec.ParamSet{
+				{Args: []sqlexec.Param{int64(1), "John"}},
+				{Args: []sqlexec.Param{int64(2), nil}},
+			}))
+		})
+
+		It("reads the named params by the header", func() {
+			routine := &sqlexec.Routine{
+				Name:  "select-user",
+				Query: "SELECT * FROM users WHERE id = :id AND name = :name",
+			}
+
+			sets, err := sqlexec.ReadParamSets(strings.NewReader("name,id\nJohn,int:1\n"), routine)
+			Expect(err).To(BeNil())
+			Expect(sets).To(Equal([]sqlexec.ParamSet{
+				{Arg: map[string]sqlexec.Param{"id": int64(1), "name": "John"}},
+			}))
+		})
+
+		Context("when the header is missing", func() {
+			It("returns an error", func() {
+				_, err := sqlexec.ReadParamSets(strings.NewReader(""), &sqlexec.Routine{})
+				Expect(err).To(MatchError("the parameters do not have a header"))
+			})
+		})
+
+		Context("when the value is not valid", func() {
+			It("returns an error", func() {
+				routine := &sqlexec.Routine{
+					Query:  "SELECT * FROM users WHERE id = ?",
+					Params: []sqlexec.RoutineParam{{Name: "id", Type: "int"}},
+				}
+
+				_, err := sqlexec.ReadParamSets(strings.NewReader("id\none\n"), routine)
+				Expect(err).To(MatchError("param 'one' is not a valid int"))
+			})
+		})
+	})
+
+	Describe("BenchmarkResult", func() {
+		var baseline *sqlexec.BenchmarkResult
+
+		BeforeEach(func() {
+			baseline = &sqlexec.BenchmarkResult{
+				Iterations: 100,
+				Throughput: 100,
+				P50:        10 * time.Millisecond,
+				P95:        20 * time.Millisecond,
+				P99:        30 * time.Millisecond,
+			}
+		})
+
+		It("does not report changes within the tolerance", func() {
+			result := *baseline
+			result.P95 = 21 * time.Millisecond
+			result.Throughput = 95
+
+			Expect(result.Regressions(baseline, 0.1)).To(BeEmpty())
+		})
+
+		It("reports the regressions", func() {
+			result := *baseline
+			result.P99 = 45 * time.Millisecond
+			result.Throughput = 50
+
+			Expect(result.Regressions(baseline, 0.1)).To(Equal([]string{
+				"p99 latency 45ms is 50.0% slower than the baseline 30ms",
+				"throughput 50.0/s is 50.0% lower than the baseline 100.0/s",
+			}))
+		})
+
+		Describe("Fbench", func() {
+			It("prints the result", func() {
+				w := &bytes.Buffer{}
+				sqlexec.Fbench(w, baseline, nil)
+
+				content := w.String()
+				Expect(content).To(ContainSubstring("ITERATIONS"))
+				Expect(content).To(ContainSubstring("100.0/s"))
+				Expect(content).To(ContainSubstring("P95"))
+				Expect(content).To(ContainSubstring("20ms"))
+				Expect(content).NotTo(ContainSubstring("BASELINE"))
+			})
+
+			It("prints the comparison with the baseline", func() {
+				result := *baseline
+				result.P95 = 30 * time.Millisecond
+
+				w := &bytes.Buffer{}
+				sqlexec.Fbench(w, &result, baseline)
+
+				content := w.String()
+				Expect(content).To(ContainSubstring("BASELINE"))
+				Expect(content).To(MatchRegexp(`P95\s+30ms\s+20ms\s+\+50.0%`))
+			})
+		})
+	})
+})
@@ -0,0 +1,54 @@
+package sqlexec
+
+import (
+	"database/sql"
+	"reflect"
+	"time"
+)
+
+// Cursor iterates over the rows of a routine one by one. It allows
+// processing of large result sets without loading them into memory.
+type Cursor struct {
+	rows *Rows
+}
+
+// Next prepares the next row for scanning. It returns false if there are no
+// more rows or an error occurred.
+func (c *Cursor) Next() bool {
+	return c.rows.Next()
+}
+
+// Scan scans the current row into a given destination. The destination can
+// be a pointer to a struct or to a scalar value.
+func (c *Cursor) Scan(dest interface{}) error {
+	if scannable(dest) {
+		return c.rows.StructScan(dest)
+	}
+
+	return c.rows.Scan(dest)
+}
+
+// Err returns the error that occurred during the iteration.
+func (c *Cursor) Err() error {
+	return c.rows.Err()
+}
+
+// Close closes the cursor.
+func (c *Cursor) Close() error {
+	return c.rows.Close()
+}
+
+// scannable returns true if the destination should be scanned as a struct
+func scannable(dest interface{}) bool {
+	if _, ok := dest.(sql.Scanner); ok {
+		return false
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(dest))
+
+	if value.Kind() != reflect.Struct {
+		return false
+	}
+
+	return value.Type() != reflect.TypeOf(time.Time{})
+}
@@ -0,0 +1,576 @@
+package sqlexec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultScanThreshold is the number of rows above which a sequential scan
+// of a table is reported.
+const DefaultScanThreshold = 1000
+
+var (
+	alias       = regexp.MustCompile(`(?i)\b(?:FROM|JOIN)\s+"?([\w.]+)"?(?:\s+(?:AS\s+)?(\w+))?`)
+	sqliteScan  = regexp.MustCompile(`^SCAN (?:TABLE )?(\w+)`)
+	mysqlLine   = regexp.MustCompile(`^(\s*)-> (.*?)(?:  (\(.*))?$`)
+	mysqlScan   = regexp.MustCompile(`^Table scan on (\w+)`)
+	mysqlRows   = regexp.MustCompile(`rows=(\d+)`)
+	sqlKeywords = map[string]bool{
+		"WHERE": true, "JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true,
+		"FULL": true, "CROSS": true, "OUTER": true, "ON": true, "USING": true,
+		"GROUP": true, "ORDER": true, "LIMIT": true, "UNION": true, "SET": true,
+		"NATURAL": true, "HAVING": true, "OFFSET": true, "VALUES": true,
+	}
+)
+
+// PlanNode is an operation of a query plan.
+type PlanNode struct {
+	// Operation is the description of the operation
+	Operation string
+	// Table is the table that the operation accesses
+	Table string
+	// Rows is the estimated or, if the plan is analyzed, actual number of rows
+	Rows int64
+	// Detail contains the cost and the timing of the operation
+	Detail string
+	// Warning reports a possible performance problem of the operation
+	Warning string
+	// Children are the operations whose output is used by this operation
+	Children []*PlanNode
+}
+
+// Plan is the query plan of a routine.
+type Plan struct {
+	// Routine is the name of the explained routine
+	Routine string
+	// Driver is the name of the database driver
+	Driver string
+	// Nodes are the root operations of the plan
+	Nodes []*PlanNode
+}
+
+// Warnings returns the warnings of all operations in the plan.
+func (p *Plan) Warnings() []string {
+	warnings := []string{}
+
+	var walk func(nodes []*PlanNode)
+
+	walk = func(nodes []*PlanNode) {
+		for _, node := range nodes {
+			if node.Warning != "" {
+				warnings = append(warnings, node.Warning)
+			}
+			walk(node.Children)
+		}
+	}
+
+	walk(p.Nodes)
+	return warnings
+}
+
+// Explainer explains the query plan of the routines by using the EXPLAIN
+// syntax of the database driver.
+type Explainer struct {
+	// FileSystem represents the project directory file system.
+	FileSystem FileSystem
+	// DB is a client to underlying database.
+	DB *sqlx.DB
+	// Analyze executes the routine in a transaction that is rolled back in
+	// order to report the actual number of rows and timing.
+	Analyze bool
+	// Threshold is the number of rows above which a sequential scan is
+	// reported. Defaults to DefaultScanThreshold.
+	Threshold int64
+	// Count counts the rows of the scanned SQLite tables that have no
+	// statistics collected by ANALYZE. Note that it reads the whole tables.
+	Count bool
+}
+
+// Explain explains the query plan of a routine for given parameters.
+func (e *Explainer) Explain(name string, args ...Param) (*Plan, error) {
+	provider, err := e.provider()
+	if err != nil {
+		return nil, err
+	}
+
+	query, args, err := provider.Bind(name, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.explain(name, trim(query), args)
+}
+
+// ExplainNamed explains the query plan of a routine that has named
+// parameters. The argument can be a struct or map[string]interface{}.
+func (e *Explainer) ExplainNamed(name string, arg Param) (*Plan, error) {
+	provider, err := e.provider()
+	if err != nil {
+		return nil, err
+	}
+
+	query, args, err := provider.BindNamed(name, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.explain(name, trim(query), args)
+}
+
+func (e *Explainer) provider() (*Provider, error) {
+	provider := &Provider{
+		DriverName: e.DB.DriverName(),
+		Mapper:     e.DB.Mapper,
+	}
+
+	if err := provider.ReadDir(e.FileSystem); err != nil {
+		return nil, err
+	}
+
+	return provider, nil
+}
+
+// trim removes the trailing semicolon, which cannot follow EXPLAIN options
+func trim(query string) string {
+	return strings.TrimRight(query, "; \t\n")
+}
+
+func (e *Explainer) explain(name, query string, args []interface{}) (*Plan, error) {
+	driver := e.DB.DriverName()
+
+	statement, err := e.statement(driver, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// the analyzed statement is executed, so its changes have to be reverted
+	tx, err := e.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	rows, err := tx.Queryx(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	records := [][]interface{}{}
+
+	for rows.Next() {
+		record, err := rows.SliceScan()
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{
+		Routine: name,
+		Driver:  driver,
+	}
+
+	switch {
+	case driver == "sqlite3":
+		plan.Nodes = e.sqlite(tx, query, records)
+	case driver == "postgres":
+		plan.Nodes, err = e.postgres(records)
+	case driver == "mysql" && e.Analyze:
+		plan.Nodes = e.mysqlTree(records)
+	case driver == "mysql":
+		plan.Nodes, err = e.mysql(records)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+func (e *Explainer) statement(driver, query string) (string, error) {
+	switch driver {
+	case "sqlite3":
+		if e.Analyze {
+			return "", fmt.Errorf("explain analyze is not supported by '%s'", driver)
+		}
+		return "EXPLAIN QUERY PLAN " + query, nil
+	case "postgres":
+		if e.Analyze {
+			return "EXPLAIN (ANALYZE, FORMAT JSON) " + query, nil
+		}
+		return "EXPLAIN (FORMAT JSON) " + query, nil
+	case "mysql":
+		if e.Analyze {
+			return "EXPLAIN ANALYZE " + query, nil
+		}
+		return "EXPLAIN FORMAT=JSON " + query, nil
+	default:
+		return "", fmt.Errorf("explain is not supported by '%s'", driver)
+	}
+}
+
+func (e *Explainer) threshold() int64 {
+	if e.Threshold <= 0 {
+		return DefaultScanThreshold
+	}
+	return e.Threshold
+}
+
+func (e *Explainer) warn(node *PlanNode) {
+	if node.Rows >= e.threshold() {
+		node.Warning = fmt.Sprintf("sequential scan on table '%s' with %d rows", node.Table, node.Rows)
+	}
+}
+
+// sqlite builds the plan from the id, parent, notused and detail columns
+// of EXPLAIN QUERY PLAN
+func (e *Explainer) sqlite(tx *sqlx.Tx, query string, records [][]interface{}) []*PlanNode {
+	var (
+		roots  = []*PlanNode{}
+		nodes  = make(map[int64]*PlanNode)
+		tables = aliases(query)
+	)
+
+	for _, record := range records {
+		node := &PlanNode{
+			Operation: text(record[len(record)-1]),
+		}
+
+		if matches := sqliteScan.FindStringSubmatch(node.Operation); matches != nil && !strings.Contains(node.Operation, " USING ") {
+			node.Table = matches[1]
+
+			if table, ok := tables[node.Table]; ok {
+				node.Table = table
+			}
+
+			// SQLite does not estimate the rows of a scan
+			if rows, ok := e.sqliteRows(tx, node.Table); ok {
+				node.Rows = rows
+				e.warn(node)
+			}
+		}
+
+		// the older versions of SQLite do not report the parent
+		if len(record) != 4 {
+			roots = append(roots, node)
+			continue
+		}
+
+		id, parent := number(record[0]), number(record[1])
+		nodes[id] = node
+
+		if owner, ok := nodes[parent]; ok {
+			owner.Children = append(owner.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	return roots
+}
+
+// sqliteRows returns the number of rows of a table from the statistics
+// collected by ANALYZE or by counting them if it is enabled
+func (e *Explainer) sqliteRows(tx *sqlx.Tx, table string) (int64, bool) {
+	stats := []string{}
+	query := "SELECT stat FROM sqlite_stat1 WHERE tbl = ? ORDER BY idx IS NOT NULL"
+
+	if err := tx.Select(&stats, query, table); err == nil && len(stats) > 0 {
+		// the first number of the statistics is the number of rows
+		if fields := strings.Fields(stats[0]); len(fields) > 0 {
+			if rows, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+				return rows, true
+			}
+		}
+	}
+
+	if !e.Count {
+		return 0, false
+	}
+
+	var rows int64
+
+	if err := tx.Get(&rows, fmt.Sprintf("SELECT count(*) FROM %q", table)); err != nil {
+		return 0, false
+	}
+
+	return rows, true
+}
+
+// postgres builds the plan from the output of EXPLAIN (FORMAT JSON)
+func (e *Explainer) postgres(records [][]interface{}) ([]*PlanNode, error) {
+	type plan struct {
+		NodeType    string  `json:"Node Type"`
+		Relation    string  `json:"Relation Name"`
+		Index       string  `json:"Index Name"`
+		StartupCost float64 `json:"Startup Cost"`
+		TotalCost   float64 `json:"Total Cost"`
+		PlanRows    float64 `json:"Plan Rows"`
+		ActualRows  float64 `json:"Actual Rows"`
+		ActualStart float64 `json:"Actual Startup Time"`
+		ActualTotal float64 `json:"Actual Total Time"`
+		Plans       []json.RawMessage
+	}
+
+	output := []struct {
+		Plan json.RawMessage
+	}{}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("explain did not return a plan")
+	}
+
+	if err := json.Unmarshal([]byte(text(records[0][0])), &output); err != nil {
+		return nil, err
+	}
+
+	var build func(data json.RawMessage) (*PlanNode, error)
+
+	build = func(data json.RawMessage) (*PlanNode, error) {
+		current := plan{}
+
+		if err := json.Unmarshal(data, &current); err != nil {
+			return nil, err
+		}
+
+		node := &PlanNode{
+			Operation: current.NodeType,
+			Table:     current.Relation,
+			Rows:      int64(current.PlanRows),
+			Detail:    fmt.Sprintf("cost=%.2f..%.2f", current.StartupCost, current.TotalCost),
+		}
+
+		if current.Relation != "" {
+			node.Operation = fmt.Sprintf("%s on %s", node.Operation, current.Relation)
+		}
+
+		if current.Index != "" {
+			node.Operation = fmt.Sprintf("%s using %s", node.Operation, current.Index)
+		}
+
+		if e.Analyze {
+			node.Rows = int64(current.ActualRows)
+			node.Detail = fmt.Sprintf("%s time=%.3f..%.3f", node.Detail, current.ActualStart, current.ActualTotal)
+		}
+
+		if current.NodeType == "Seq Scan" {
+			e.warn(node)
+		}
+
+		for _, child := range current.Plans {
+			childNode, err := build(child)
+			if err != nil {
+				return nil, err
+			}
+
+			node.Children = append(node.Children, childNode)
+		}
+
+		return node, nil
+	}
+
+	nodes := []*PlanNode{}
+
+	for _, item := range output {
+		node, err := build(item.Plan)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+// mysql builds the plan from the output of EXPLAIN FORMAT=JSON
+func (e *Explainer) mysql(records [][]interface{}) ([]*PlanNode, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("explain did not return a plan")
+	}
+
+	output := make(map[string]interface{})
+
+	decoder := json.NewDecoder(strings.NewReader(text(records[0][0])))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&output); err != nil {
+		return nil, err
+	}
+
+	return e.mysqlNodes(output), nil
+}
+
+// mysqlNodes converts the nested operations of the JSON plan to nodes
+func (e *Explainer) mysqlNodes(object map[string]interface{}) []*PlanNode {
+	keys := []string{}
+
+	for key := range object {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	nodes := []*PlanNode{}
+
+	for _, key := range keys {
+		if key == "cost_info" {
+			continue
+		}
+
+		switch value := object[key].(type) {
+		case map[string]interface{}:
+			nodes = append(nodes, e.mysqlNode(key, value))
+		case []interface{}:
+			node := &PlanNode{Operation: key}
+
+			for _, item := range value {
+				if child, ok := item.(map[string]interface{}); ok {
+					node.Children = append(node.Children, e.mysqlNodes(child)...)
+				}
+			}
+
+			if len(node.Children) > 0 {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+
+	return nodes
+}
+
+func (e *Explainer) mysqlNode(key string, object map[string]interface{}) *PlanNode {
+	node := &PlanNode{
+		Operation: key,
+		Children:  e.mysqlNodes(object),
+	}
+
+	if message, ok := object["message"]; ok {
+		node.Detail = text(message)
+	}
+
+	if table, ok := object["table_name"]; ok {
+		access := text(object["access_type"])
+
+		node.Table = text(table)
+		node.Operation = fmt.Sprintf("table %s", node.Table)
+		node.Detail = fmt.Sprintf("access_type=%s", access)
+		node.Rows = number(object["rows_examined_per_scan"])
+
+		if index, ok := object["key"]; ok {
+			node.Operation = fmt.Sprintf("%s using %s", node.Operation, text(index))
+		}
+
+		if access == "ALL" {
+			e.warn(node)
+		}
+	}
+
+	return node
+}
+
+// mysqlTree builds the plan from the indented output of EXPLAIN ANALYZE
+func (e *Explainer) mysqlTree(records [][]interface{}) []*PlanNode {
+	var (
+		roots = []*PlanNode{}
+		stack = []*PlanNode{}
+		depth = []int{}
+	)
+
+	for _, record := range records {
+		scanner := bufio.NewScanner(bytes.NewBufferString(text(record[0])))
+
+		for scanner.Scan() {
+			matches := mysqlLine.FindStringSubmatch(scanner.Text())
+			if matches == nil {
+				continue
+			}
+
+			indent := len(matches[1])
+			node := &PlanNode{
+				Operation: matches[2],
+				Detail:    matches[3],
+			}
+
+			if rows := mysqlRows.FindAllStringSubmatch(node.Detail, -1); rows != nil {
+				node.Rows, _ = strconv.ParseInt(rows[len(rows)-1][1], 10, 64)
+			}
+
+			if scan := mysqlScan.FindStringSubmatch(node.Operation); scan != nil {
+				node.Table = scan[1]
+				e.warn(node)
+			}
+
+			for len(depth) > 0 && depth[len(depth)-1] >= indent {
+				stack, depth = stack[:len(stack)-1], depth[:len(depth)-1]
+			}
+
+			if len(stack) == 0 {
+				roots = append(roots, node)
+			} else {
+				owner := stack[len(stack)-1]
+				owner.Children = append(owner.Children, node)
+			}
+
+			stack, depth = append(stack, node), append(depth, indent)
+		}
+	}
+
+	return roots
+}
+
+// aliases returns the tables of the query by their alias
+func aliases(query string) map[string]string {
+	tables := make(map[string]string)
+
+	for _, matches := range alias.FindAllStringSubmatch(query, -1) {
+		if name := matches[2]; name != "" && !sqlKeywords[strings.ToUpper(name)] {
+			tables[name] = matches[1]
+		}
+	}
+
+	return tables
+}
+
+func text(value interface{}) string {
+	switch data := value.(type) {
+	case []byte:
+		return string(data)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", data)
+	}
+}
+
+func number(value interface{}) int64 {
+	switch data := value.(type) {
+	case int64:
+		return data
+	case float64:
+		return int64(data)
+	default:
+		result, _ := strconv.ParseFloat(text(value), 64)
+		return int64(result)
+	}
+}
@@ -0,0 +1,379 @@
+package sqlexec_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/prana/sqlexec"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var _ = Describe("Explainer", func() {
+	var (
+		explainer *sqlexec.Explainer
+		dir       string
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "prana_explainer")
+		Expect(err).To(BeNil())
+
+		script := &bytes.Buffer{}
+		fmt.Fprintln(script, "-- name: select-users")
+		fmt.Fprintln(script, "SELECT * FROM users u WHERE u.name = ?;")
+		fmt.Fprintln(script)
+		fmt.Fprintln(script, "-- name: select-user")
+		fmt.Fprintln(script, "SELECT * FROM users WHERE id = :id")
+		fmt.Fprintln(script)
+		fmt.Fprintln(script, "-- name: select-users-by-id")
+		fmt.Fprintln(script, "SELECT * FROM users WHERE id IN (?)")
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, "users.sql"), script.Bytes(), 0700)).To(Succeed())
+
+		explainer = &sqlexec.Explainer{
+			FileSystem: parcello.Dir(dir),
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	Context("when the driver is sqlite3", func() {
+		BeforeEach(func() {
+			db, err := sqlx.Open("sqlite3", ":memory:")
+			Expect(err).To(BeNil())
+			db.SetMaxOpenConns(1)
+
+			_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+			Expect(err).To(BeNil())
+
+			_, err = db.Exec("INSERT INTO users (name) VALUES ('John'), ('Jane'), ('Peter')")
+			Expect(err).To(BeNil())
+
+			_, err = db.Exec("ANALYZE")
+			Expect(err).To(BeNil())
+
+			explainer.DB = db
+		})
+
+		AfterEach(func() {
+			Expect(explainer.DB.Close()).To(Succeed())
+		})
+
+		It("explains the query plan", func() {
+			plan, err := explainer.Explain("select-users", "John")
+			Expect(err).To(BeNil())
+			Expect(plan.Routine).To(Equal("select-users"))
+			Expect(plan.Driver).To(Equal("sqlite3"))
+			Expect(plan.Nodes).To(HaveLen(1))
+			Expect(plan.Nodes[0].Operation).To(HavePrefix("SCAN"))
+			Expect(plan.Nodes[0].Table).To(Equal("users"))
+			Expect(plan.Nodes[0].Rows).To(BeEquivalentTo(3))
+			Expect(plan.Warnings()).To(BeEmpty())
+		})
+
+		It("explains the query plan of a named routine", func() {
+			plan, err := explainer.ExplainNamed("select-user", map[string]interface{}{"id": 1})
+			Expect(err).To(BeNil())
+			Expect(plan.Nodes).To(HaveLen(1))
+			Expect(plan.Nodes[0].Operation).To(HavePrefix("SEARCH"))
+			Expect(plan.Nodes[0].Warning).To(BeEmpty())
+		})
+
+		It("explains the query plan of a routine with a slice parameter", func() {
+			plan, err := explainer.Explain("select-users-by-id", []int64{1, 2})
+			Expect(err).To(BeNil())
+			Expect(plan.Nodes).To(HaveLen(1))
+			Expect(plan.Nodes[0].Operation).To(HavePrefix("SEARCH"))
+		})
+
+		Context("when the database has a mapper", func() {
+			BeforeEach(func() {
+				explainer.DB.Mapper = reflectx.NewMapperFunc("json", strings.ToLower)
+			})
+
+			It("binds the named routine with the mapper", func() {
+				type User struct {
+					UserID int `json:"id"`
+				}
+
+				plan, err := explainer.ExplainNamed("select-user", &User{UserID: 1})
+				Expect(err).To(BeNil())
+				Expect(plan.Nodes).To(HaveLen(1))
+				Expect(plan.Nodes[0].Operation).To(HavePrefix("SEARCH"))
+			})
+		})
+
+		Context("when the table is larger than the threshold", func() {
+			BeforeEach(func() {
+				explainer.Threshold = 2
+			})
+
+			It("reports the sequential scan", func() {
+				plan, err := explainer.Explain("select-users", "John")
+				Expect(err).To(BeNil())
+				Expect(plan.Warnings()).To(ConsistOf("sequential scan on table 'users' with 3 rows"))
+			})
+		})
+
+		Context("when the table has no statistics", func() {
+			BeforeEach(func() {
+				_, err := explainer.DB.Exec("DELETE FROM sqlite_stat1")
+				Expect(err).To(BeNil())
+
+				explainer.Threshold = 2
+			})
+
+			It("does not count the rows", func() {
+				plan, err := explainer.Explain("select-users", "John")
+				Expect(err).To(BeNil())
+				Expect(plan.Nodes[0].Table).To(Equal("users"))
+				Expect(plan.Nodes[0].Rows).To(BeZero())
+				Expect(plan.Warnings()).To(BeEmpty())
+			})
+
+			Context("when the counting is enabled", func() {
+				BeforeEach(func() {
+					explainer.Count = true
+				})
+
+				It("counts the rows", func() {
+					plan, err := explainer.Explain("select-users", "John")
+					Expect(err).To(BeNil())
+					Expect(plan.Nodes[0].Rows).To(BeEquivalentTo(3))
+					Expect(plan.Warnings()).To(ConsistOf("sequential scan on table 'users' with 3 rows"))
+				})
+			})
+		})
+
+		Context("when the plan is analyzed", func() {
+			BeforeEach(func() {
+				explainer.Analyze = true
+			})
+
+			It("returns an error", func() {
+				_, err := explainer.Explain("select-users", "John")
+				Expect(err).To(MatchError("explain analyze is not supported by 'sqlite3'"))
+			})
+		})
+
+		Context("when the routine does not exist", func() {
+			It("returns an error", func() {
+				_, err := explainer.Explain("unknown")
+				Expect(err).To(MatchError("query 'unknown' not found"))
+			})
+		})
+	})
+
+	Context("when the driver is postgres", func() {
+		var mock sqlmock.Sqlmock
+
+		BeforeEach(func() {
+			db, m, err := sqlmock.New()
+			Expect(err).To(BeNil())
+
+			mock = m
+			explainer.DB = sqlx.NewDb(db, "postgres")
+		})
+
+		AfterEach(func() {
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("explains the query plan", func() {
+			output := `[{"Plan": {"Node Type": "Sort", "Startup Cost": 1.5, "Total Cost": 2.5, "Plan Rows": 5000,
+				"Plans": [{"Node Type": "Seq Scan", "Relation Name": "users", "Startup Cost": 0, "Total Cost": 1.5, "Plan Rows": 5000}]}}]`
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(`EXPLAIN \(FORMAT JSON\) SELECT \* FROM users u WHERE u.name = \$1$`).
+				WithArgs("John").
+				WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow(output))
+			mock.ExpectRollback()
+
+			plan, err := explainer.Explain("select-users", "John")
+			Expect(err).To(BeNil())
+			Expect(plan.Nodes).To(HaveLen(1))
+
+			root := plan.Nodes[0]
+			Expect(root.Operation).To(Equal("Sort"))
+			Expect(root.Detail).To(Equal("cost=1.50..2.50"))
+			Expect(root.Children).To(HaveLen(1))
+			Expect(root.Children[0].Operation).To(Equal("Seq Scan on users"))
+			Expect(root.Children[0].Warning).To(Equal("sequential scan on table 'users' with 5000 rows"))
+		})
+
+		Context("when the plan is analyzed", func() {
+			BeforeEach(func() {
+				explainer.Analyze = true
+			})
+
+			It("reports the actual rows", func() {
+				output := `[{"Plan": {"Node Type": "Index Scan", "Relation Name": "users", "Index Name": "users_pkey",
+					"Plan Rows": 1, "Actual Rows": 0, "Actual Startup Time": 0.01, "Actual Total Time": 0.02}}]`
+
+				mock.ExpectBegin()
+				mock.ExpectQuery(`EXPLAIN \(ANALYZE, FORMAT JSON\) SELECT \* FROM users WHERE id = \$1`).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow(output))
+				mock.ExpectRollback()
+
+				plan, err := explainer.ExplainNamed("select-user", map[string]interface{}{"id": 1})
+				Expect(err).To(BeNil())
+				Expect(plan.Nodes[0].Operation).To(Equal("Index Scan on users using users_pkey"))
+				Expect(plan.Nodes[0].Rows).To(BeEquivalentTo(0))
+				Expect(plan.Nodes[0].Detail).To(Equal("cost=0.00..0.00 time=0.010..0.020"))
+			})
+		})
+
+		It("expands the slice parameters", func() {
+			output := `[{"Plan": {"Node Type": "Index Scan", "Relation Name": "users", "Plan Rows": 2}}]`
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(`EXPLAIN \(FORMAT JSON\) SELECT \* FROM users WHERE id IN \(\$1, \$2\)$`).
+				WithArgs(1, 2).
+				WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow(output))
+			mock.ExpectRollback()
+
+			plan, err := explainer.Explain("select-users-by-id", []int64{1, 2})
+			Expect(err).To(BeNil())
+			Expect(plan.Nodes[0].Operation).To(Equal("Index Scan on users"))
+		})
+
+		Context("when the query fails", func() {
+			It("returns the error", func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("EXPLAIN").WillReturnError(fmt.Errorf("oh no"))
+				mock.ExpectRollback()
+
+				_, err := explainer.Explain("select-users", "John")
+				Expect(err).To(MatchError("oh no"))
+			})
+		})
+	})
+
+	Context("when the driver is mysql", func() {
+		var mock sqlmock.Sqlmock
+
+		BeforeEach(func() {
+			db, m, err := sqlmock.New()
+			Expect(err).To(BeNil())
+
+			mock = m
+			explainer.DB = sqlx.NewDb(db, "mysql")
+		})
+
+		AfterEach(func() {
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("explains the query plan", func() {
+			output := `{"query_block": {"select_id": 1, "cost_info": {"query_cost": "1.00"},
+				"nested_loop": [
+					{"table": {"table_name": "u", "access_type": "ALL", "rows_examined_per_scan": 2000}},
+					{"table": {"table_name": "r", "access_type": "ref", "key": "user_id", "rows_examined_per_scan": 1}}
+				]}}`
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(`EXPLAIN FORMAT=JSON SELECT \* FROM users u WHERE u.name = \?$`).
+				WithArgs("John").
+				WillReturnRows(sqlmock.NewRows([]string{"EXPLAIN"}).AddRow(output))
+			mock.ExpectRollback()
+
+			plan, err := explainer.Explain("select-users", "John")
+			Expect(err).To(BeNil())
+			Expect(plan.Nodes).To(HaveLen(1))
+
+			block := plan.Nodes[0]
+			Expect(block.Operation).To(Equal("query_block"))
+			Expect(block.Children).To(HaveLen(1))
+
+			loop := block.Children[0]
+			Expect(loop.Operation).To(Equal("nested_loop"))
+			Expect(loop.Children).To(HaveLen(2))
+			Expect(loop.Children[0].Operation).To(Equal("table u"))
+			Expect(loop.Children[0].Detail).To(Equal("access_type=ALL"))
+			Expect(loop.Children[1].Operation).To(Equal("table r using user_id"))
+
+			Expect(plan.Warnings()).To(ConsistOf("sequential scan on table 'u' with 2000 rows"))
+		})
+
+		Context("when the plan is analyzed", func() {
+			BeforeEach(func() {
+				explainer.Analyze = true
+			})
+
+			It("parses the tree", func() {
+				output := "-> Filter: (u.name = 'John')  (cost=0.45 rows=1) (actual time=0.02..0.03 rows=1 loops=1)\n" +
+					"    -> Table scan on u  (cost=0.45 rows=2) (actual time=0.01..0.02 rows=1500 loops=1)\n"
+
+				mock.ExpectBegin()
+				mock.ExpectQuery(`EXPLAIN ANALYZE SELECT`).
+					WithArgs("John").
+					WillReturnRows(sqlmock.NewRows([]string{"EXPLAIN"}).AddRow(output))
+				mock.ExpectRollback()
+
+				plan, err := explainer.Explain("select-users", "John")
+				Expect(err).To(BeNil())
+				Expect(plan.Nodes).To(HaveLen(1))
+				Expect(plan.Nodes[0].Operation).To(Equal("Filter: (u.name = 'John')"))
+				Expect(plan.Nodes[0].Children).To(HaveLen(1))
+
+				scan := plan.Nodes[0].Children[0]
+				Expect(scan.Operation).To(Equal("Table scan on u"))
+				Expect(scan.Rows).To(BeEquivalentTo(1500))
+				Expect(scan.Warning).To(Equal("sequential scan on table 'u' with 1500 rows"))
+			})
+		})
+	})
+
+	Context("when the driver is not supported", func() {
+		It("returns an error", func() {
+			db, _, err := sqlmock.New()
+			Expect(err).To(BeNil())
+
+			explainer.DB = sqlx.NewDb(db, "oracle")
+
+			_, err = explainer.Explain("select-users")
+			Expect(err).To(MatchError("explain is not supported by 'oracle'"))
+		})
+	})
+
+	Describe("Fplan", func() {
+		It("prints the plan as tree", func() {
+			plan := &sqlexec.Plan{
+				Routine: "select-users",
+				Driver:  "postgres",
+				Nodes: []*sqlexec.PlanNode{
+					{
+						Operation: "Hash Join",
+						Children: []*sqlexec.PlanNode{
+							{Operation: "Seq Scan on users", Rows: 10},
+							{Operation: "Hash", Children: []*sqlexec.PlanNode{{Operation: "Seq Scan on roles"}}},
+						},
+					},
+				},
+			}
+
+			w := &bytes.Buffer{}
+			sqlexec.Fplan(w, plan)
+
+			Expect(w.String()).To(Equal("select-users (postgres)\n" +
+				"└── Hash Join\n" +
+				"    ├── Seq Scan on users (rows=10)\n" +
+				"    └── Hash\n" +
+				"        └── Seq Scan on roles\n"))
+		})
+	})
+})
@@ -0,0 +1,398 @@
+package sqlexec
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+const (
+	// FormatTable prints the rows as table
+	FormatTable = "table"
+	// FormatCSV prints the rows as comma separated values
+	FormatCSV = "csv"
+	// FormatTSV prints the rows as tab separated values
+	FormatTSV = "tsv"
+	// FormatJSON prints the rows as JSON array of objects
+	FormatJSON = "json"
+	// FormatJSONL prints each row as JSON object on a separate line
+	FormatJSONL = "jsonl"
+	// FormatMarkdown prints the rows as markdown table
+	FormatMarkdown = "markdown"
+	// FormatVertical prints each column of the rows on a separate line
+	FormatVertical = "vertical"
+)
+
+const (
+	// BinaryHex encodes the binary values as hex string
+	BinaryHex = "hex"
+	// BinaryBase64 encodes the binary values as base64 string
+	BinaryBase64 = "base64"
+)
+
+// RowSet is a set of rows that can be formatted
+type RowSet interface {
+	// Columns returns the column names
+	Columns() ([]string, error)
+	// Next prepares the next row for scanning
+	Next() bool
+	// SliceScan scans the current row into a slice
+	SliceScan() ([]interface{}, error)
+	// Err returns the error that occurred during the iteration
+	Err() error
+}
+
+// Formatter writes the rows of a routine in given format
+type Formatter struct {
+	// Format is the output format. Defaults to table.
+	Format string
+	// Binary is the encoding of the binary values. Defaults to hex.
+	Binary string
+	// Null is the text of the NULL values in the text formats. Defaults to
+	// NULL.
+	Null string
+}
+
+// Write writes the rows to the writer
+func (f *Formatter) Write(w io.Writer, rows RowSet) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	binary := f.binaryColumns(rows, len(columns))
+
+	next := func() ([]interface{}, bool, error) {
+		if !rows.Next() {
+			return nil, false, rows.Err()
+		}
+
+		record, err := rows.SliceScan()
+		if err != nil {
+			return nil, false, err
+		}
+
+		for index, value := range record {
+			record[index] = f.value(value, binary[index])
+		}
+
+		return record, true, nil
+	}
+
+	switch f.format() {
+	case FormatTable:
+		return f.table(w, columns, next, false)
+	case FormatMarkdown:
+		return f.table(w, columns, next, true)
+	case FormatCSV:
+		return f.csv(w, columns, next, ',')
+	case FormatTSV:
+		return f.csv(w, columns, next, '\t')
+	case FormatJSON:
+		return f.json(w, columns, next, false)
+	case FormatJSONL:
+		return f.json(w, columns, next, true)
+	case FormatVertical:
+		return f.vertical(w, columns, next)
+	default:
+		return fmt.Errorf("unsupported format '%s'", f.Format)
+	}
+}
+
+// WriteResult writes the result of a routine that does not return rows
+func (f *Formatter) WriteResult(w io.Writer, result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	record := []interface{}{affected, nil}
+
+	// not all drivers support the last insert id
+	if id, err := result.LastInsertId(); err == nil {
+		record[1] = id
+	}
+
+	return f.Write(w, &resultSet{record: record})
+}
+
+func (f *Formatter) format() string {
+	if f.Format == "" {
+		return FormatTable
+	}
+	return f.Format
+}
+
+func (f *Formatter) null() string {
+	if f.Null == "" {
+		return "NULL"
+	}
+	return f.Null
+}
+
+// binaryColumns returns which columns have a binary database type
+func (f *Formatter) binaryColumns(rows RowSet, count int) []bool {
+	binary := make([]bool, count)
+
+	typed, ok := rows.(interface {
+		ColumnTypes() ([]*sql.ColumnType, error)
+	})
+
+	if !ok {
+		return binary
+	}
+
+	types, err := typed.ColumnTypes()
+	if err != nil || len(types) != count {
+		return binary
+	}
+
+	for index, kind := range types {
+		name := strings.ToUpper(kind.DatabaseTypeName())
+		binary[index] = strings.Contains(name, "BLOB") ||
+			strings.Contains(name, "BINARY") ||
+			name == "BYTEA"
+	}
+
+	return binary
+}
+
+// value converts the value to its JSON compatible representation
+func (f *Formatter) value(value interface{}, binary bool) interface{} {
+	switch data := value.(type) {
+	case []byte:
+		if binary || !utf8.Valid(data) {
+			if f.Binary == BinaryBase64 {
+				return base64.StdEncoding.EncodeToString(data)
+			}
+			return hex.EncodeToString(data)
+		}
+		return string(data)
+	case time.Time:
+		return data.Format(time.RFC3339)
+	default:
+		return value
+	}
+}
+
+// text converts the value to its text representation
+func (f *Formatter) text(value interface{}) string {
+	if value == nil {
+		return f.null()
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func (f *Formatter) table(w io.Writer, columns []string, next func() ([]interface{}, bool, error), markdown bool) error {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(columns)
+
+	if markdown {
+		table.SetAutoFormatHeaders(false)
+		table.SetAutoWrapText(false)
+		table.SetBorders(tablewriter.Border{Left: true, Right: true})
+		table.SetCenterSeparator("|")
+	}
+
+	for {
+		record, ok, err := next()
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			break
+		}
+
+		row := []string{}
+
+		for _, value := range record {
+			cell := f.text(value)
+
+			if markdown {
+				cell = strings.Replace(cell, "|", "\\|", -1)
+				cell = strings.Replace(cell, "\n", " ", -1)
+			}
+
+			row = append(row, cell)
+		}
+
+		table.Append(row)
+	}
+
+	table.Render()
+	return nil
+}
+
+func (f *Formatter) csv(w io.Writer, columns []string, next func() ([]interface{}, bool, error), comma rune) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+
+	for {
+		record, ok, err := next()
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			break
+		}
+
+		row := []string{}
+
+		for _, value := range record {
+			row = append(row, f.text(value))
+		}
+
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func (f *Formatter) json(w io.Writer, columns []string, next func() ([]interface{}, bool, error), lines bool) error {
+	count := 0
+
+	if !lines {
+		fmt.Fprint(w, "[")
+	}
+
+	for {
+		record, ok, err := next()
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			break
+		}
+
+		object, err := f.object(columns, record)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case lines:
+			fmt.Fprintf(w, "%s\n", object)
+		case count > 0:
+			fmt.Fprintf(w, ",\n  %s", object)
+		default:
+			fmt.Fprintf(w, "\n  %s", object)
+		}
+
+		count++
+	}
+
+	if !lines {
+		if count > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, "]")
+	}
+
+	return nil
+}
+
+// object encodes the record as JSON object that keeps the order of the
+// columns
+func (f *Formatter) object(columns []string, record []interface{}) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	buffer.WriteString("{")
+
+	for index, column := range columns {
+		if index > 0 {
+			buffer.WriteString(",")
+		}
+
+		key, err := json.Marshal(column)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Marshal(record[index])
+		if err != nil {
+			return nil, err
+		}
+
+		buffer.Write(key)
+		buffer.WriteString(":")
+		buffer.Write(value)
+	}
+
+	buffer.WriteString("}")
+	return buffer.Bytes(), nil
+}
+
+func (f *Formatter) vertical(w io.Writer, columns []string, next func() ([]interface{}, bool, error)) error {
+	width := 0
+
+	for _, column := range columns {
+		if length := utf8.RuneCountInString(column); length > width {
+			width = length
+		}
+	}
+
+	for count := 1; ; count++ {
+		record, ok, err := next()
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			break
+		}
+
+		fmt.Fprintf(w, "*************************** %d. row ***************************\n", count)
+
+		for index, column := range columns {
+			fmt.Fprintf(w, "%*s: %s\n", width, column, f.text(record[index]))
+		}
+	}
+
+	return nil
+}
+
+// resultSet represents the result of a routine that does not return rows
+type resultSet struct {
+	record []interface{}
+	done   bool
+}
+
+func (r *resultSet) Columns() ([]string, error) {
+	return []string{"rows_affected", "last_insert_id"}, nil
+}
+
+func (r *resultSet) Next() bool {
+	if r.done {
+		return false
+	}
+
+	r.done = true
+	return true
+}
+
+func (r *resultSet) SliceScan() ([]interface{}, error) {
+	return r.record, nil
+}
+
+func (r *resultSet) Err() error {
+	return nil
+}
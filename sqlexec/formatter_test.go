@@ -0,0 +1,170 @@
+package sqlexec_test
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/prana/sqlexec"
+)
+
+var _ = Describe("Formatter", func() {
+	var (
+		db        *sqlx.DB
+		formatter *sqlexec.Formatter
+		buffer    *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		db, err = sqlx.Open("sqlite3", ":memory:")
+		Expect(err).To(BeNil())
+		db.SetMaxOpenConns(1)
+
+		_, err = db.Exec("CREATE TABLE users (id INTEGER, name TEXT, note TEXT, avatar BLOB, created_at DATETIME)")
+		Expect(err).To(BeNil())
+
+		_, err = db.Exec("INSERT INTO users VALUES (1, 'John', NULL, X'CAFE', '2006-01-02 15:04:05'), (2, 'Jane', '', NULL, NULL)")
+		Expect(err).To(BeNil())
+
+		formatter = &sqlexec.Formatter{}
+		buffer = &bytes.Buffer{}
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).To(Succeed())
+	})
+
+	write := func() error {
+		rows, err := db.Queryx("SELECT * FROM users ORDER BY id")
+		Expect(err).To(BeNil())
+		defer rows.Close()
+
+		return formatter.Write(buffer, rows)
+	}
+
+	It("writes the rows as table by default", func() {
+		Expect(write()).To(Succeed())
+
+		content := buffer.String()
+		Expect(content).To(ContainSubstring("| ID | NAME | NOTE | AVATAR |      CREATED AT      |"))
+		Expect(content).To(ContainSubstring("|  1 | John | NULL | cafe   | 2006-01-02T15:04:05Z |"))
+		Expect(content).To(ContainSubstring("|  2 | Jane |      | NULL   | NULL                 |"))
+	})
+
+	It("writes the rows as csv", func() {
+		formatter.Format = sqlexec.FormatCSV
+		Expect(write()).To(Succeed())
+
+		Expect(buffer.String()).To(Equal(
+			"id,name,note,avatar,created_at\n" +
+				"1,John,NULL,cafe,2006-01-02T15:04:05Z\n" +
+				"2,Jane,,NULL,NULL\n",
+		))
+	})
+
+	It("writes the rows as tsv", func() {
+		formatter.Format = sqlexec.FormatTSV
+		formatter.Null = `\N`
+		Expect(write()).To(Succeed())
+
+		Expect(buffer.String()).To(Equal(
+			"id\tname\tnote\tavatar\tcreated_at\n" +
+				"1\tJohn\t\\N\tcafe\t2006-01-02T15:04:05Z\n" +
+				"2\tJane\t\t\\N\t\\N\n",
+		))
+	})
+
+	It("writes the rows as json", func() {
+		formatter.Format = sqlexec.FormatJSON
+		formatter.Binary = sqlexec.BinaryBase64
+		Expect(write()).To(Succeed())
+
+		Expect(buffer.String()).To(Equal(
+			"[\n" +
+				`  {"id":1,"name":"John","note":null,"avatar":"yv4=","created_at":"2006-01-02T15:04:05Z"},` + "\n" +
+				`  {"id":2,"name":"Jane","note":"","avatar":null,"created_at":null}` + "\n" +
+				"]\n",
+		))
+	})
+
+	It("writes the rows as json lines", func() {
+		formatter.Format = sqlexec.FormatJSONL
+		Expect(write()).To(Succeed())
+
+		Expect(buffer.String()).To(Equal(
+			`{"id":1,"name":"John","note":null,"avatar":"cafe","created_at":"2006-01-02T15:04:05Z"}` + "\n" +
+				`{"id":2,"name":"Jane","note":"","avatar":null,"created_at":null}` + "\n",
+		))
+	})
+
+	It("writes the rows as markdown", func() {
+		formatter.Format = sqlexec.FormatMarkdown
+		Expect(write()).To(Succeed())
+
+		content := buffer.String()
+		Expect(content).To(ContainSubstring("| id | name | note | avatar |      created_at      |"))
+		Expect(content).To(ContainSubstring("|----|------|------|--------|----------------------|"))
+		Expect(content).To(ContainSubstring("|  1 | John | NULL | cafe   | 2006-01-02T15:04:05Z |"))
+	})
+
+	It("writes the rows vertically", func() {
+		formatter.Format = sqlexec.FormatVertical
+		Expect(write()).To(Succeed())
+
+		content := buffer.String()
+		Expect(content).To(ContainSubstring("*************************** 1. row ***************************\n"))
+		Expect(content).To(ContainSubstring("        id: 1\n      name: John\n      note: NULL\n"))
+		Expect(content).To(ContainSubstring("*************************** 2. row ***************************\n"))
+	})
+
+	Context("when there are no rows", func() {
+		It("writes an empty json array", func() {
+			formatter.Format = sqlexec.FormatJSON
+
+			rows, err := db.Queryx("SELECT * FROM users WHERE id = 0")
+			Expect(err).To(BeNil())
+			defer rows.Close()
+
+			Expect(formatter.Write(buffer, rows)).To(Succeed())
+			Expect(buffer.String()).To(Equal("[]\n"))
+		})
+	})
+
+	Context("when the format is not supported", func() {
+		It("returns an error", func() {
+			formatter.Format = "xml"
+			Expect(write()).To(MatchError("unsupported format 'xml'"))
+		})
+	})
+
+	Describe("WriteResult", func() {
+		It("writes the affected rows and the last insert id", func() {
+			result, err := db.Exec("INSERT INTO users (id, name) VALUES (3, 'Peter')")
+			Expect(err).To(BeNil())
+
+			formatter.Format = sqlexec.FormatJSONL
+			Expect(formatter.WriteResult(buffer, result)).To(Succeed())
+			Expect(buffer.String()).To(Equal(`{"rows_affected":1,"last_insert_id":3}` + "\n"))
+		})
+
+		Context("when the result fails", func() {
+			It("returns the error", func() {
+				Expect(formatter.WriteResult(buffer, &failedResult{})).To(MatchError("oh no"))
+			})
+		})
+	})
+})
+
+type failedResult struct{}
+
+func (r *failedResult) LastInsertId() (int64, error) {
+	return 0, fmt.Errorf("oh no")
+}
+
+func (r *failedResult) RowsAffected() (int64, error) {
+	return 0, fmt.Errorf("oh no")
+}
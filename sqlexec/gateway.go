@@ -0,0 +1,386 @@
+package sqlexec
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// GatewayStats represents the statistics of the gateway statement cache.
+type GatewayStats struct {
+	// Hits is the number of executions that used a cached statement.
+	Hits uint64
+	// Misses is the number of executions that prepared a statement.
+	Misses uint64
+	// Statements is the number of cached statements.
+	Statements int
+}
+
+// statement is the key of a cached statement
+type statement struct {
+	db    *sqlx.DB
+	name  string
+	named bool
+}
+
+// call is a routine bound to its parameters
+type call struct {
+	query string
+	args  []Param
+	// stmt is the cached statement of the routine or nil if the query
+	// depends on the parameters
+	stmt    *sqlx.Stmt
+	release func()
+}
+
+// cachedStmt is a cached statement that is closed when it is retired and
+// all executions that use it have finished
+type cachedStmt struct {
+	mu      sync.Mutex
+	stmt    io.Closer
+	refs    int
+	retired bool
+}
+
+func (c *cachedStmt) acquire() *cachedStmt {
+	c.mu.Lock()
+	c.refs++
+	c.mu.Unlock()
+	return c
+}
+
+func (c *cachedStmt) release() {
+	c.mu.Lock()
+	c.refs--
+	idle := c.retired && c.refs == 0
+	c.mu.Unlock()
+
+	if idle {
+		c.stmt.Close()
+	}
+}
+
+func (c *cachedStmt) retire() error {
+	c.mu.Lock()
+	c.retired = true
+	idle := c.refs == 0
+	c.mu.Unlock()
+
+	if idle {
+		return c.stmt.Close()
+	}
+
+	return nil
+}
+
+// Gateway executes the routines of a given provider. The statements are
+// prepared lazily and cached per underlying database. The gateway is safe
+// for concurrent use. The statements that are in use when the gateway is
+// closed or the routines are reloaded are closed once their executions
+// finish. The routines are bound by the provider, so the statements whose
+// slice parameters are expanded are not cached.
+type Gateway struct {
+	// Provider provides the SQL routines.
+	Provider *Provider
+	// DB is a client to underlying database.
+	DB *sqlx.DB
+	// private fields
+	mu       sync.RWMutex
+	stmts    map[statement]*cachedStmt
+	revision uint64
+	closed   bool
+	hits     uint64
+	misses   uint64
+}
+
+// Run runs a given routine with provided parameters.
+func (g *Gateway) Run(name string, args ...Param) (*Rows, error) {
+	call, err := g.bind(name, args)
+	if err != nil {
+		return nil, err
+	}
+
+	defer call.release()
+
+	if call.stmt == nil {
+		return g.DB.Queryx(call.query, call.args...)
+	}
+
+	return call.stmt.Queryx(call.args...)
+}
+
+// RunNamed runs a given routine that has named parameters. The argument can
+// be a struct or map[string]interface{}. The templates are rendered for the
+// argument and their statements are not cached.
+func (g *Gateway) RunNamed(name string, arg Param) (*Rows, error) {
+	call, err := g.bindNamed(name, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer call.release()
+
+	if call.stmt == nil {
+		return g.DB.Queryx(call.query, call.args...)
+	}
+
+	return call.stmt.Queryx(call.args...)
+}
+
+// Exec executes a given routine that does not return rows.
+func (g *Gateway) Exec(name string, args ...Param) (sql.Result, error) {
+	call, err := g.bind(name, args)
+	if err != nil {
+		return nil, err
+	}
+
+	defer call.release()
+
+	if call.stmt == nil {
+		return g.DB.Exec(call.query, call.args...)
+	}
+
+	return call.stmt.Exec(call.args...)
+}
+
+// ExecNamed executes a given routine that has named parameters and does not
+// return rows. The argument can be a struct or map[string]interface{}. The
+// templates are rendered for the argument and their statements are not
+// cached.
+func (g *Gateway) ExecNamed(name string, arg Param) (sql.Result, error) {
+	call, err := g.bindNamed(name, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer call.release()
+
+	if call.stmt == nil {
+		return g.DB.Exec(call.query, call.args...)
+	}
+
+	return call.stmt.Exec(call.args...)
+}
+
+// Select runs a given routine and scans the rows into a slice.
+func (g *Gateway) Select(dest interface{}, name string, args ...Param) error {
+	call, err := g.bind(name, args)
+	if err != nil {
+		return err
+	}
+
+	defer call.release()
+
+	if call.stmt == nil {
+		return g.DB.Select(dest, call.query, call.args...)
+	}
+
+	return call.stmt.Select(dest, call.args...)
+}
+
+// Get runs a given routine and scans the first row into the destination. It
+// returns NotFoundError if the routine does not return any rows.
+func (g *Gateway) Get(dest interface{}, name string, args ...Param) error {
+	call, err := g.bind(name, args)
+	if err != nil {
+		return err
+	}
+
+	defer call.release()
+
+	if call.stmt == nil {
+		err = g.DB.Get(dest, call.query, call.args...)
+	} else {
+		err = call.stmt.Get(dest, call.args...)
+	}
+
+	if err == sql.ErrNoRows {
+		return &NotFoundError{Routine: name}
+	}
+
+	return err
+}
+
+// Cursor runs a given routine and returns a cursor that iterates over its
+// rows.
+func (g *Gateway) Cursor(name string, args ...Param) (*Cursor, error) {
+	rows, err := g.Run(name, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cursor{rows: rows}, nil
+}
+
+// Stats returns the statistics of the statement cache.
+func (g *Gateway) Stats() GatewayStats {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return GatewayStats{
+		Hits:       atomic.LoadUint64(&g.hits),
+		Misses:     atomic.LoadUint64(&g.misses),
+		Statements: len(g.stmts),
+	}
+}
+
+// Close closes all cached statements. The gateway cannot be used after
+// that. Note that the underlying database is not closed.
+func (g *Gateway) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return nil
+	}
+
+	g.closed = true
+	return g.flush()
+}
+
+func (g *Gateway) flush() error {
+	var err error
+
+	for _, stmt := range g.stmts {
+		if closeErr := stmt.retire(); err == nil {
+			err = closeErr
+		}
+	}
+
+	g.stmts = nil
+	return err
+}
+
+// bind binds a given routine to its parameters. The statement of the
+// routine is cached unless its slice parameters are expanded.
+func (g *Gateway) bind(name string, args []Param) (*call, error) {
+	if err := g.check(); err != nil {
+		return nil, err
+	}
+
+	revision := g.Provider.revision()
+
+	query, params, err := g.Provider.Bind(name, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if slices(args) {
+		return &call{query: query, args: params, release: func() {}}, nil
+	}
+
+	return g.cache(statement{db: g.DB, name: name}, revision, query, params)
+}
+
+// bindNamed binds a given routine that has named parameters to its argument.
+// The statement of the routine is cached unless it is rendered from a
+// template or its slice parameters are expanded.
+func (g *Gateway) bindNamed(name string, arg Param) (*call, error) {
+	if err := g.check(); err != nil {
+		return nil, err
+	}
+
+	revision := g.Provider.revision()
+
+	query, args, dynamic, err := g.Provider.bindNamed(name, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	if dynamic {
+		return &call{query: query, args: args, release: func() {}}, nil
+	}
+
+	return g.cache(statement{db: g.DB, name: name, named: true}, revision, query, args)
+}
+
+// cache returns a call that uses the cached statement of a given query
+func (g *Gateway) cache(key statement, revision uint64, query string, args []Param) (*call, error) {
+	cached, err := g.prepare(key, revision, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &call{
+		query:   query,
+		args:    args,
+		stmt:    cached.stmt.(*sqlx.Stmt),
+		release: cached.release,
+	}, nil
+}
+
+// check returns an error if the gateway is closed
+func (g *Gateway) check() error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.closed {
+		return closedGatewayErr()
+	}
+
+	return nil
+}
+
+// prepare returns an acquired cached statement for a given key. The query
+// has to be bound at the given revision of the routines.
+func (g *Gateway) prepare(key statement, revision uint64, query string) (*cachedStmt, error) {
+	g.mu.RLock()
+
+	if g.closed {
+		g.mu.RUnlock()
+		return nil, closedGatewayErr()
+	}
+
+	if cached, ok := g.stmts[key]; ok && g.revision == revision {
+		// the statement is acquired under the lock so it cannot be closed
+		// before the execution finishes
+		cached.acquire()
+		g.mu.RUnlock()
+		atomic.AddUint64(&g.hits, 1)
+		return cached, nil
+	}
+
+	g.mu.RUnlock()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return nil, closedGatewayErr()
+	}
+
+	// the routines have been reloaded since the statements were prepared
+	if g.revision != revision {
+		g.flush()
+		g.revision = revision
+	}
+
+	// the statement might be prepared while we were waiting for the lock
+	if cached, ok := g.stmts[key]; ok {
+		atomic.AddUint64(&g.hits, 1)
+		return cached.acquire(), nil
+	}
+
+	atomic.AddUint64(&g.misses, 1)
+
+	stmt, err := key.db.Preparex(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if g.stmts == nil {
+		g.stmts = make(map[statement]*cachedStmt)
+	}
+
+	cached := &cachedStmt{stmt: stmt}
+	g.stmts[key] = cached
+	return cached.acquire(), nil
+}
+
+func closedGatewayErr() error {
+	return fmt.Errorf("gateway is closed")
+}
@@ -0,0 +1,376 @@
+package sqlexec_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/prana/sqlexec"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var _ = Describe("Gateway", func() {
+	var gateway *sqlexec.Gateway
+
+	BeforeEach(func() {
+		dir, err := ioutil.TempDir("", "prana_gateway")
+		Expect(err).To(BeNil())
+
+		db, err := sqlx.Open("sqlite3", filepath.Join(dir, "prana.db"))
+		Expect(err).To(BeNil())
+
+		_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)")
+		Expect(err).To(BeNil())
+
+		buffer := &bytes.Buffer{}
+		fmt.Fprintln(buffer, "-- name: select-users")
+		fmt.Fprintln(buffer, "SELECT * FROM users WHERE name = ?")
+		fmt.Fprintln(buffer)
+		fmt.Fprintln(buffer, "-- name: select-named-users")
+		fmt.Fprintln(buffer, "SELECT * FROM users WHERE name = :name")
+		fmt.Fprintln(buffer)
+		fmt.Fprintln(buffer, "-- name: insert-user")
+		fmt.Fprintln(buffer, "INSERT INTO users (name) VALUES (?)")
+		fmt.Fprintln(buffer)
+		fmt.Fprintln(buffer, "-- name: insert-named-user")
+		fmt.Fprintln(buffer, "INSERT INTO users (name) VALUES (:name)")
+		fmt.Fprintln(buffer)
+		fmt.Fprintln(buffer, "-- name: select-users-by-id")
+		fmt.Fprintln(buffer, "SELECT * FROM users WHERE id IN (?)")
+		fmt.Fprintln(buffer)
+		fmt.Fprintln(buffer, "-- name: select-named-users-by-id")
+		fmt.Fprintln(buffer, "SELECT * FROM users WHERE id IN (:ids)")
+
+		provider := &sqlexec.Provider{DriverName: "sqlite3"}
+		_, err = provider.ReadFrom(buffer)
+		Expect(err).To(BeNil())
+
+		gateway = &sqlexec.Gateway{
+			Provider: provider,
+			DB:       db,
+		}
+	})
+
+	AfterEach(func() {
+		Expect(gateway.Close()).To(Succeed())
+		Expect(gateway.DB.Close()).To(Succeed())
+	})
+
+	It("executes and runs the routines successfully", func() {
+		result, err := gateway.Exec("insert-user", "John")
+		Expect(err).To(Succeed())
+
+		affected, err := result.RowsAffected()
+		Expect(err).To(Succeed())
+		Expect(affected).To(BeEquivalentTo(1))
+
+		rows, err := gateway.Run("select-users", "John")
+		Expect(err).To(Succeed())
+		Expect(rows.Next()).To(BeTrue())
+		Expect(rows.Close()).To(Succeed())
+	})
+
+	It("executes and runs the named routines successfully", func() {
+		arg := map[string]interface{}{"name": "John"}
+
+		_, err := gateway.ExecNamed("insert-named-user", arg)
+		Expect(err).To(Succeed())
+
+		rows, err := gateway.RunNamed("select-named-users", arg)
+		Expect(err).To(Succeed())
+		Expect(rows.Next()).To(BeTrue())
+		Expect(rows.Close()).To(Succeed())
+	})
+
+	Describe("Select and Get", func() {
+		type user struct {
+			ID   int    `db:"id"`
+			Name string `db:"name"`
+		}
+
+		BeforeEach(func() {
+			_, err := gateway.Exec("insert-user", "John")
+			Expect(err).To(Succeed())
+		})
+
+		It("selects the rows into a slice", func() {
+			users := []user{}
+			Expect(gateway.Select(&users, "select-users", "John")).To(Succeed())
+			Expect(users).To(Equal([]user{{ID: 1, Name: "John"}}))
+		})
+
+		It("gets a single row", func() {
+			record := user{}
+			Expect(gateway.Get(&record, "select-users", "John")).To(Succeed())
+			Expect(record).To(Equal(user{ID: 1, Name: "John"}))
+		})
+
+		It("iterates over the rows with a cursor", func() {
+			cursor, err := gateway.Cursor("select-users", "John")
+			Expect(err).To(Succeed())
+			Expect(cursor.Next()).To(BeTrue())
+
+			record := user{}
+			Expect(cursor.Scan(&record)).To(Succeed())
+			Expect(record.Name).To(Equal("John"))
+			Expect(cursor.Next()).To(BeFalse())
+			Expect(cursor.Close()).To(Succeed())
+		})
+
+		Context("when the row is not found", func() {
+			It("returns a not found error", func() {
+				err := gateway.Get(&user{}, "select-users", "Jane")
+				Expect(sqlexec.IsNotFound(err)).To(BeTrue())
+			})
+		})
+	})
+
+	Context("when the parameters are slices", func() {
+		type user struct {
+			ID   int    `db:"id"`
+			Name string `db:"name"`
+		}
+
+		BeforeEach(func() {
+			for _, name := range []string{"John", "Jane", "Peter"} {
+				_, err := gateway.Exec("insert-user", name)
+				Expect(err).To(Succeed())
+			}
+		})
+
+		It("expands the slices", func() {
+			users := []user{}
+			Expect(gateway.Select(&users, "select-users-by-id", []int64{1, 3})).To(Succeed())
+			Expect(users).To(Equal([]user{{ID: 1, Name: "John"}, {ID: 3, Name: "Peter"}}))
+
+			rows, err := gateway.RunNamed("select-named-users-by-id", map[string]interface{}{"ids": []int64{1, 2}})
+			Expect(err).To(Succeed())
+
+			count := 0
+			for rows.Next() {
+				count++
+			}
+
+			Expect(rows.Close()).To(Succeed())
+			Expect(count).To(Equal(2))
+		})
+
+		It("does not cache the expanded statements", func() {
+			rows, err := gateway.Run("select-users-by-id", []int64{1, 2})
+			Expect(err).To(Succeed())
+			Expect(rows.Close()).To(Succeed())
+
+			Expect(gateway.Stats().Statements).To(Equal(1))
+		})
+	})
+
+	Context("when the provider has a mapper", func() {
+		BeforeEach(func() {
+			gateway.Provider.Mapper = reflectx.NewMapperFunc("json", strings.ToLower)
+		})
+
+		It("binds the named routines with the mapper", func() {
+			type user struct {
+				UserName string `json:"name"`
+			}
+
+			_, err := gateway.ExecNamed("insert-named-user", &user{UserName: "John"})
+			Expect(err).To(Succeed())
+
+			rows, err := gateway.RunNamed("select-named-users", &user{UserName: "John"})
+			Expect(err).To(Succeed())
+			Expect(rows.Next()).To(BeTrue())
+			Expect(rows.Close()).To(Succeed())
+		})
+	})
+
+	It("caches the prepared statements", func() {
+		for index := 0; index < 3; index++ {
+			rows, err := gateway.Run("select-users", "John")
+			Expect(err).To(Succeed())
+			Expect(rows.Close()).To(Succeed())
+		}
+
+		rows, err := gateway.RunNamed("select-named-users", map[string]interface{}{"name": "John"})
+		Expect(err).To(Succeed())
+		Expect(rows.Close()).To(Succeed())
+
+		stats := gateway.Stats()
+		Expect(stats.Hits).To(BeEquivalentTo(2))
+		Expect(stats.Misses).To(BeEquivalentTo(2))
+		Expect(stats.Statements).To(Equal(2))
+	})
+
+	It("is safe for concurrent use", func() {
+		group := sync.WaitGroup{}
+
+		for index := 0; index < 10; index++ {
+			group.Add(1)
+
+			go func() {
+				defer GinkgoRecover()
+				defer group.Done()
+
+				rows, err := gateway.Run("select-users", "John")
+				Expect(err).To(Succeed())
+				Expect(rows.Close()).To(Succeed())
+			}()
+		}
+
+		group.Wait()
+
+		stats := gateway.Stats()
+		Expect(stats.Hits + stats.Misses).To(BeEquivalentTo(10))
+		Expect(stats.Misses).To(BeEquivalentTo(1))
+	})
+
+	Context("when the routines are reloaded", func() {
+		It("prepares the statements again", func() {
+			dir, err := ioutil.TempDir("", "prana_gateway")
+			Expect(err).To(BeNil())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			gateway.Provider.Watch(ctx, parcello.Dir(dir), 10*time.Millisecond, nil)
+
+			rows, err := gateway.Run("select-users", "John")
+			Expect(err).To(Succeed())
+			Expect(rows.Close()).To(Succeed())
+
+			path := filepath.Join(dir, "routine.sql")
+			Expect(ioutil.WriteFile(path, []byte("-- name: select-users\nSELECT name FROM users WHERE name = ?"), 0600)).To(Succeed())
+
+			Eventually(func() (string, error) {
+				return gateway.Provider.Query("select-users")
+			}).Should(Equal("SELECT name FROM users WHERE name = ?"))
+
+			rows, err = gateway.Run("select-users", "John")
+			Expect(err).To(Succeed())
+
+			columns, err := rows.Columns()
+			Expect(err).To(Succeed())
+			Expect(columns).To(Equal([]string{"name"}))
+			Expect(rows.Close()).To(Succeed())
+		})
+	})
+
+	Context("when the routine does not exist", func() {
+		It("returns an error", func() {
+			_, err := gateway.Run("unknown")
+			Expect(err).To(MatchError("query 'unknown' not found"))
+			Expect(gateway.Stats().Statements).To(BeZero())
+		})
+	})
+
+	Context("when the statement cannot be prepared", func() {
+		BeforeEach(func() {
+			_, err := gateway.DB.Exec("DROP TABLE users")
+			Expect(err).To(BeNil())
+		})
+
+		It("returns an error", func() {
+			_, err := gateway.Exec("insert-user", "John")
+			Expect(err).To(MatchError("no such table: users"))
+		})
+	})
+
+	Context("when the gateway is closed", func() {
+		It("returns an error", func() {
+			rows, err := gateway.Run("select-users", "John")
+			Expect(err).To(Succeed())
+			Expect(rows.Close()).To(Succeed())
+
+			Expect(gateway.Close()).To(Succeed())
+			Expect(gateway.Stats().Statements).To(BeZero())
+
+			_, err = gateway.Run("select-users", "John")
+			Expect(err).To(MatchError("gateway is closed"))
+
+			_, err = gateway.ExecNamed("insert-named-user", map[string]interface{}{})
+			Expect(err).To(MatchError("gateway is closed"))
+		})
+
+		It("keeps the statements open while they are used", func() {
+			_, err := gateway.Exec("insert-user", "John")
+			Expect(err).To(Succeed())
+
+			rows, err := gateway.Run("select-users", "John")
+			Expect(err).To(Succeed())
+
+			Expect(gateway.Close()).To(Succeed())
+
+			Expect(rows.Next()).To(BeTrue())
+			Expect(rows.Err()).To(Succeed())
+			Expect(rows.Close()).To(Succeed())
+		})
+
+		It("does not close the statements of the running executions", func() {
+			group := sync.WaitGroup{}
+
+			for index := 0; index < 10; index++ {
+				group.Add(1)
+
+				go func() {
+					defer GinkgoRecover()
+					defer group.Done()
+
+					for {
+						if _, err := gateway.Exec("insert-user", "John"); err != nil {
+							Expect(err).To(MatchError("gateway is closed"))
+							return
+						}
+					}
+				}()
+			}
+
+			time.Sleep(10 * time.Millisecond)
+			Expect(gateway.Close()).To(Succeed())
+			group.Wait()
+		})
+	})
+
+	Context("when the driver is postgres", func() {
+		It("keeps the question marks of the named routines", func() {
+			db, mock, err := sqlmock.New()
+			Expect(err).To(BeNil())
+
+			buffer := &bytes.Buffer{}
+			fmt.Fprintln(buffer, "-- name: select-tagged-users")
+			fmt.Fprintln(buffer, "SELECT * FROM users WHERE tags ? 'admin' AND name = :name")
+
+			provider := &sqlexec.Provider{DriverName: "postgres"}
+			_, err = provider.ReadFrom(buffer)
+			Expect(err).To(BeNil())
+
+			pgGateway := &sqlexec.Gateway{
+				Provider: provider,
+				DB:       sqlx.NewDb(db, "postgres"),
+			}
+
+			mock.ExpectPrepare(`SELECT \* FROM users WHERE tags \? 'admin' AND name = \$1`).
+				ExpectQuery().
+				WithArgs("John").
+				WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("John"))
+			mock.ExpectClose()
+
+			rows, err := pgGateway.RunNamed("select-tagged-users", map[string]interface{}{"name": "John"})
+			Expect(err).To(Succeed())
+			Expect(rows.Close()).To(Succeed())
+
+			Expect(pgGateway.Close()).To(Succeed())
+			Expect(db.Close()).To(Succeed())
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,270 @@
+package sqlexec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// SeverityError is the severity of an issue that breaks the routines
+	SeverityError = "error"
+	// SeverityWarning is the severity of an issue that might be a mistake
+	SeverityWarning = "warning"
+)
+
+var namedParam = regexp.MustCompile(`(^|[^:\w]):\w+`)
+
+// LintIssue represents a problem found in the SQL routines.
+type LintIssue struct {
+	// File is the path of the file that has the issue
+	File string
+	// Line is the line of the issue in the file
+	Line int
+	// Routine is the name of the routine that has the issue
+	Routine string
+	// Severity is the severity of the issue
+	Severity string
+	// Message describes the issue
+	Message string
+}
+
+// String returns the issue as string
+func (i *LintIssue) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", i.File, i.Line, i.Severity, i.Message)
+}
+
+// Linter validates the SQL routines of all drivers in given directory.
+type Linter struct {
+	// FileSystem represents the routine directory file system.
+	FileSystem FileSystem
+	// DB is an optional client to underlying database. If provided, the
+	// routines for its driver are prepared to detect invalid statements.
+	DB *sqlx.DB
+}
+
+// Lint returns the issues of the routines sorted by their location.
+func (l *Linter) Lint() ([]*LintIssue, error) {
+	issues := []*LintIssue{}
+	definitions := make(map[string][]*Routine)
+
+	err := l.FileSystem.Walk("/", func(path string, info os.FileInfo, err error) error {
+		if info == nil {
+			return os.ErrNotExist
+		}
+
+		if info.IsDir() || !l.filter(path) {
+			return nil
+		}
+
+		routines, errs, err := l.scan(path)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range errs {
+			issues = append(issues, &LintIssue{
+				File:     path,
+				Line:     item.line,
+				Severity: SeverityError,
+				Message:  item.err.Error(),
+			})
+		}
+
+		for _, routine := range routines {
+			definitions[routine.Name] = append(definitions[routine.Name], routine)
+			issues = append(issues, l.check(routine)...)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, routines := range definitions {
+		issues = append(issues, l.conflicts(routines)...)
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		return issues[i].Line < issues[j].Line
+	})
+
+	return issues, nil
+}
+
+func (l *Linter) scan(path string) ([]*Routine, []scanError, error) {
+	file, err := l.FileSystem.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer file.Close()
+
+	scanner := &Scanner{}
+	routines, errs := scanner.scan(file)
+
+	for _, routine := range routines {
+		routine.File = path
+		routine.Driver = PathDriver(path)
+	}
+
+	return routines, errs, nil
+}
+
+func (l *Linter) check(routine *Routine) []*LintIssue {
+	issues := []*LintIssue{}
+
+	add := func(severity, format string, args ...interface{}) {
+		issues = append(issues, &LintIssue{
+			File:     routine.File,
+			Line:     routine.Line,
+			Routine:  routine.Name,
+			Severity: severity,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	if routine.Query == "" {
+		add(SeverityWarning, "routine '%s' is empty", routine.Name)
+		return issues
+	}
+
+	if err := syntax(routine.Query); err != nil {
+		add(SeverityError, "routine '%s' %v", routine.Name, err)
+		return issues
+	}
+
+	// the statement of a template depends on its parameters, so it cannot
+	// be prepared
+	if routine.IsTemplate() {
+		if _, err := routine.template(); err != nil {
+			add(SeverityError, "routine '%s' %v", routine.Name, err)
+		}
+
+		return issues
+	}
+
+	if l.DB == nil {
+		return issues
+	}
+
+	if !routine.IsGeneric() && routine.Driver != l.DB.DriverName() {
+		return issues
+	}
+
+	if err := l.prepare(routine); err != nil {
+		add(SeverityError, "routine '%s' cannot be prepared: %v", routine.Name, err)
+	}
+
+	return issues
+}
+
+func (l *Linter) conflicts(routines []*Routine) []*LintIssue {
+	issues := []*LintIssue{}
+
+	for index, routine := range routines {
+		for _, previous := range routines[:index] {
+			switch {
+			case routine.Driver == previous.Driver:
+				issues = append(issues, &LintIssue{
+					File:     routine.File,
+					Line:     routine.Line,
+					Routine:  routine.Name,
+					Severity: SeverityError,
+					Message:  fmt.Sprintf("routine '%s' is already defined at '%s'", routine.Name, previous.Location()),
+				})
+			case previous.IsGeneric():
+				issues = append(issues, l.shadowed(previous, routine))
+			case routine.IsGeneric():
+				issues = append(issues, l.shadowed(routine, previous))
+			}
+		}
+	}
+
+	return issues
+}
+
+func (l *Linter) shadowed(generic, specific *Routine) *LintIssue {
+	return &LintIssue{
+		File:     generic.File,
+		Line:     generic.Line,
+		Routine:  generic.Name,
+		Severity: SeverityWarning,
+		Message:  fmt.Sprintf("routine '%s' is shadowed by '%s' for driver '%s'", generic.Name, specific.Location(), specific.Driver),
+	}
+}
+
+func (l *Linter) prepare(routine *Routine) error {
+	query := namedParam.ReplaceAllString(routine.Query, "$1?")
+	query = l.DB.Rebind(query)
+
+	tx, err := l.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	stmt, err := tx.Preparex(query)
+	if err != nil {
+		return err
+	}
+
+	return stmt.Close()
+}
+
+func (l *Linter) filter(path string) bool {
+	return filepath.Ext(path) == ".sql"
+}
+
+// syntax checks whether the quotes and the parentheses of a query are
+// balanced
+func syntax(query string) error {
+	var (
+		quote   rune
+		depth   int
+		comment bool
+	)
+
+	chars := []rune(query)
+
+	for index, char := range chars {
+		switch {
+		case comment:
+			comment = char != '\n'
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '-' && index+1 < len(chars) && chars[index+1] == '-':
+			comment = true
+		case char == '\'' || char == '"' || char == '`':
+			quote = char
+		case char == '(':
+			depth++
+		case char == ')':
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("has unbalanced parentheses")
+			}
+		}
+	}
+
+	switch {
+	case quote != 0:
+		return fmt.Errorf("has unterminated quote %q", quote)
+	case depth != 0:
+		return fmt.Errorf("has unbalanced parentheses")
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,171 @@
+package sqlexec_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/prana/fake"
+	"github.com/phogolabs/prana/sqlexec"
+)
+
+var _ = Describe("Linter", func() {
+	var (
+		linter *sqlexec.Linter
+		dir    string
+	)
+
+	write := func(name string, lines ...string) {
+		buffer := &bytes.Buffer{}
+
+		for _, line := range lines {
+			fmt.Fprintln(buffer, line)
+		}
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, name), buffer.Bytes(), 0600)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "prana_linter")
+		Expect(err).To(BeNil())
+
+		linter = &sqlexec.Linter{
+			FileSystem: parcello.Dir(dir),
+		}
+	})
+
+	It("returns no issues for valid routines", func() {
+		write("routine.sql", "-- name: select-users", "SELECT * FROM users WHERE name = 'it''s (me)';")
+
+		issues, err := linter.Lint()
+		Expect(err).To(BeNil())
+		Expect(issues).To(BeEmpty())
+	})
+
+	It("reports the duplicate routines", func() {
+		write("a.sql", "-- name: select-users", "SELECT * FROM users;")
+		write("b.sql", "", "-- name: select-users", "SELECT id FROM users;")
+
+		issues, err := linter.Lint()
+		Expect(err).To(BeNil())
+		Expect(issues).To(HaveLen(1))
+		Expect(issues[0].String()).To(Equal("b.sql:2: error: routine 'select-users' is already defined at 'a.sql:1'"))
+	})
+
+	It("reports the duplicate routines in the same file", func() {
+		write("a.sql", "-- name: select-users", "SELECT * FROM users;", "-- name: select-users", "SELECT id FROM users;")
+
+		issues, err := linter.Lint()
+		Expect(err).To(BeNil())
+		Expect(issues).To(HaveLen(1))
+		Expect(issues[0].String()).To(Equal("a.sql:3: error: routine 'select-users' is already defined at 'a.sql:1'"))
+	})
+
+	It("reports the empty routines", func() {
+		write("a.sql", "-- name: select-users", "", "-- name: select-roles", "SELECT * FROM roles;")
+
+		issues, err := linter.Lint()
+		Expect(err).To(BeNil())
+		Expect(issues).To(HaveLen(1))
+		Expect(issues[0].Routine).To(Equal("select-users"))
+		Expect(issues[0].Severity).To(Equal(sqlexec.SeverityWarning))
+		Expect(issues[0].Message).To(Equal("routine 'select-users' is empty"))
+	})
+
+	It("reports the routines shadowed by driver specific files", func() {
+		write("a.sql", "-- name: select-users", "SELECT * FROM users;")
+		write("a_postgres.sql", "-- name: select-users", "SELECT * FROM public.users;")
+
+		issues, err := linter.Lint()
+		Expect(err).To(BeNil())
+		Expect(issues).To(HaveLen(1))
+		Expect(issues[0].String()).To(Equal("a.sql:1: warning: routine 'select-users' is shadowed by 'a_postgres.sql:1' for driver 'postgres'"))
+	})
+
+	It("reports the malformed routines", func() {
+		write("a.sql",
+			"-- name: select-users",
+			"-- returns: all",
+			"SELECT * FROM users;",
+			"-- name: select-roles",
+			"SELECT * FROM roles WHERE (id = 1;",
+			"-- name: select-tags",
+			"SELECT * FROM tags WHERE name = 'tag;",
+		)
+
+		issues, err := linter.Lint()
+		Expect(err).To(BeNil())
+		Expect(issues).To(HaveLen(3))
+		Expect(issues[0].String()).To(Equal("a.sql:2: error: routine 'select-users' has invalid returns 'all'"))
+		Expect(issues[1].String()).To(Equal("a.sql:4: error: routine 'select-roles' has unbalanced parentheses"))
+		Expect(issues[2].String()).To(Equal("a.sql:6: error: routine 'select-tags' has unterminated quote '\\''"))
+	})
+
+	It("reports the unsafe templates", func() {
+		write("a.sql",
+			"-- name: search-users",
+			"SELECT * FROM users WHERE {{ if .name }}name = {{ bind .name }}{{ end }};",
+			"-- name: select-users",
+			"SELECT * FROM users WHERE name = '{{ .name }}';",
+		)
+
+		issues, err := linter.Lint()
+		Expect(err).To(BeNil())
+		Expect(issues).To(HaveLen(1))
+		Expect(issues[0].String()).To(Equal("a.sql:3: error: routine 'select-users' has unsafe action {{.name}}: the values should be passed to bind or in"))
+	})
+
+	Context("when the database is provided", func() {
+		BeforeEach(func() {
+			db, err := sqlx.Open("sqlite3", ":memory:")
+			Expect(err).To(BeNil())
+			db.SetMaxOpenConns(1)
+
+			_, err = db.Exec("CREATE TABLE users (id INT, name TEXT)")
+			Expect(err).To(BeNil())
+
+			linter.DB = db
+		})
+
+		AfterEach(func() {
+			Expect(linter.DB.Close()).To(Succeed())
+		})
+
+		It("reports the routines that cannot be prepared", func() {
+			write("a.sql",
+				"-- name: select-users",
+				"SELECT * FROM users WHERE id = :id;",
+				"-- name: select-roles",
+				"SELECT * FROM roles;",
+				"-- name: search-users",
+				"SELECT * FROM users {{ if .id }}WHERE id = {{ bind .id }}{{ end }};",
+			)
+
+			write("a_postgres.sql", "-- name: select-tags", "SELECT * FROM tags;")
+
+			issues, err := linter.Lint()
+			Expect(err).To(BeNil())
+			Expect(issues).To(HaveLen(1))
+			Expect(issues[0].String()).To(Equal("a.sql:3: error: routine 'select-roles' cannot be prepared: no such table: roles"))
+		})
+	})
+
+	Context("when the file system fails", func() {
+		It("returns an error", func() {
+			fileSystem := &fake.FileSystem{}
+			fileSystem.WalkReturns(fmt.Errorf("oh no!"))
+			linter.FileSystem = fileSystem
+
+			issues, err := linter.Lint()
+			Expect(err).To(MatchError("oh no!"))
+			Expect(issues).To(BeNil())
+		})
+	})
+})
@@ -3,14 +3,23 @@
 package sqlexec
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
 	"github.com/jmoiron/sqlx"
 	"github.com/phogolabs/parcello"
 )
 
 var (
 	format = "20060102150405"
+	rows   = regexp.MustCompile(`(?is)^\s*(?:SELECT|WITH|PRAGMA|SHOW|VALUES|EXPLAIN|DESCRIBE)\b|\bRETURNING\b`)
 )
 
+// Drivers are the names of the drivers that can have their own routines
+var Drivers = []string{"sqlite3", "postgres", "mysql"}
+
 // Param is a command parameter for given query.
 type Param = interface{}
 
@@ -20,3 +29,109 @@ type Rows = sqlx.Rows
 
 // FileSystem provides with primitives to work with the underlying file system
 type FileSystem = parcello.FileSystem
+
+const (
+	// ReturnsOne is the kind of routine that returns a single row
+	ReturnsOne = "one"
+	// ReturnsMany is the kind of routine that returns many rows
+	ReturnsMany = "many"
+	// ReturnsExec is the kind of routine that does not return rows
+	ReturnsExec = "exec"
+	// ReturnsAffected is the kind of routine that returns the number of
+	// affected rows
+	ReturnsAffected = "affected"
+)
+
+// Routine represents a named SQL statement and its annotations.
+type Routine struct {
+	// Name is the name of the routine
+	Name string
+	// Doc is the documentation of the routine
+	Doc string
+	// Query is the SQL statement of the routine
+	Query string
+	// Params are the declared parameters of the routine
+	Params []RoutineParam
+	// Returns is the declared kind of result of the routine
+	Returns string
+	// Timeout is the maximum duration of the routine execution
+	Timeout time.Duration
+	// Tags are the tags of the routine
+	Tags []string
+	// File is the path of the file that defines the routine
+	File string
+	// Driver is the driver of the file that defines the routine
+	Driver string
+	// Line is the line of the name tag in the file
+	Line int
+}
+
+// IsGeneric returns true if the routine is defined for all drivers
+func (r *Routine) IsGeneric() bool {
+	return r.Driver == every
+}
+
+// Location returns the file and line that define the routine
+func (r *Routine) Location() string {
+	if r.File == "" {
+		return fmt.Sprintf("line %d", r.Line)
+	}
+
+	return fmt.Sprintf("%s:%d", r.File, r.Line)
+}
+
+// HasTag returns true if the routine is tagged with given tag
+func (r *Routine) HasTag(tag string) bool {
+	for _, item := range r.Tags {
+		if strings.EqualFold(item, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsQuery returns true if the routine returns rows. The returns annotation
+// takes precedence over the kind of the statement.
+func (r *Routine) IsQuery() bool {
+	switch r.Returns {
+	case ReturnsOne, ReturnsMany:
+		return true
+	case ReturnsExec, ReturnsAffected:
+		return false
+	default:
+		return rows.MatchString(r.Query)
+	}
+}
+
+// IsNamed returns true if the routine has named parameters.
+func (r *Routine) IsNamed() bool {
+	return namedParam.MatchString(r.Query)
+}
+
+// NotFoundError is returned when a routine that is expected to return a
+// single row does not return any.
+type NotFoundError struct {
+	// Routine is the name of the routine
+	Routine string
+}
+
+// Error returns the error message
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("routine '%s' returned no rows", e.Routine)
+}
+
+// IsNotFound returns true if the error is caused by a routine that did not
+// return any rows.
+func IsNotFound(err error) bool {
+	_, ok := err.(*NotFoundError)
+	return ok
+}
+
+// RoutineParam represents a declared parameter of SQL routine
+type RoutineParam struct {
+	// Name is the name of the parameter
+	Name string
+	// Type is the Golang type of the parameter
+	Type string
+}
@@ -0,0 +1,60 @@
+package sqlexec_test
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/prana/sqlexec"
+)
+
+var _ = Describe("Routine", func() {
+	Describe("IsQuery", func() {
+		It("returns true for statements that return rows", func() {
+			Expect((&sqlexec.Routine{Query: "SELECT * FROM users"}).IsQuery()).To(BeTrue())
+			Expect((&sqlexec.Routine{Query: "with t AS (SELECT 1) SELECT * FROM t"}).IsQuery()).To(BeTrue())
+			Expect((&sqlexec.Routine{Query: "INSERT INTO users VALUES (1) RETURNING id"}).IsQuery()).To(BeTrue())
+		})
+
+		It("returns false for statements that do not return rows", func() {
+			Expect((&sqlexec.Routine{Query: "INSERT INTO users VALUES (1)"}).IsQuery()).To(BeFalse())
+			Expect((&sqlexec.Routine{Query: "UPDATE users SET name = ?"}).IsQuery()).To(BeFalse())
+			Expect((&sqlexec.Routine{Query: "DELETE FROM users"}).IsQuery()).To(BeFalse())
+		})
+
+		Context("when the routine is annotated", func() {
+			It("respects the annotation", func() {
+				routine := &sqlexec.Routine{
+					Query:   "SELECT * FROM users",
+					Returns: sqlexec.ReturnsAffected,
+				}
+
+				Expect(routine.IsQuery()).To(BeFalse())
+
+				routine = &sqlexec.Routine{
+					Query:   "CALL users()",
+					Returns: sqlexec.ReturnsMany,
+				}
+
+				Expect(routine.IsQuery()).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("IsNamed", func() {
+		It("returns true for statements that have named parameters", func() {
+			Expect((&sqlexec.Routine{Query: "SELECT * FROM users WHERE id = :id"}).IsNamed()).To(BeTrue())
+			Expect((&sqlexec.Routine{Query: "SELECT * FROM users WHERE id = ?"}).IsNamed()).To(BeFalse())
+			Expect((&sqlexec.Routine{Query: "SELECT id::text FROM users"}).IsNamed()).To(BeFalse())
+		})
+	})
+
+	Describe("NotFoundError", func() {
+		It("returns the error message", func() {
+			err := &sqlexec.NotFoundError{Routine: "select-user"}
+			Expect(err).To(MatchError("routine 'select-user' returned no rows"))
+			Expect(sqlexec.IsNotFound(err)).To(BeTrue())
+			Expect(sqlexec.IsNotFound(fmt.Errorf("oh no!"))).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,352 @@
+package sqlexec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// ParamString is a string parameter
+	ParamString = "string"
+	// ParamInt is an integer parameter
+	ParamInt = "int"
+	// ParamFloat is a floating point parameter
+	ParamFloat = "float"
+	// ParamBool is a boolean parameter
+	ParamBool = "bool"
+	// ParamNull is a NULL parameter
+	ParamNull = "null"
+	// ParamTime is a time parameter in RFC3339 or date format
+	ParamTime = "time"
+	// ParamJSON is a JSON document parameter
+	ParamJSON = "json"
+)
+
+// paramKinds maps the declared types of the routine params to the kind of
+// the parameter
+var paramKinds = map[string]string{
+	"string":          ParamString,
+	"null.string":     ParamString,
+	"sql.nullstring":  ParamString,
+	"int":             ParamInt,
+	"int8":            ParamInt,
+	"int16":           ParamInt,
+	"int32":           ParamInt,
+	"int64":           ParamInt,
+	"uint":            ParamInt,
+	"uint8":           ParamInt,
+	"uint16":          ParamInt,
+	"uint32":          ParamInt,
+	"uint64":          ParamInt,
+	"null.int":        ParamInt,
+	"null.int8":       ParamInt,
+	"null.int16":      ParamInt,
+	"null.int32":      ParamInt,
+	"null.int64":      ParamInt,
+	"null.uint":       ParamInt,
+	"null.uint8":      ParamInt,
+	"null.uint16":     ParamInt,
+	"null.uint32":     ParamInt,
+	"null.uint64":     ParamInt,
+	"sql.nullint32":   ParamInt,
+	"sql.nullint64":   ParamInt,
+	"float":           ParamFloat,
+	"float32":         ParamFloat,
+	"float64":         ParamFloat,
+	"null.float":      ParamFloat,
+	"null.float32":    ParamFloat,
+	"null.float64":    ParamFloat,
+	"sql.nullfloat64": ParamFloat,
+	"bool":            ParamBool,
+	"null.bool":       ParamBool,
+	"sql.nullbool":    ParamBool,
+	"time":            ParamTime,
+	"time.time":       ParamTime,
+	"null.time":       ParamTime,
+	"sql.nulltime":    ParamTime,
+	"json":            ParamJSON,
+	"json.rawmessage": ParamJSON,
+}
+
+// pgParamTypes maps the PostgreSQL types of the statement parameters to the
+// declared types of the routine params. The types are nullable, because
+// PostgreSQL does not expose the nullability of the parameters.
+var pgParamTypes = map[string]string{
+	"smallint":                    "*int64",
+	"integer":                     "*int64",
+	"bigint":                      "*int64",
+	"real":                        "*float64",
+	"double precision":            "*float64",
+	"numeric":                     "*float64",
+	"boolean":                     "*bool",
+	"date":                        "*time.Time",
+	"timestamp without time zone": "*time.Time",
+	"timestamp with time zone":    "*time.Time",
+	"json":                        "json",
+	"jsonb":                       "json",
+}
+
+// ParseParam converts a textual parameter to a typed value. The value can
+// have a kind prefix, such as int:42, null: or json:@file.json. Otherwise it
+// is converted to the declared type of the parameter. Values that have
+// neither are passed as strings.
+func ParseParam(value, declared string) (Param, error) {
+	if index := strings.Index(value, ":"); index > 0 {
+		if kind := value[:index]; isParamKind(kind) {
+			return parseParam(kind, value[index+1:])
+		}
+	}
+
+	if declared == "" {
+		return value, nil
+	}
+
+	kind, ok := paramKinds[strings.ToLower(strings.TrimPrefix(declared, "*"))]
+	if !ok {
+		return value, nil
+	}
+
+	if value == "" && kind != ParamString && nullable(declared) {
+		return nil, nil
+	}
+
+	return parseParam(kind, value)
+}
+
+// ParseParams converts the textual parameters to typed values by using the
+// declared params of the routine in their order.
+func (r *Routine) ParseParams(values []string) ([]Param, error) {
+	params := []Param{}
+
+	for index, value := range values {
+		declared := ""
+
+		if index < len(r.Params) {
+			declared = r.Params[index].Type
+		}
+
+		param, err := ParseParam(value, declared)
+		if err != nil {
+			return nil, err
+		}
+
+		params = append(params, param)
+	}
+
+	return params, nil
+}
+
+// ParseNamedParams converts the textual named parameters to typed values by
+// using the declared params of the routine with the same name.
+func (r *Routine) ParseNamedParams(values map[string]string) (map[string]Param, error) {
+	params := make(map[string]Param, len(values))
+
+	for name, value := range values {
+		declared := ""
+
+		for _, param := range r.Params {
+			if param.Name == name {
+				declared = param.Type
+				break
+			}
+		}
+
+		param, err := ParseParam(value, declared)
+		if err != nil {
+			return nil, err
+		}
+
+		params[name] = param
+	}
+
+	return params, nil
+}
+
+// DescribeParams returns a copy of a given routine whose params without a
+// declared type have the type that the database infers for the statement.
+// Only PostgreSQL exposes the types of the statement parameters, so the
+// routine is returned as it is for the other drivers and for the templates.
+func DescribeParams(db *sqlx.DB, routine *Routine) (*Routine, error) {
+	if db.DriverName() != "postgres" || routine.IsTemplate() {
+		return routine, nil
+	}
+
+	query, names := routine.Query, []string{}
+
+	if routine.IsNamed() {
+		for _, match := range namedParam.FindAllStringSubmatch(query, -1) {
+			names = append(names, match[0][len(match[1])+1:])
+		}
+
+		query = namedParam.ReplaceAllString(query, "$1?")
+	}
+
+	types, err := describeParams(db, db.Rebind(query))
+	if err != nil {
+		return nil, err
+	}
+
+	described := *routine
+	described.Params = append([]RoutineParam{}, routine.Params...)
+
+	for position, kind := range types {
+		declared, ok := pgParamTypes[kind]
+		if !ok {
+			continue
+		}
+
+		if len(names) == 0 {
+			for len(described.Params) <= position {
+				described.Params = append(described.Params, RoutineParam{})
+			}
+
+			if described.Params[position].Type == "" {
+				described.Params[position].Type = declared
+			}
+
+			continue
+		}
+
+		described.Params = declareParam(described.Params, names[position], declared)
+	}
+
+	return &described, nil
+}
+
+// describeParams returns the types that PostgreSQL infers for the parameters
+// of given statement
+func describeParams(db *sqlx.DB, query string) ([]string, error) {
+	// the prepared statements belong to the session
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(fmt.Sprintf("PREPARE prana_params AS %s", query)); err != nil {
+		return nil, err
+	}
+
+	defer tx.Exec("DEALLOCATE prana_params")
+
+	statement := &bytes.Buffer{}
+	statement.WriteString("SELECT p.kind::text FROM pg_prepared_statements, ")
+	statement.WriteString("unnest(parameter_types) WITH ORDINALITY AS p(kind, position) ")
+	statement.WriteString("WHERE name = 'prana_params' ORDER BY p.position")
+
+	types := []string{}
+
+	if err = tx.Select(&types, statement.String()); err != nil {
+		return nil, err
+	}
+
+	return types, nil
+}
+
+// declareParam sets the type of the named param unless it is declared
+func declareParam(params []RoutineParam, name, declared string) []RoutineParam {
+	for index := range params {
+		if params[index].Name != name {
+			continue
+		}
+
+		if params[index].Type == "" {
+			params[index].Type = declared
+		}
+
+		return params
+	}
+
+	return append(params, RoutineParam{Name: name, Type: declared})
+}
+
+func parseParam(kind, value string) (Param, error) {
+	var (
+		param Param
+		err   error
+	)
+
+	if kind == ParamJSON {
+		if value, err = readParam(value); err != nil {
+			return nil, err
+		}
+	}
+
+	switch kind {
+	case ParamString:
+		param = value
+	case ParamInt:
+		param, err = strconv.ParseInt(value, 10, 64)
+	case ParamFloat:
+		param, err = strconv.ParseFloat(value, 64)
+	case ParamBool:
+		param, err = strconv.ParseBool(value)
+	case ParamNull:
+		return nil, nil
+	case ParamTime:
+		param, err = parseTime(value)
+	case ParamJSON:
+		param, err = parseJSON(value)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("param '%s' is not a valid %s", value, kind)
+	}
+
+	return param, nil
+}
+
+// readParam reads the content of the file for values in @path format
+func readParam(value string) (string, error) {
+	if !strings.HasPrefix(value, "@") {
+		return value, nil
+	}
+
+	data, err := ioutil.ReadFile(value[1:])
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse("2006-01-02", value)
+}
+
+func parseJSON(value string) (string, error) {
+	if !json.Valid([]byte(value)) {
+		return "", fmt.Errorf("invalid json")
+	}
+
+	// the drivers accept JSON documents as strings
+	return value, nil
+}
+
+func isParamKind(kind string) bool {
+	switch kind {
+	case ParamString, ParamInt, ParamFloat, ParamBool, ParamNull, ParamTime, ParamJSON:
+		return true
+	default:
+		return false
+	}
+}
+
+func nullable(declared string) bool {
+	declared = strings.ToLower(declared)
+	return strings.HasPrefix(declared, "*") ||
+		strings.HasPrefix(declared, "null.") ||
+		strings.HasPrefix(declared, "sql.null")
+}
@@ -0,0 +1,243 @@
+package sqlexec_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/prana/sqlexec"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var _ = Describe("Param", func() {
+	Describe("ParseParam", func() {
+		It("parses the typed values", func() {
+			Expect(sqlexec.ParseParam("int:42", "")).To(Equal(int64(42)))
+			Expect(sqlexec.ParseParam("float:4.2", "")).To(Equal(4.2))
+			Expect(sqlexec.ParseParam("bool:true", "")).To(Equal(true))
+			Expect(sqlexec.ParseParam("string:int:42", "")).To(Equal("int:42"))
+			Expect(sqlexec.ParseParam("json:{\"id\": 1}", "")).To(Equal("{\"id\": 1}"))
+			Expect(sqlexec.ParseParam("time:2024-01-01T00:00:00Z", "")).To(Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+			Expect(sqlexec.ParseParam("time:2024-01-01", "")).To(Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+		})
+
+		It("parses the null value", func() {
+			param, err := sqlexec.ParseParam("null:", "int")
+			Expect(err).To(Succeed())
+			Expect(param).To(BeNil())
+		})
+
+		It("keeps the untyped values as strings", func() {
+			Expect(sqlexec.ParseParam("42", "")).To(Equal("42"))
+			Expect(sqlexec.ParseParam("12:30", "")).To(Equal("12:30"))
+			Expect(sqlexec.ParseParam("", "")).To(Equal(""))
+		})
+
+		It("converts the value to the declared type", func() {
+			Expect(sqlexec.ParseParam("42", "int")).To(Equal(int64(42)))
+			Expect(sqlexec.ParseParam("42", "null.Int")).To(Equal(int64(42)))
+			Expect(sqlexec.ParseParam("42", "null.Int64")).To(Equal(int64(42)))
+			Expect(sqlexec.ParseParam("4.2", "null.Float64")).To(Equal(4.2))
+			Expect(sqlexec.ParseParam("2024-01-01", "null.Time")).To(Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+			Expect(sqlexec.ParseParam("true", "*bool")).To(Equal(true))
+			Expect(sqlexec.ParseParam("2024-01-01", "time.Time")).To(Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+			Expect(sqlexec.ParseParam("42", "string")).To(Equal("42"))
+			Expect(sqlexec.ParseParam("42", "uuid.UUID")).To(Equal("42"))
+		})
+
+		It("prefers the type prefix over the declared type", func() {
+			Expect(sqlexec.ParseParam("string:42", "int")).To(Equal("42"))
+		})
+
+		Context("when the declared type is nullable", func() {
+			It("converts the empty value to null", func() {
+				param, err := sqlexec.ParseParam("", "null.Int")
+				Expect(err).To(Succeed())
+				Expect(param).To(BeNil())
+
+				Expect(sqlexec.ParseParam("", "null.String")).To(Equal(""))
+			})
+		})
+
+		Context("when the value refers to a file", func() {
+			var path string
+
+			BeforeEach(func() {
+				dir, err := ioutil.TempDir("", "prana_param")
+				Expect(err).To(BeNil())
+
+				path = filepath.Join(dir, "user.json")
+				Expect(ioutil.WriteFile(path, []byte(`{"name": "John"}`), 0600)).To(Succeed())
+			})
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(filepath.Dir(path))).To(Succeed())
+			})
+
+			It("reads the content of the file", func() {
+				Expect(sqlexec.ParseParam("json:@"+path, "")).To(Equal(`{"name": "John"}`))
+			})
+
+			It("does not read the file for the other kinds", func() {
+				Expect(sqlexec.ParseParam("string:@"+path, "")).To(Equal("@" + path))
+				Expect(sqlexec.ParseParam("@"+path, "string")).To(Equal("@" + path))
+			})
+
+			Context("when the file does not exist", func() {
+				It("returns an error", func() {
+					_, err := sqlexec.ParseParam("json:@"+path+".missing", "")
+					Expect(os.IsNotExist(err)).To(BeTrue())
+				})
+			})
+		})
+
+		Context("when the value is not valid", func() {
+			It("returns an error", func() {
+				_, err := sqlexec.ParseParam("int:forty", "")
+				Expect(err).To(MatchError("param 'forty' is not a valid int"))
+
+				_, err = sqlexec.ParseParam("yes", "bool")
+				Expect(err).To(MatchError("param 'yes' is not a valid bool"))
+
+				_, err = sqlexec.ParseParam("json:{", "")
+				Expect(err).To(MatchError("param '{' is not a valid json"))
+
+				_, err = sqlexec.ParseParam("time:today", "")
+				Expect(err).To(MatchError("param 'today' is not a valid time"))
+			})
+		})
+	})
+
+	Describe("Routine", func() {
+		var routine *sqlexec.Routine
+
+		BeforeEach(func() {
+			routine = &sqlexec.Routine{
+				Name: "select-users",
+				Params: []sqlexec.RoutineParam{
+					{Name: "id", Type: "int64"},
+					{Name: "active", Type: "bool"},
+				},
+			}
+		})
+
+		It("parses the params in the declared order", func() {
+			params, err := routine.ParseParams([]string{"1", "false", "John"})
+			Expect(err).To(Succeed())
+			Expect(params).To(Equal([]sqlexec.Param{int64(1), false, "John"}))
+		})
+
+		It("parses the named params by their name", func() {
+			params, err := routine.ParseNamedParams(map[string]string{"active": "true", "name": "John"})
+			Expect(err).To(Succeed())
+			Expect(params).To(Equal(map[string]sqlexec.Param{"active": true, "name": "John"}))
+		})
+
+		Context("when the param is not valid", func() {
+			It("returns an error", func() {
+				_, err := routine.ParseParams([]string{"one"})
+				Expect(err).To(MatchError("param 'one' is not a valid int"))
+
+				_, err = routine.ParseNamedParams(map[string]string{"id": "one"})
+				Expect(err).To(MatchError("param 'one' is not a valid int"))
+			})
+		})
+	})
+
+	Describe("DescribeParams", func() {
+		var (
+			db   *sqlx.DB
+			mock sqlmock.Sqlmock
+		)
+
+		BeforeEach(func() {
+			conn, m, err := sqlmock.New()
+			Expect(err).To(BeNil())
+
+			mock = m
+			db = sqlx.NewDb(conn, "postgres")
+		})
+
+		AfterEach(func() {
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		expect := func(query string, types ...string) {
+			rows := sqlmock.NewRows([]string{"kind"})
+
+			for _, kind := range types {
+				rows.AddRow(kind)
+			}
+
+			mock.ExpectBegin()
+			mock.ExpectExec("PREPARE prana_params AS " + regexp.QuoteMeta(query)).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery("SELECT p.kind::text FROM pg_prepared_statements").WillReturnRows(rows)
+			mock.ExpectExec("DEALLOCATE prana_params").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectRollback()
+		}
+
+		It("declares the types of the positional params", func() {
+			expect("SELECT * FROM users WHERE id = $1 AND active = $2 AND name = $3", "integer", "boolean", "text")
+
+			routine := &sqlexec.Routine{
+				Query:  "SELECT * FROM users WHERE id = ? AND active = ? AND name = ?",
+				Params: []sqlexec.RoutineParam{{Name: "id", Type: "string"}},
+			}
+
+			described, err := sqlexec.DescribeParams(db, routine)
+			Expect(err).To(BeNil())
+			Expect(described.Params).To(Equal([]sqlexec.RoutineParam{
+				{Name: "id", Type: "string"},
+				{Type: "*bool"},
+			}))
+
+			Expect(routine.Params).To(HaveLen(1))
+
+			params, err := described.ParseParams([]string{"1", "true", "John"})
+			Expect(err).To(BeNil())
+			Expect(params).To(Equal([]sqlexec.Param{"1", true, "John"}))
+		})
+
+		It("declares the types of the named params", func() {
+			expect("SELECT * FROM users WHERE id = $1 AND created_at > $2::date", "bigint", "date")
+
+			routine := &sqlexec.Routine{
+				Query: "SELECT * FROM users WHERE id = :id AND created_at > :since::date",
+			}
+
+			described, err := sqlexec.DescribeParams(db, routine)
+			Expect(err).To(BeNil())
+			Expect(described.Params).To(Equal([]sqlexec.RoutineParam{
+				{Name: "id", Type: "*int64"},
+				{Name: "since", Type: "*time.Time"},
+			}))
+		})
+
+		Context("when the driver does not expose the parameter types", func() {
+			It("returns the routine as it is", func() {
+				routine := &sqlexec.Routine{Query: "SELECT * FROM users WHERE id = ?"}
+
+				described, err := sqlexec.DescribeParams(sqlx.NewDb(nil, "sqlite3"), routine)
+				Expect(err).To(BeNil())
+				Expect(described).To(Equal(routine))
+			})
+		})
+
+		Context("when the statement cannot be prepared", func() {
+			It("returns an error", func() {
+				mock.ExpectBegin()
+				mock.ExpectExec("PREPARE prana_params").WillReturnError(fmt.Errorf("oh no"))
+				mock.ExpectRollback()
+
+				_, err := sqlexec.DescribeParams(db, &sqlexec.Routine{Query: "SELECT ?"})
+				Expect(err).To(MatchError("oh no"))
+			})
+		})
+	})
+})
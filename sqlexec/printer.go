@@ -0,0 +1,293 @@
+package sqlexec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/gosuri/uitable"
+	"github.com/jmoiron/sqlx"
+)
+
+// Variant is the routine that is active for given driver
+type Variant struct {
+	// Driver is the name of the driver
+	Driver string
+	// Routine is the active routine for the driver
+	Routine *Routine
+}
+
+// Query returns the query of the routine rebound for the driver
+func (v *Variant) Query() string {
+	return sqlx.Rebind(sqlx.BindType(v.Driver), v.Routine.Query)
+}
+
+// Variants returns the active routines of given drivers whose name matches
+// the glob pattern. An empty pattern matches all routines.
+func Variants(fs FileSystem, pattern string, drivers ...string) ([]*Variant, error) {
+	variants := []*Variant{}
+
+	for _, driver := range drivers {
+		provider := &Provider{
+			DriverName: driver,
+		}
+
+		if err := provider.ReadDir(fs); err != nil {
+			return nil, err
+		}
+
+		for _, routine := range provider.Routines() {
+			ok, err := match(pattern, routine.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			if ok {
+				variants = append(variants, &Variant{Driver: driver, Routine: routine})
+			}
+		}
+	}
+
+	// the variants are grouped by routine in the order of the drivers
+	sort.SliceStable(variants, func(i, j int) bool {
+		return variants[i].Routine.Name < variants[j].Routine.Name
+	})
+
+	return variants, nil
+}
+
+// Ftable prints the routine variants as table
+func Ftable(w io.Writer, variants []*Variant) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+
+	table.AddRow("NAME", "DRIVER", "FILE", "RETURNS", "TIMEOUT", "TAGS", "DOC")
+
+	for _, variant := range variants {
+		routine := variant.Routine
+		timeout := "--"
+
+		if routine.Timeout > 0 {
+			timeout = routine.Timeout.String()
+		}
+
+		table.AddRow(
+			routine.Name,
+			variant.Driver,
+			fmt.Sprintf("%s:%d", routine.File, routine.Line),
+			dash(routine.Returns),
+			timeout,
+			dash(strings.Join(routine.Tags, ", ")),
+			dash(strings.Replace(routine.Doc, "\n", " ", -1)),
+		)
+	}
+
+	fmt.Fprintln(w, table)
+}
+
+// Fscript prints the routine variants as SQL script
+func Fscript(w io.Writer, variants []*Variant) {
+	for index, variant := range variants {
+		if index > 0 {
+			fmt.Fprintln(w)
+		}
+
+		fmt.Fprintf(w, "-- name: %s", variant.Routine.Name)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, variant.Query())
+	}
+}
+
+// Fjson prints the routine variants as JSON array
+func Fjson(w io.Writer, variants []*Variant) error {
+	type param struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}
+
+	type item struct {
+		Name    string   `json:"name"`
+		Driver  string   `json:"driver"`
+		File    string   `json:"file"`
+		Line    int      `json:"line"`
+		Doc     string   `json:"doc,omitempty"`
+		Params  []param  `json:"params,omitempty"`
+		Returns string   `json:"returns,omitempty"`
+		Timeout string   `json:"timeout,omitempty"`
+		Tags    []string `json:"tags,omitempty"`
+		Query   string   `json:"query"`
+	}
+
+	items := []item{}
+
+	for _, variant := range variants {
+		routine := variant.Routine
+
+		current := item{
+			Name:    routine.Name,
+			Driver:  variant.Driver,
+			File:    routine.File,
+			Line:    routine.Line,
+			Doc:     routine.Doc,
+			Returns: routine.Returns,
+			Tags:    routine.Tags,
+			Query:   variant.Query(),
+		}
+
+		if routine.Timeout > 0 {
+			current.Timeout = routine.Timeout.String()
+		}
+
+		for _, p := range routine.Params {
+			current.Params = append(current.Params, param{Name: p.Name, Type: p.Type})
+		}
+
+		items = append(items, current)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(items)
+}
+
+func match(pattern, name string) (bool, error) {
+	if pattern == "" {
+		return true, nil
+	}
+
+	return filepath.Match(pattern, name)
+}
+
+func dash(value string) string {
+	if value == "" {
+		return "--"
+	}
+	return value
+}
+
+// Fplan prints the query plan as tree
+func Fplan(w io.Writer, plan *Plan) {
+	fmt.Fprintf(w, "%s (%s)", plan.Routine, plan.Driver)
+	fmt.Fprintln(w)
+
+	var walk func(nodes []*PlanNode, indent string)
+
+	walk = func(nodes []*PlanNode, indent string) {
+		for index, node := range nodes {
+			branch, next := "├── ", "│   "
+
+			if index == len(nodes)-1 {
+				branch, next = "└── ", "    "
+			}
+
+			line := node.Operation
+
+			if node.Rows > 0 {
+				line = fmt.Sprintf("%s (rows=%d)", line, node.Rows)
+			}
+
+			if node.Detail != "" {
+				line = fmt.Sprintf("%s [%s]", line, node.Detail)
+			}
+
+			if node.Warning != "" {
+				line = fmt.Sprintf("%s %s", line, color.YellowString("WARNING: %s", node.Warning))
+			}
+
+			fmt.Fprintln(w, indent+branch+line)
+			walk(node.Children, indent+next)
+		}
+	}
+
+	walk(plan.Nodes, "")
+}
+
+// Flint prints the lint issues with their severity highlighted
+func Flint(w io.Writer, issues []*LintIssue) {
+	for _, issue := range issues {
+		severity := issue.Severity
+
+		switch severity {
+		case SeverityError:
+			severity = color.RedString(severity)
+		case SeverityWarning:
+			severity = color.YellowString(severity)
+		}
+
+		fmt.Fprintf(w, "%s:%d: %s: %s", issue.File, issue.Line, severity, issue.Message)
+		fmt.Fprintln(w)
+	}
+}
+
+// Fbench prints the benchmark result as table. The result is compared to
+// the baseline if it is provided.
+func Fbench(w io.Writer, result *BenchmarkResult, baseline *BenchmarkResult) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+
+	if baseline == nil {
+		baseline = &BenchmarkResult{}
+		table.AddRow("ROUTINE", result.Routine)
+	} else {
+		table.AddRow("ROUTINE", result.Routine, "BASELINE", "CHANGE")
+	}
+
+	row := func(name, current, previous string, change float64, compare bool) {
+		if !compare {
+			table.AddRow(name, current)
+			return
+		}
+
+		table.AddRow(name, current, previous, fmt.Sprintf("%+.1f%%", change))
+	}
+
+	compare := baseline.Iterations > 0
+
+	table.AddRow("ITERATIONS", result.Iterations)
+	table.AddRow("CONCURRENCY", result.Concurrency)
+	table.AddRow("ELAPSED", result.Elapsed)
+
+	row("THROUGHPUT",
+		fmt.Sprintf("%.1f/s", result.Throughput),
+		fmt.Sprintf("%.1f/s", baseline.Throughput),
+		ratio(result.Throughput, baseline.Throughput),
+		compare && baseline.Throughput > 0,
+	)
+
+	latencies := []struct {
+		name     string
+		current  time.Duration
+		baseline time.Duration
+	}{
+		{"MIN", result.Min, baseline.Min},
+		{"MEAN", result.Mean, baseline.Mean},
+		{"P50", result.P50, baseline.P50},
+		{"P95", result.P95, baseline.P95},
+		{"P99", result.P99, baseline.P99},
+		{"MAX", result.Max, baseline.Max},
+	}
+
+	for _, latency := range latencies {
+		row(latency.name,
+			latency.current.String(),
+			latency.baseline.String(),
+			ratio(float64(latency.current), float64(latency.baseline)),
+			compare && latency.baseline > 0,
+		)
+	}
+
+	fmt.Fprintln(w, table)
+}
+
+// ratio returns the change of the current value in percent
+func ratio(current, previous float64) float64 {
+	if previous == 0 {
+		return 0
+	}
+	return 100 * (current/previous - 1)
+}
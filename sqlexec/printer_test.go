@@ -0,0 +1,187 @@
+package sqlexec_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/prana/fake"
+	"github.com/phogolabs/prana/sqlexec"
+)
+
+var _ = Describe("Printer", func() {
+	var variants []*sqlexec.Variant
+
+	BeforeEach(func() {
+		variants = []*sqlexec.Variant{
+			{
+				Driver: "postgres",
+				Routine: &sqlexec.Routine{
+					Name:    "select-user",
+					Doc:     "returns the user",
+					Query:   "SELECT * FROM users WHERE id = ?",
+					Params:  []sqlexec.RoutineParam{{Name: "id", Type: "int"}},
+					Returns: sqlexec.ReturnsOne,
+					Timeout: 2 * time.Second,
+					Tags:    []string{"users", "read"},
+					File:    "users.sql",
+					Line:    3,
+				},
+			},
+		}
+	})
+
+	Describe("Variants", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "prana_printer")
+			Expect(err).To(BeNil())
+
+			generic := &bytes.Buffer{}
+			fmt.Fprintln(generic, "-- name: select-users")
+			fmt.Fprintln(generic, "SELECT * FROM users")
+			fmt.Fprintln(generic)
+			fmt.Fprintln(generic, "-- name: delete-users")
+			fmt.Fprintln(generic, "DELETE FROM users")
+
+			Expect(ioutil.WriteFile(filepath.Join(dir, "users.sql"), generic.Bytes(), 0700)).To(Succeed())
+
+			specific := &bytes.Buffer{}
+			fmt.Fprintln(specific, "-- name: select-users")
+			fmt.Fprintln(specific, "SELECT * FROM public.users")
+
+			Expect(ioutil.WriteFile(filepath.Join(dir, "users_postgres.sql"), specific.Bytes(), 0700)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("returns the active variants of each driver", func() {
+			result, err := sqlexec.Variants(parcello.Dir(dir), "", "sqlite3", "postgres")
+			Expect(err).To(Succeed())
+			Expect(result).To(HaveLen(4))
+
+			Expect(result[0].Routine.Name).To(Equal("delete-users"))
+			Expect(result[0].Driver).To(Equal("sqlite3"))
+			Expect(result[1].Routine.Name).To(Equal("delete-users"))
+			Expect(result[1].Driver).To(Equal("postgres"))
+
+			Expect(result[2].Driver).To(Equal("sqlite3"))
+			Expect(result[2].Routine.File).To(Equal("users.sql"))
+			Expect(result[3].Driver).To(Equal("postgres"))
+			Expect(result[3].Routine.File).To(Equal("users_postgres.sql"))
+		})
+
+		It("filters the routines by glob pattern", func() {
+			result, err := sqlexec.Variants(parcello.Dir(dir), "select-*", "sqlite3")
+			Expect(err).To(Succeed())
+			Expect(result).To(HaveLen(1))
+			Expect(result[0].Routine.Name).To(Equal("select-users"))
+		})
+
+		Context("when the pattern is invalid", func() {
+			It("returns an error", func() {
+				_, err := sqlexec.Variants(parcello.Dir(dir), "[", "sqlite3")
+				Expect(err).To(MatchError("syntax error in pattern"))
+			})
+		})
+
+		Context("when the file system fails", func() {
+			It("returns the error", func() {
+				fileSystem := &fake.FileSystem{}
+				fileSystem.WalkReturns(fmt.Errorf("Oh no!"))
+
+				_, err := sqlexec.Variants(fileSystem, "", "sqlite3")
+				Expect(err).To(MatchError("Oh no!"))
+			})
+		})
+	})
+
+	Describe("Query", func() {
+		It("rebinds the query for the driver", func() {
+			Expect(variants[0].Query()).To(Equal("SELECT * FROM users WHERE id = $1"))
+		})
+	})
+
+	Describe("Ftable", func() {
+		It("prints the routines", func() {
+			w := &bytes.Buffer{}
+			sqlexec.Ftable(w, variants)
+
+			content := w.String()
+			Expect(content).To(ContainSubstring("NAME"))
+			Expect(content).To(ContainSubstring("DRIVER"))
+			Expect(content).To(ContainSubstring("select-user"))
+			Expect(content).To(ContainSubstring("postgres"))
+			Expect(content).To(ContainSubstring("users.sql:3"))
+			Expect(content).To(ContainSubstring("2s"))
+			Expect(content).To(ContainSubstring("users, read"))
+			Expect(content).To(ContainSubstring("returns the user"))
+		})
+	})
+
+	Describe("Fscript", func() {
+		It("prints the rebound queries", func() {
+			w := &bytes.Buffer{}
+			sqlexec.Fscript(w, variants)
+			Expect(w.String()).To(Equal("-- name: select-user\nSELECT * FROM users WHERE id = $1\n"))
+		})
+	})
+
+	Describe("Flint", func() {
+		It("prints the issues", func() {
+			w := &bytes.Buffer{}
+			sqlexec.Flint(w, []*sqlexec.LintIssue{
+				{File: "users.sql", Line: 1, Severity: sqlexec.SeverityWarning, Message: "routine 'a' is empty"},
+				{File: "users.sql", Line: 3, Severity: sqlexec.SeverityError, Message: "routine 'b' is already defined"},
+			})
+
+			content := w.String()
+			Expect(content).To(ContainSubstring("users.sql:1: "))
+			Expect(content).To(ContainSubstring("warning"))
+			Expect(content).To(ContainSubstring(": routine 'a' is empty\n"))
+			Expect(content).To(ContainSubstring("users.sql:3: "))
+			Expect(content).To(ContainSubstring("error"))
+			Expect(content).To(ContainSubstring(": routine 'b' is already defined\n"))
+		})
+	})
+
+	Describe("Fjson", func() {
+		It("prints the routines", func() {
+			w := &bytes.Buffer{}
+			Expect(sqlexec.Fjson(w, variants)).To(Succeed())
+
+			items := []map[string]interface{}{}
+			Expect(json.Unmarshal(w.Bytes(), &items)).To(Succeed())
+			Expect(items).To(HaveLen(1))
+
+			item := items[0]
+			Expect(item).To(HaveKeyWithValue("name", "select-user"))
+			Expect(item).To(HaveKeyWithValue("driver", "postgres"))
+			Expect(item).To(HaveKeyWithValue("file", "users.sql"))
+			Expect(item).To(HaveKeyWithValue("line", BeEquivalentTo(3)))
+			Expect(item).To(HaveKeyWithValue("returns", "one"))
+			Expect(item).To(HaveKeyWithValue("timeout", "2s"))
+			Expect(item).To(HaveKeyWithValue("query", "SELECT * FROM users WHERE id = $1"))
+			Expect(item["params"]).To(HaveLen(1))
+		})
+
+		Context("when there are no routines", func() {
+			It("prints an empty array", func() {
+				w := &bytes.Buffer{}
+				Expect(sqlexec.Fjson(w, []*sqlexec.Variant{})).To(Succeed())
+				Expect(w.String()).To(Equal("[]\n"))
+			})
+		})
+	})
+})
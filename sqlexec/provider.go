@@ -1,31 +1,54 @@
 package sqlexec
 
 import (
+	"context"
+	"database/sql/driver"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/lib/pq"
 )
 
 const every = "sql"
 
+var (
+	anyParam       = regexp.MustCompile(`(?i)\b(?:ANY|ALL)\s*\(\s*\?\s*\)`)
+	anyDollarParam = regexp.MustCompile(`(?i)\b(?:ANY|ALL)\s*\(\s*\$(\d+)\s*\)`)
+	dollarParam    = regexp.MustCompile(`\$(\d+)`)
+)
+
 // Provider loads SQL sqlexecs and provides all SQL statements as commands.
 type Provider struct {
 	// DriverName is the current SQL driver
 	DriverName string
+	// Mapper maps the struct fields to the named parameters. The default
+	// mapper of sqlx is used if it is not set.
+	Mapper *reflectx.Mapper
 	// private fields
 	mu         sync.RWMutex
-	repository map[string]string
+	repository map[string]*Routine
+	generic    map[string]*Routine
+	files      map[string]string
+	version    uint64
 }
 
 // ReadDir loads all sqlexec commands from a given directory. Note that all
 // sqlexecs should have .sql extension.
 func (p *Provider) ReadDir(fs FileSystem) error {
-	return fs.Walk("/", func(path string, info os.FileInfo, err error) error {
+	files := make(map[string]string)
+
+	err := fs.Walk("/", func(path string, info os.FileInfo, err error) error {
 		if info == nil {
 			return os.ErrNotExist
 		}
@@ -34,8 +57,24 @@ func (p *Provider) ReadDir(fs FileSystem) error {
 			return nil
 		}
 
+		// the file is stamped before it is read so that the watcher picks
+		// up the changes made in the meantime
+		if p.filter(path) {
+			files[path] = stamp(info)
+		}
+
 		return p.ReadFile(path, fs)
 	})
+
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.files = files
+	p.mu.Unlock()
+
+	return nil
 }
 
 // ReadFile reads a given file
@@ -55,7 +94,7 @@ func (p *Provider) ReadFile(path string, fs FileSystem) error {
 		}
 	}()
 
-	if _, err = p.ReadFrom(file); err != nil {
+	if _, err = p.read(file, path); err != nil {
 		return err
 	}
 
@@ -64,25 +103,59 @@ func (p *Provider) ReadFile(path string, fs FileSystem) error {
 
 // ReadFrom reads the sqlexec from a reader
 func (p *Provider) ReadFrom(r io.Reader) (int64, error) {
+	return p.read(r, "")
+}
+
+func (p *Provider) read(r io.Reader, path string) (int64, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	if p.repository == nil {
-		p.repository = make(map[string]string)
+		p.repository = make(map[string]*Routine)
+	}
+
+	if p.generic == nil {
+		p.generic = make(map[string]*Routine)
 	}
 
 	scanner := &Scanner{}
-	stmts := scanner.Scan(r)
+	routines, err := scanner.ScanRoutines(r)
+	if err != nil {
+		return 0, err
+	}
+
+	registered := 0
+
+	for _, routine := range routines {
+		routine.File = path
+		routine.Driver = PathDriver(path)
+
+		// the generic routines are tracked on their own, so they are
+		// compared with each other even if they are overridden
+		if routine.Driver == every {
+			if existing, ok := p.generic[routine.Name]; ok {
+				return 0, existQueryErr(existing, routine)
+			}
+
+			p.generic[routine.Name] = routine
+		}
 
-	for name, stmt := range stmts {
-		if _, ok := p.repository[name]; ok {
-			return 0, fmt.Errorf("query '%s' already exists", name)
+		if existing, ok := p.repository[routine.Name]; ok {
+			switch {
+			// the driver specific routine overrides the generic one
+			case existing.Driver == every && routine.Driver != every:
+			case existing.Driver != every && routine.Driver == every:
+				continue
+			default:
+				return 0, existQueryErr(existing, routine)
+			}
 		}
 
-		p.repository[name] = stmt
+		p.repository[routine.Name] = routine
+		registered++
 	}
 
-	return int64(len(stmts)), nil
+	return int64(registered), nil
 }
 
 // Query returns a query statement for given name and parameters. The operation can
@@ -91,13 +164,253 @@ func (p *Provider) Query(name string) (string, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	if query, ok := p.repository[name]; ok {
-		return sqlx.Rebind(sqlx.BindType(p.DriverName), query), nil
+	if routine, ok := p.repository[name]; ok {
+		if routine.IsTemplate() {
+			return "", fmt.Errorf("routine '%s' is a template that should be rendered with named parameters", name)
+		}
+
+		return sqlx.Rebind(sqlx.BindType(p.DriverName), routine.Query), nil
 	}
 
 	return "", nonExistQueryErr(name)
 }
 
+// Bind returns the query of a routine in the bind type of the driver for
+// given arguments. The placeholder of a slice argument is expanded to a list
+// of placeholders, one for each item. On PostgreSQL the slice argument of
+// '= ANY(?)' is passed as an array instead.
+func (p *Provider) Bind(name string, args ...Param) (string, []Param, error) {
+	query, err := p.Query(name)
+	if err != nil || !slices(args) {
+		return query, args, err
+	}
+
+	routine, err := p.Routine(name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return expand(p.DriverName, routine.Query, args)
+}
+
+// BindNamed returns the query of a routine that has named parameters in the
+// bind type of the driver and its arguments. The argument can be a struct or
+// map[string]interface{}. The slice values are expanded as in Bind and the
+// templates are rendered for the argument.
+func (p *Provider) BindNamed(name string, arg Param) (string, []Param, error) {
+	query, args, _, err := p.bindNamed(name, arg)
+	return query, args, err
+}
+
+// bindNamed binds a routine that has named parameters and reports whether
+// its query depends on the argument, because it is rendered from a template
+// or its slices are expanded
+func (p *Provider) bindNamed(name string, arg Param) (string, []Param, bool, error) {
+	if p.IsTemplate(name) {
+		query, args, err := p.Render(name, arg)
+		return query, args, true, err
+	}
+
+	routine, err := p.Routine(name)
+	if err != nil {
+		return "", nil, false, err
+	}
+
+	// the query is bound in the bind type of the driver, so the question
+	// marks of the routine are not taken as placeholders on PostgreSQL
+	query, args, err := binder(p.DriverName, p.Mapper).BindNamed(routine.Query, arg)
+	if err != nil || !slices(args) {
+		return query, args, false, err
+	}
+
+	if sqlx.BindType(p.DriverName) == sqlx.DOLLAR {
+		query, args, err = expandDollar(query, args)
+	} else {
+		query, args, err = expand(p.DriverName, query, args)
+	}
+
+	return query, args, true, err
+}
+
+func binder(driverName string, mapper *reflectx.Mapper) *sqlx.DB {
+	db := sqlx.NewDb(nil, driverName)
+
+	if mapper != nil {
+		db.Mapper = mapper
+	}
+
+	return db
+}
+
+// IsTemplate returns true if the routine with given name contains template
+// actions.
+func (p *Provider) IsTemplate(name string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	routine, ok := p.repository[name]
+	return ok && routine.IsTemplate()
+}
+
+// Render renders a routine that contains template actions for given named
+// parameters. The argument can be a struct or map[string]interface{}. The
+// values are bound as parameters by the bind and in actions. It returns the
+// query in the bind type of the driver and its arguments.
+func (p *Provider) Render(name string, arg Param) (string, []Param, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	routine, ok := p.repository[name]
+	if !ok {
+		return "", nil, nonExistQueryErr(name)
+	}
+
+	renderer := &renderer{
+		provider: p,
+		data:     arg,
+		args:     []Param{},
+	}
+
+	query, err := renderer.render(routine)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query = sqlx.Rebind(sqlx.BindType(p.DriverName), clean(query))
+	return query, renderer.args, nil
+}
+
+// Routine returns the routine for given name. The query of the routine uses
+// the bind parameters in which it is written.
+func (p *Provider) Routine(name string) (*Routine, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if routine, ok := p.repository[name]; ok {
+		return routine, nil
+	}
+
+	return nil, nonExistQueryErr(name)
+}
+
+// Routines returns all loaded routines sorted by their name.
+func (p *Provider) Routines() []*Routine {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	routines := []*Routine{}
+
+	for _, routine := range p.repository {
+		routines = append(routines, routine)
+	}
+
+	sort.Slice(routines, func(i, j int) bool {
+		return routines[i].Name < routines[j].Name
+	})
+
+	return routines
+}
+
+// Watch polls a given directory with given interval and reloads the routines
+// when its files change until the context is canceled. The repository is
+// replaced only if all files are read successfully. Otherwise the error is
+// reported and the last good version is kept. The files are compared with
+// the ones read by ReadDir, so the changes made before the watch starts are
+// picked up as well. The report function can be nil.
+func (p *Provider) Watch(ctx context.Context, fs FileSystem, interval time.Duration, report func(error)) {
+	// the files are compared with the ones read by ReadDir if any
+	p.mu.RLock()
+	current := p.files
+	p.mu.RUnlock()
+
+	if current == nil {
+		current, _ = p.snapshot(fs)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			next, err := p.snapshot(fs)
+			if err == nil && reflect.DeepEqual(current, next) {
+				continue
+			}
+
+			// the broken files are reported once until they change again
+			current = next
+
+			if err == nil {
+				err = p.reload(fs)
+			}
+
+			if err != nil && report != nil {
+				report(err)
+			}
+		}
+	}()
+}
+
+// reload reads the routines of a given directory and replaces the
+// repository with them
+func (p *Provider) reload(fs FileSystem) error {
+	provider := &Provider{
+		DriverName: p.DriverName,
+	}
+
+	if err := provider.ReadDir(fs); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.repository = provider.repository
+	p.generic = provider.generic
+	p.files = provider.files
+	p.version++
+
+	return nil
+}
+
+// snapshot returns the modification time and size of all routine files
+func (p *Provider) snapshot(fs FileSystem) (map[string]string, error) {
+	files := make(map[string]string)
+
+	err := fs.Walk("/", func(path string, info os.FileInfo, err error) error {
+		if info == nil {
+			return os.ErrNotExist
+		}
+
+		if !info.IsDir() && p.filter(path) {
+			files[path] = stamp(info)
+		}
+
+		return nil
+	})
+
+	return files, err
+}
+
+// stamp returns the modification time and size of a given file
+func stamp(info os.FileInfo) string {
+	return fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size())
+}
+
+// revision returns the number of times the repository has been reloaded
+func (p *Provider) revision() uint64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.version
+}
+
 // Filter returns true if the file can be processed for the current driver
 func (p *Provider) filter(path string) bool {
 	ext := filepath.Ext(path)
@@ -118,12 +431,148 @@ func PathDriver(path string) string {
 	parts := strings.Split(path, "_")
 	driver := strings.ToLower(parts[len(parts)-1])
 
-	switch driver {
-	case "sqlite3", "postgres", "mysql":
-		return driver
-	default:
-		return every
+	for _, name := range Drivers {
+		if driver == name {
+			return driver
+		}
+	}
+
+	return every
+}
+
+// slices returns true if any of the arguments should be expanded
+func slices(args []Param) bool {
+	for _, arg := range args {
+		if expandable(arg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func expandable(arg Param) bool {
+	if _, ok := arg.(driver.Valuer); ok {
+		return false
+	}
+
+	value := reflect.ValueOf(arg)
+	return value.Kind() == reflect.Slice && value.Type() != reflect.TypeOf([]byte{})
+}
+
+// expand expands the slice arguments of a query that uses question mark
+// placeholders and rebinds it for the driver
+func expand(driverName, query string, args []Param) (string, []Param, error) {
+	args = append([]Param{}, args...)
+
+	// the arrays are hidden from sqlx.In, so it does not expand them
+	if driverName == "postgres" {
+		for _, index := range arrayParams(query) {
+			if index < len(args) && expandable(args[index]) {
+				args[index] = &array{value: args[index]}
+			}
+		}
+	}
+
+	query, args, err := sqlx.In(query, args...)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for index, arg := range args {
+		if item, ok := arg.(*array); ok {
+			args[index] = pq.Array(item.value)
+		}
+	}
+
+	return sqlx.Rebind(sqlx.BindType(driverName), query), args, nil
+}
+
+// expandDollar expands the slice arguments of a query that uses dollar
+// placeholders. The placeholders are numbered again in the order of the
+// expanded arguments.
+func expandDollar(query string, args []Param) (string, []Param, error) {
+	arrays := make(map[int]bool)
+
+	for _, match := range anyDollarParam.FindAllStringSubmatch(query, -1) {
+		index, _ := strconv.Atoi(match[1])
+		arrays[index-1] = true
+	}
+
+	var (
+		expanded = []Param{}
+		err      error
+	)
+
+	next := func(arg Param) string {
+		expanded = append(expanded, arg)
+		return "$" + strconv.Itoa(len(expanded))
 	}
+
+	query = dollarParam.ReplaceAllStringFunc(query, func(placeholder string) string {
+		index, _ := strconv.Atoi(placeholder[1:])
+		index--
+
+		if index < 0 || index >= len(args) {
+			return placeholder
+		}
+
+		arg := args[index]
+
+		switch {
+		case !expandable(arg):
+			return next(arg)
+		case arrays[index]:
+			return next(pq.Array(arg))
+		}
+
+		value := reflect.ValueOf(arg)
+
+		if value.Len() == 0 {
+			err = fmt.Errorf("empty slice passed to 'in' query")
+			return placeholder
+		}
+
+		items := make([]string, value.Len())
+
+		for item := range items {
+			items[item] = next(value.Index(item).Interface())
+		}
+
+		return strings.Join(items, ", ")
+	})
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	return query, expanded, nil
+}
+
+// array is a slice argument that is passed as PostgreSQL array
+type array struct {
+	value Param
+}
+
+// arrayParams returns the positions of the parameters compared with
+// ANY or ALL
+func arrayParams(query string) []int {
+	positions := []int{}
+
+	for _, match := range anyParam.FindAllStringIndex(query, -1) {
+		placeholder := match[0] + strings.IndexByte(query[match[0]:match[1]], '?')
+		positions = append(positions, strings.Count(query[:placeholder], "?"))
+	}
+
+	return positions
+}
+
+func existQueryErr(existing, routine *Routine) error {
+	if existing.File == "" && routine.File == "" {
+		return fmt.Errorf("query '%s' already exists", routine.Name)
+	}
+
+	return fmt.Errorf("query '%s' already exists: defined at '%s' and '%s'", routine.Name, existing.Location(), routine.Location())
 }
 
 func nonExistQueryErr(name string) error {
@@ -2,13 +2,20 @@ package sqlexec_test
 
 import (
 	"bytes"
+	"context"
+	"database/sql/driver"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/jmoiron/sqlx/reflectx"
 	"github.com/phogolabs/parcello"
 	"github.com/phogolabs/prana/fake"
 	"github.com/phogolabs/prana/sqlexec"
@@ -184,7 +191,7 @@ var _ = Describe("Provider", func() {
 					Expect(provider.ReadDir(fileSystem)).To(Succeed())
 
 					fileSystem.OpenFileReturns(parcello.NewResourceFile(node), nil)
-					Expect(provider.ReadDir(fileSystem)).To(MatchError("query 'up' already exists"))
+					Expect(provider.ReadDir(fileSystem)).To(MatchError("query 'up' already exists: defined at 'file.sql:1' and 'file.sql:1'"))
 				})
 			})
 		})
@@ -237,4 +244,381 @@ var _ = Describe("Provider", func() {
 			})
 		})
 	})
+
+	Describe("Bind", func() {
+		BeforeEach(func() {
+			buffer := &bytes.Buffer{}
+			fmt.Fprintln(buffer, "-- name: select-users")
+			fmt.Fprintln(buffer, "SELECT * FROM users WHERE id IN (?) AND name = ?")
+			fmt.Fprintln(buffer)
+			fmt.Fprintln(buffer, "-- name: select-named-users")
+			fmt.Fprintln(buffer, "SELECT * FROM users WHERE id IN (:ids) AND name = :name")
+			fmt.Fprintln(buffer)
+			fmt.Fprintln(buffer, "-- name: select-any-users")
+			fmt.Fprintln(buffer, "SELECT * FROM users WHERE name = ? AND id = ANY(?)")
+			fmt.Fprintln(buffer)
+			fmt.Fprintln(buffer, "-- name: select-named-any-users")
+			fmt.Fprintln(buffer, "SELECT * FROM users WHERE name = :name AND id = ANY(:ids)")
+			fmt.Fprintln(buffer)
+			fmt.Fprintln(buffer, "-- name: select-tagged-users")
+			fmt.Fprintln(buffer, "SELECT * FROM users WHERE tags ? 'admin' AND id IN (:ids) AND name = :name")
+
+			_, err := provider.ReadFrom(buffer)
+			Expect(err).To(Succeed())
+		})
+
+		It("expands the slice parameters", func() {
+			query, args, err := provider.Bind("select-users", []int64{1, 2, 3}, "John")
+			Expect(err).To(BeNil())
+			Expect(query).To(Equal("SELECT * FROM users WHERE id IN (?, ?, ?) AND name = ?"))
+			Expect(args).To(Equal([]interface{}{int64(1), int64(2), int64(3), "John"}))
+		})
+
+		It("does not expand the binary parameters", func() {
+			query, args, err := provider.Bind("select-users", 1, []byte("John"))
+			Expect(err).To(BeNil())
+			Expect(query).To(Equal("SELECT * FROM users WHERE id IN (?) AND name = ?"))
+			Expect(args).To(Equal([]interface{}{1, []byte("John")}))
+		})
+
+		It("expands the named slice parameters", func() {
+			query, args, err := provider.BindNamed("select-named-users", map[string]interface{}{
+				"ids":  []int{1, 2},
+				"name": "John",
+			})
+
+			Expect(err).To(BeNil())
+			Expect(query).To(Equal("SELECT * FROM users WHERE id IN (?, ?) AND name = ?"))
+			Expect(args).To(Equal([]interface{}{1, 2, "John"}))
+		})
+
+		Context("when the provider has a mapper", func() {
+			BeforeEach(func() {
+				provider.Mapper = reflectx.NewMapperFunc("json", strings.ToLower)
+			})
+
+			It("binds the struct fields with the mapper", func() {
+				arg := struct {
+					IDs  []int  `json:"ids"`
+					User string `json:"name"`
+				}{
+					IDs:  []int{1, 2},
+					User: "John",
+				}
+
+				query, args, err := provider.BindNamed("select-named-users", arg)
+				Expect(err).To(BeNil())
+				Expect(query).To(Equal("SELECT * FROM users WHERE id IN (?, ?) AND name = ?"))
+				Expect(args).To(Equal([]interface{}{1, 2, "John"}))
+			})
+		})
+
+		Context("when the driver is postgres", func() {
+			BeforeEach(func() {
+				provider.DriverName = "postgres"
+			})
+
+			It("expands the named slice parameters", func() {
+				query, args, err := provider.BindNamed("select-named-users", map[string]interface{}{
+					"ids":  []int{1, 2},
+					"name": "John",
+				})
+
+				Expect(err).To(BeNil())
+				Expect(query).To(Equal("SELECT * FROM users WHERE id IN ($1, $2) AND name = $3"))
+				Expect(args).To(Equal([]interface{}{1, 2, "John"}))
+			})
+
+			It("keeps the question marks of the named routines", func() {
+				query, args, err := provider.BindNamed("select-tagged-users", map[string]interface{}{
+					"ids":  []int{1, 2},
+					"name": "John",
+				})
+
+				Expect(err).To(BeNil())
+				Expect(query).To(Equal("SELECT * FROM users WHERE tags ? 'admin' AND id IN ($1, $2) AND name = $3"))
+				Expect(args).To(Equal([]interface{}{1, 2, "John"}))
+
+				query, args, err = provider.BindNamed("select-tagged-users", map[string]interface{}{
+					"ids":  1,
+					"name": "John",
+				})
+
+				Expect(err).To(BeNil())
+				Expect(query).To(Equal("SELECT * FROM users WHERE tags ? 'admin' AND id IN ($1) AND name = $2"))
+				Expect(args).To(Equal([]interface{}{1, "John"}))
+			})
+
+			It("passes the named parameters of ANY as array", func() {
+				query, args, err := provider.BindNamed("select-named-any-users", map[string]interface{}{
+					"ids":  []int64{1, 2},
+					"name": "John",
+				})
+
+				Expect(err).To(BeNil())
+				Expect(query).To(Equal("SELECT * FROM users WHERE name = $1 AND id = ANY($2)"))
+				Expect(args).To(HaveLen(2))
+
+				value, err := args[1].(driver.Valuer).Value()
+				Expect(err).To(BeNil())
+				Expect(value).To(Equal("{1,2}"))
+			})
+
+			It("returns an error for the empty named slice", func() {
+				_, _, err := provider.BindNamed("select-named-users", map[string]interface{}{
+					"ids":  []int{},
+					"name": "John",
+				})
+
+				Expect(err).To(MatchError("empty slice passed to 'in' query"))
+			})
+
+			It("rebinds the expanded parameters", func() {
+				query, _, err := provider.Bind("select-users", []int64{1, 2}, "John")
+				Expect(err).To(BeNil())
+				Expect(query).To(Equal("SELECT * FROM users WHERE id IN ($1, $2) AND name = $3"))
+			})
+
+			It("passes the parameters of ANY as array", func() {
+				query, args, err := provider.Bind("select-any-users", "John", []int64{1, 2})
+				Expect(err).To(BeNil())
+				Expect(query).To(Equal("SELECT * FROM users WHERE name = $1 AND id = ANY($2)"))
+				Expect(args).To(HaveLen(2))
+				Expect(args[0]).To(Equal("John"))
+
+				value, err := args[1].(driver.Valuer).Value()
+				Expect(err).To(BeNil())
+				Expect(value).To(Equal("{1,2}"))
+			})
+		})
+
+		Context("when the slice is empty", func() {
+			It("returns an error", func() {
+				_, _, err := provider.Bind("select-users", []int64{}, "John")
+				Expect(err).To(MatchError("empty slice passed to 'in' query"))
+			})
+		})
+
+		Context("when the routine does not exist", func() {
+			It("returns an error", func() {
+				_, _, err := provider.Bind("unknown", []int64{1})
+				Expect(err).To(MatchError("query 'unknown' not found"))
+
+				_, _, err = provider.BindNamed("unknown", map[string]interface{}{})
+				Expect(err).To(MatchError("query 'unknown' not found"))
+			})
+		})
+	})
+
+	Describe("Routines", func() {
+		BeforeEach(func() {
+			buffer := bytes.NewBufferString("-- name: show-users")
+			fmt.Fprintln(buffer)
+			fmt.Fprintln(buffer, "SELECT * FROM users WHERE id = ?")
+
+			_, err := provider.ReadFrom(buffer)
+			Expect(err).To(Succeed())
+
+			provider.DriverName = "postgres"
+		})
+
+		It("returns all routines", func() {
+			routines := provider.Routines()
+			Expect(routines).To(HaveLen(1))
+			Expect(routines[0].Name).To(Equal("show-users"))
+			Expect(routines[0].Query).To(Equal("SELECT * FROM users WHERE id = ?"))
+		})
+
+		Describe("Routine", func() {
+			It("returns the routine", func() {
+				routine, err := provider.Routine("show-users")
+				Expect(err).To(BeNil())
+				Expect(routine.Query).To(Equal("SELECT * FROM users WHERE id = ?"))
+			})
+
+			Context("when the routine is not found", func() {
+				It("returns an error", func() {
+					routine, err := provider.Routine("show-roles")
+					Expect(err).To(MatchError("query 'show-roles' not found"))
+					Expect(routine).To(BeNil())
+				})
+			})
+		})
+
+		Context("when the annotations are invalid", func() {
+			It("returns an error", func() {
+				buffer := bytes.NewBufferString("-- name: show-roles")
+				fmt.Fprintln(buffer)
+				fmt.Fprintln(buffer, "-- timeout: never")
+				fmt.Fprintln(buffer, "SELECT * FROM roles")
+
+				_, err := provider.ReadFrom(buffer)
+				Expect(err).To(MatchError("routine 'show-roles' has invalid timeout 'never'"))
+			})
+		})
+	})
+
+	Describe("Watch", func() {
+		var (
+			dir    string
+			ctx    context.Context
+			cancel context.CancelFunc
+			errs   chan error
+		)
+
+		write := func(content string, age time.Duration) {
+			path := filepath.Join(dir, "routine.sql")
+			Expect(ioutil.WriteFile(path, []byte(content), 0600)).To(Succeed())
+
+			// the modification time should differ from the previous one
+			modtime := time.Now().Add(age)
+			Expect(os.Chtimes(path, modtime, modtime)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			var err error
+
+			dir, err = ioutil.TempDir("", "prana_watch")
+			Expect(err).To(BeNil())
+
+			write("-- name: show-users\nSELECT * FROM users", -time.Hour)
+			Expect(provider.ReadDir(parcello.Dir(dir))).To(Succeed())
+		})
+
+		JustBeforeEach(func() {
+			errs = make(chan error, 10)
+			ctx, cancel = context.WithCancel(context.Background())
+
+			provider.Watch(ctx, parcello.Dir(dir), 10*time.Millisecond, func(err error) {
+				errs <- err
+			})
+		})
+
+		AfterEach(func() {
+			cancel()
+		})
+
+		It("reloads the changed routines", func() {
+			write("-- name: show-users\nSELECT id FROM users\n\n-- name: show-roles\nSELECT * FROM roles", 0)
+
+			Eventually(func() (string, error) {
+				return provider.Query("show-roles")
+			}).Should(Equal("SELECT * FROM roles"))
+
+			query, err := provider.Query("show-users")
+			Expect(err).To(BeNil())
+			Expect(query).To(Equal("SELECT id FROM users"))
+		})
+
+		Context("when the routines are changed before the watch starts", func() {
+			BeforeEach(func() {
+				write("-- name: show-users\nSELECT id FROM users", 0)
+			})
+
+			It("reloads the changed routines", func() {
+				Eventually(func() (string, error) {
+					return provider.Query("show-users")
+				}).Should(Equal("SELECT id FROM users"))
+			})
+		})
+
+		Context("when the changed routines are invalid", func() {
+			It("keeps the last good version", func() {
+				write("-- name: show-users\n-- timeout: never\nSELECT id FROM users", 0)
+
+				Eventually(errs).Should(Receive(MatchError("routine 'show-users' has invalid timeout 'never'")))
+
+				query, err := provider.Query("show-users")
+				Expect(err).To(BeNil())
+				Expect(query).To(Equal("SELECT * FROM users"))
+			})
+		})
+	})
+
+	Describe("driver overrides", func() {
+		var dir string
+
+		write := func(name, content string) {
+			Expect(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			var err error
+
+			dir, err = ioutil.TempDir("", "prana_override")
+			Expect(err).To(BeNil())
+
+			write("b.sql", "-- name: show-users\nSELECT * FROM users\n\n-- name: show-roles\nSELECT * FROM roles")
+			write("a_postgres.sql", "-- name: show-users\nSELECT * FROM public.users")
+			write("c_postgres.sql", "-- name: show-roles\nSELECT * FROM public.roles")
+		})
+
+		It("uses the driver specific routines", func() {
+			provider.DriverName = "postgres"
+			Expect(provider.ReadDir(parcello.Dir(dir))).To(Succeed())
+
+			query, err := provider.Query("show-users")
+			Expect(err).To(BeNil())
+			Expect(query).To(Equal("SELECT * FROM public.users"))
+
+			query, err = provider.Query("show-roles")
+			Expect(err).To(BeNil())
+			Expect(query).To(Equal("SELECT * FROM public.roles"))
+
+			routine, err := provider.Routine("show-users")
+			Expect(err).To(BeNil())
+			Expect(routine.Driver).To(Equal("postgres"))
+			Expect(routine.IsGeneric()).To(BeFalse())
+		})
+
+		It("falls back to the generic routines", func() {
+			provider.DriverName = "sqlite3"
+			Expect(provider.ReadDir(parcello.Dir(dir))).To(Succeed())
+
+			query, err := provider.Query("show-users")
+			Expect(err).To(BeNil())
+			Expect(query).To(Equal("SELECT * FROM users"))
+
+			routine, err := provider.Routine("show-users")
+			Expect(err).To(BeNil())
+			Expect(routine.IsGeneric()).To(BeTrue())
+		})
+
+		It("counts only the registered routines", func() {
+			Expect(os.Remove(filepath.Join(dir, "b.sql"))).To(Succeed())
+
+			provider.DriverName = "postgres"
+			Expect(provider.ReadDir(parcello.Dir(dir))).To(Succeed())
+
+			n, err := provider.ReadFrom(bytes.NewBufferString("-- name: show-users\nSELECT id FROM users\n\n-- name: show-groups\nSELECT * FROM groups"))
+			Expect(err).To(BeNil())
+			Expect(n).To(BeEquivalentTo(1))
+
+			query, err := provider.Query("show-users")
+			Expect(err).To(BeNil())
+			Expect(query).To(Equal("SELECT * FROM public.users"))
+		})
+
+		Context("when the driver specific routine is duplicated", func() {
+			BeforeEach(func() {
+				write("d_postgres.sql", "-- name: show-users\nSELECT id FROM public.users")
+			})
+
+			It("returns an error", func() {
+				provider.DriverName = "postgres"
+				Expect(provider.ReadDir(parcello.Dir(dir))).To(MatchError("query 'show-users' already exists: defined at 'a_postgres.sql:1' and 'd_postgres.sql:1'"))
+			})
+		})
+
+		Context("when the overridden generic routine is duplicated", func() {
+			BeforeEach(func() {
+				write("e.sql", "-- name: show-users\nSELECT id FROM users")
+			})
+
+			It("returns an error", func() {
+				provider.DriverName = "postgres"
+				Expect(provider.ReadDir(parcello.Dir(dir))).To(MatchError("query 'show-users' already exists: defined at 'b.sql:1' and 'e.sql:1'"))
+			})
+		})
+	})
 })
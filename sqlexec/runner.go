@@ -1,6 +1,10 @@
 package sqlexec
 
-import "github.com/jmoiron/sqlx"
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
 
 // Runner runs a SQL statement for given command name and parameters.
 type Runner struct {
@@ -10,19 +14,103 @@ type Runner struct {
 	DB *sqlx.DB
 }
 
-// Run runs a given command with provided parameters.
+// Run runs a given command with provided parameters. The slice parameters
+// are expanded as in Provider.Bind.
 func (r *Runner) Run(name string, args ...Param) (*Rows, error) {
-	provider := &Provider{}
+	query, args, err := r.bind(name, args)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := provider.ReadDir(r.FileSystem); err != nil {
+	return r.run(query, args)
+}
+
+// RunNamed runs a given command that has named parameters. The argument can
+// be a struct or map[string]interface{}. The slice values are expanded and
+// the templates are rendered for the argument.
+func (r *Runner) RunNamed(name string, arg Param) (*Rows, error) {
+	query, args, err := r.bindNamed(name, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.run(query, args)
+}
+
+// Exec executes a given command that does not return rows.
+func (r *Runner) Exec(name string, args ...Param) (sql.Result, error) {
+	query, args, err := r.bind(name, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.DB.Exec(query, args...)
+}
+
+// ExecNamed executes a given command that has named parameters and does not
+// return rows. The argument can be a struct or map[string]interface{}. The
+// slice values are expanded and the templates are rendered for the argument.
+func (r *Runner) ExecNamed(name string, arg Param) (sql.Result, error) {
+	query, args, err := r.bindNamed(name, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.DB.Exec(query, args...)
+}
+
+// Select runs a given routine and scans the rows into a slice.
+func (r *Runner) Select(dest interface{}, name string, args ...Param) error {
+	query, args, err := r.bind(name, args)
+	if err != nil {
+		return err
+	}
+
+	return r.DB.Select(dest, query, args...)
+}
+
+// Get runs a given routine and scans the first row into the destination. It
+// returns NotFoundError if the routine does not return any rows.
+func (r *Runner) Get(dest interface{}, name string, args ...Param) error {
+	query, args, err := r.bind(name, args)
+	if err != nil {
+		return err
+	}
+
+	if err = r.DB.Get(dest, query, args...); err == sql.ErrNoRows {
+		return &NotFoundError{Routine: name}
+	}
+
+	return err
+}
+
+// Cursor runs a given routine and returns a cursor that iterates over its
+// rows.
+func (r *Runner) Cursor(name string, args ...Param) (*Cursor, error) {
+	query, args, err := r.bind(name, args)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.DB.Queryx(query, args...)
+	if err != nil {
 		return nil, err
 	}
 
-	query, err := provider.Query(name)
+	return &Cursor{rows: rows}, nil
+}
+
+// Routine returns the routine for given name.
+func (r *Runner) Routine(name string) (*Routine, error) {
+	provider, err := r.provider()
 	if err != nil {
 		return nil, err
 	}
 
+	return provider.Routine(name)
+}
+
+func (r *Runner) run(query string, args []Param) (*Rows, error) {
 	stmt, err := r.DB.Preparex(query)
 	if err != nil {
 		return nil, err
@@ -36,3 +124,34 @@ func (r *Runner) Run(name string, args ...Param) (*Rows, error) {
 
 	return stmt.Queryx(args...)
 }
+
+func (r *Runner) bind(name string, args []Param) (string, []Param, error) {
+	provider, err := r.provider()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return provider.Bind(name, args...)
+}
+
+func (r *Runner) bindNamed(name string, arg Param) (string, []Param, error) {
+	provider, err := r.provider()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return provider.BindNamed(name, arg)
+}
+
+func (r *Runner) provider() (*Provider, error) {
+	provider := &Provider{
+		DriverName: r.DB.DriverName(),
+		Mapper:     r.DB.Mapper,
+	}
+
+	if err := provider.ReadDir(r.FileSystem); err != nil {
+		return nil, err
+	}
+
+	return provider, nil
+}
@@ -6,8 +6,10 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/phogolabs/parcello"
@@ -96,6 +98,285 @@ var _ = Describe("Runner", func() {
 		})
 	})
 
+	Context("when the command has a driver specific variant", func() {
+		JustBeforeEach(func() {
+			command := &bytes.Buffer{}
+			fmt.Fprintln(command, "-- name: system-tables")
+			fmt.Fprintln(command, "SELECT name FROM sqlite_master")
+
+			path := filepath.Join(dir, "commands_sqlite3.sql")
+			Expect(ioutil.WriteFile(path, command.Bytes(), 0700)).To(Succeed())
+		})
+
+		It("runs the driver specific variant", func() {
+			rows, err := runner.Run("system-tables")
+			Expect(err).To(Succeed())
+
+			columns, err := rows.Columns()
+			Expect(err).To(Succeed())
+			Expect(columns).To(Equal([]string{"name"}))
+		})
+	})
+
+	Describe("RunNamed", func() {
+		JustBeforeEach(func() {
+			command := &bytes.Buffer{}
+			fmt.Fprintln(command, "-- name: system-tables")
+			fmt.Fprintln(command, "SELECT :param AS Param FROM sqlite_master")
+
+			path := filepath.Join(dir, "commands.sql")
+			Expect(ioutil.WriteFile(path, command.Bytes(), 0700)).To(Succeed())
+		})
+
+		It("runs the command with a map successfully", func() {
+			rows, err := runner.RunNamed("system-tables", map[string]interface{}{
+				"param": "hello",
+			})
+			Expect(err).To(Succeed())
+
+			columns, err := rows.Columns()
+			Expect(err).To(Succeed())
+			Expect(columns).To(ContainElement("Param"))
+		})
+
+		It("runs the command with a struct successfully", func() {
+			arg := struct {
+				Param string `db:"param"`
+			}{
+				Param: "hello",
+			}
+
+			rows, err := runner.RunNamed("system-tables", arg)
+			Expect(err).To(Succeed())
+
+			columns, err := rows.Columns()
+			Expect(err).To(Succeed())
+			Expect(columns).To(ContainElement("Param"))
+		})
+
+		It("runs the command with the mapper of the database", func() {
+			runner.DB.Mapper = reflectx.NewMapperFunc("json", strings.ToLower)
+
+			arg := struct {
+				Value string `json:"param"`
+			}{
+				Value: "hello",
+			}
+
+			rows, err := runner.RunNamed("system-tables", arg)
+			Expect(err).To(Succeed())
+			Expect(rows.Close()).To(Succeed())
+		})
+
+		Context("when the parameter is missing", func() {
+			It("returns an error", func() {
+				_, err := runner.RunNamed("system-tables", map[string]interface{}{})
+				Expect(err).To(MatchError("could not find name param in map[string]interface {}{}"))
+			})
+		})
+
+		Context("when the command does not exist", func() {
+			It("returns an error", func() {
+				_, err := runner.RunNamed("unknown", map[string]interface{}{})
+				Expect(err).To(MatchError("query 'unknown' not found"))
+			})
+		})
+	})
+
+	Context("when the parameter is a slice", func() {
+		JustBeforeEach(func() {
+			command := &bytes.Buffer{}
+			fmt.Fprintln(command, "-- name: system-tables")
+			fmt.Fprintln(command, "SELECT name FROM (SELECT 'a' AS name UNION SELECT 'b' UNION SELECT 'c') WHERE name IN (?) ORDER BY name")
+			fmt.Fprintln(command)
+			fmt.Fprintln(command, "-- name: named-system-tables")
+			fmt.Fprintln(command, "SELECT name FROM (SELECT 'a' AS name UNION SELECT 'b' UNION SELECT 'c') WHERE name IN (:names) ORDER BY name")
+
+			path := filepath.Join(dir, "commands.sql")
+			Expect(ioutil.WriteFile(path, command.Bytes(), 0700)).To(Succeed())
+		})
+
+		It("expands the parameter", func() {
+			names := []string{}
+			Expect(runner.Select(&names, "system-tables", []string{"a", "c"})).To(Succeed())
+			Expect(names).To(Equal([]string{"a", "c"}))
+		})
+
+		It("expands the named parameter", func() {
+			rows, err := runner.RunNamed("named-system-tables", map[string]interface{}{
+				"names": []string{"b", "c"},
+			})
+			Expect(err).To(Succeed())
+
+			names := []string{}
+
+			for rows.Next() {
+				var name string
+				Expect(rows.Scan(&name)).To(Succeed())
+				names = append(names, name)
+			}
+
+			Expect(rows.Close()).To(Succeed())
+			Expect(names).To(Equal([]string{"b", "c"}))
+		})
+	})
+
+	Describe("Exec", func() {
+		JustBeforeEach(func() {
+			command := &bytes.Buffer{}
+			fmt.Fprintln(command, "-- name: create-users")
+			fmt.Fprintln(command, "CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)")
+			fmt.Fprintln(command)
+			fmt.Fprintln(command, "-- name: insert-user")
+			fmt.Fprintln(command, "INSERT INTO users (name) VALUES (?)")
+			fmt.Fprintln(command)
+			fmt.Fprintln(command, "-- name: insert-named-user")
+			fmt.Fprintln(command, "INSERT INTO users (name) VALUES (:name)")
+
+			path := filepath.Join(dir, "commands.sql")
+			Expect(ioutil.WriteFile(path, command.Bytes(), 0700)).To(Succeed())
+
+			_, err := runner.Exec("create-users")
+			Expect(err).To(Succeed())
+		})
+
+		It("executes the command successfully", func() {
+			result, err := runner.Exec("insert-user", "John")
+			Expect(err).To(Succeed())
+
+			affected, err := result.RowsAffected()
+			Expect(err).To(Succeed())
+			Expect(affected).To(BeEquivalentTo(1))
+
+			id, err := result.LastInsertId()
+			Expect(err).To(Succeed())
+			Expect(id).To(BeEquivalentTo(1))
+		})
+
+		It("executes the named command successfully", func() {
+			result, err := runner.ExecNamed("insert-named-user", map[string]interface{}{
+				"name": "John",
+			})
+			Expect(err).To(Succeed())
+
+			affected, err := result.RowsAffected()
+			Expect(err).To(Succeed())
+			Expect(affected).To(BeEquivalentTo(1))
+		})
+
+		It("returns the routine", func() {
+			routine, err := runner.Routine("insert-user")
+			Expect(err).To(Succeed())
+			Expect(routine.IsQuery()).To(BeFalse())
+		})
+
+		Context("when the command does not exist", func() {
+			It("returns an error", func() {
+				_, err := runner.Exec("unknown")
+				Expect(err).To(MatchError("query 'unknown' not found"))
+
+				_, err = runner.ExecNamed("unknown", map[string]interface{}{})
+				Expect(err).To(MatchError("query 'unknown' not found"))
+
+				_, err = runner.Routine("unknown")
+				Expect(err).To(MatchError("query 'unknown' not found"))
+			})
+		})
+	})
+
+	Describe("Select and Get", func() {
+		type user struct {
+			ID   int    `db:"id"`
+			Name string `db:"name"`
+		}
+
+		JustBeforeEach(func() {
+			command := &bytes.Buffer{}
+			fmt.Fprintln(command, "-- name: select-users")
+			fmt.Fprintln(command, "SELECT * FROM users ORDER BY id")
+			fmt.Fprintln(command)
+			fmt.Fprintln(command, "-- name: select-user")
+			fmt.Fprintln(command, "SELECT * FROM users WHERE id = ?")
+			fmt.Fprintln(command)
+			fmt.Fprintln(command, "-- name: count-users")
+			fmt.Fprintln(command, "SELECT count(*) FROM users")
+
+			path := filepath.Join(dir, "commands.sql")
+			Expect(ioutil.WriteFile(path, command.Bytes(), 0700)).To(Succeed())
+
+			_, err := runner.DB.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+			Expect(err).To(Succeed())
+
+			_, err = runner.DB.Exec("INSERT INTO users VALUES (1, 'John'), (2, 'Jane')")
+			Expect(err).To(Succeed())
+		})
+
+		It("selects the rows into a slice", func() {
+			users := []user{}
+			Expect(runner.Select(&users, "select-users")).To(Succeed())
+			Expect(users).To(Equal([]user{{ID: 1, Name: "John"}, {ID: 2, Name: "Jane"}}))
+		})
+
+		It("gets a single row", func() {
+			record := user{}
+			Expect(runner.Get(&record, "select-user", 2)).To(Succeed())
+			Expect(record).To(Equal(user{ID: 2, Name: "Jane"}))
+		})
+
+		It("gets a scalar value", func() {
+			count := 0
+			Expect(runner.Get(&count, "count-users")).To(Succeed())
+			Expect(count).To(Equal(2))
+		})
+
+		It("iterates over the rows with a cursor", func() {
+			cursor, err := runner.Cursor("select-users")
+			Expect(err).To(Succeed())
+
+			names := []string{}
+
+			for cursor.Next() {
+				record := user{}
+				Expect(cursor.Scan(&record)).To(Succeed())
+				names = append(names, record.Name)
+			}
+
+			Expect(cursor.Err()).To(Succeed())
+			Expect(cursor.Close()).To(Succeed())
+			Expect(names).To(Equal([]string{"John", "Jane"}))
+		})
+
+		It("iterates over scalar values with a cursor", func() {
+			cursor, err := runner.Cursor("count-users")
+			Expect(err).To(Succeed())
+			Expect(cursor.Next()).To(BeTrue())
+
+			count := 0
+			Expect(cursor.Scan(&count)).To(Succeed())
+			Expect(count).To(Equal(2))
+			Expect(cursor.Close()).To(Succeed())
+		})
+
+		Context("when the row is not found", func() {
+			It("returns a not found error", func() {
+				record := user{}
+				err := runner.Get(&record, "select-user", 3)
+				Expect(err).To(MatchError("routine 'select-user' returned no rows"))
+				Expect(sqlexec.IsNotFound(err)).To(BeTrue())
+			})
+		})
+
+		Context("when the routine does not exist", func() {
+			It("returns an error", func() {
+				Expect(runner.Select(&[]user{}, "unknown")).To(MatchError("query 'unknown' not found"))
+				Expect(runner.Get(&user{}, "unknown")).To(MatchError("query 'unknown' not found"))
+
+				_, err := runner.Cursor("unknown")
+				Expect(err).To(MatchError("query 'unknown' not found"))
+			})
+		})
+	})
+
 	Context("when the command does not exist", func() {
 		JustBeforeEach(func() {
 			path := filepath.Join(dir, "commands.sql")
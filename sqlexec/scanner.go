@@ -2,33 +2,112 @@ package sqlexec
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"regexp"
 	"strings"
+	"time"
 )
 
-var rgxp = regexp.MustCompile("^\\s*--\\s*name:\\s*(\\S+)")
+var (
+	rgxp       = regexp.MustCompile("^\\s*--\\s*name:\\s*(\\S+)")
+	annotation = regexp.MustCompile("^\\s*--\\s*(doc|param|returns|timeout|tags):\\s*(.*)$")
+)
 
 // Scanner loads a SQL statements for given SQL Script
 type Scanner struct{}
 
-// Scan scans a reader for SQL commands that have name tag
+// Scan scans a reader for SQL commands that have name tag. The commands of
+// the blocks with the same name are joined.
 func (s *Scanner) Scan(reader io.Reader) map[string]string {
 	queries := make(map[string]string)
+	routines, _ := s.scan(reader)
+
+	for _, routine := range routines {
+		if routine.Query == "" {
+			continue
+		}
+
+		if current, ok := queries[routine.Name]; ok {
+			queries[routine.Name] = current + "\n" + routine.Query
+			continue
+		}
+
+		queries[routine.Name] = routine.Query
+	}
+
+	return queries
+}
+
+// ScanRoutines scans a reader for SQL routines that have name tag. The
+// annotations that follow the name tag are parsed into the routine. The
+// operation fails if an annotation is invalid.
+func (s *Scanner) ScanRoutines(reader io.Reader) ([]*Routine, error) {
+	routines, errs := s.scan(reader)
+	if len(errs) > 0 {
+		return nil, errs[0].err
+	}
+
+	result := []*Routine{}
+
+	for _, routine := range routines {
+		if routine.Query != "" {
+			result = append(result, routine)
+		}
+	}
+
+	return result, nil
+}
+
+// scanError is an error that occurred at given line
+type scanError struct {
+	line int
+	err  error
+}
+
+// scan returns all routines including the empty ones and the errors of
+// their annotations
+func (s *Scanner) scan(reader io.Reader) ([]*Routine, []scanError) {
+	var (
+		routines = []*Routine{}
+		errs     = []scanError{}
+		routine  *Routine
+		header   bool
+		number   int
+	)
+
 	scanner := bufio.NewScanner(reader)
-	name := ""
 
 	for scanner.Scan() {
 		line := scanner.Text()
+		number++
 
 		if tag := s.tag(line); tag != "" {
-			name = tag
-		} else if name != "" {
-			s.add(name, queries, line)
+			routine = &Routine{Name: tag, Line: number}
+			routines = append(routines, routine)
+			header = true
+			continue
+		}
+
+		if routine == nil {
+			continue
+		}
+
+		if header {
+			if matches := annotation.FindStringSubmatch(line); matches != nil {
+				if err := s.annotate(routine, matches[1], strings.TrimSpace(matches[2])); err != nil {
+					errs = append(errs, scanError{line: number, err: err})
+				}
+				continue
+			}
+		}
+
+		if s.add(routine, line) {
+			header = false
 		}
 	}
 
-	return queries
+	return routines, errs
 }
 
 func (s *Scanner) tag(line string) string {
@@ -39,18 +118,61 @@ func (s *Scanner) tag(line string) string {
 	return matches[1]
 }
 
-func (s *Scanner) add(name string, queries map[string]string, line string) {
-	current := queries[name]
+func (s *Scanner) annotate(routine *Routine, key, value string) error {
+	switch key {
+	case "doc":
+		if routine.Doc != "" {
+			routine.Doc = routine.Doc + "\n"
+		}
+		routine.Doc = routine.Doc + value
+	case "param":
+		fields := strings.Fields(value)
+		if len(fields) != 2 {
+			return fmt.Errorf("routine '%s' has invalid param '%s'", routine.Name, value)
+		}
+
+		routine.Params = append(routine.Params, RoutineParam{
+			Name: fields[0],
+			Type: fields[1],
+		})
+	case "returns":
+		switch value {
+		case ReturnsOne, ReturnsMany, ReturnsExec, ReturnsAffected:
+			routine.Returns = value
+		default:
+			return fmt.Errorf("routine '%s' has invalid returns '%s'", routine.Name, value)
+		}
+	case "timeout":
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("routine '%s' has invalid timeout '%s'", routine.Name, value)
+		}
+
+		routine.Timeout = timeout
+	case "tags":
+		for _, tag := range strings.FieldsFunc(value, separator) {
+			routine.Tags = append(routine.Tags, tag)
+		}
+	}
+
+	return nil
+}
+
+func (s *Scanner) add(routine *Routine, line string) bool {
 	line = strings.Trim(line, " \t")
 
 	if len(line) == 0 {
-		return
+		return false
 	}
 
-	if len(current) > 0 {
-		current = current + "\n"
+	if len(routine.Query) > 0 {
+		routine.Query = routine.Query + "\n"
 	}
 
-	current = current + line
-	queries[name] = current
+	routine.Query = routine.Query + line
+	return true
+}
+
+func separator(char rune) bool {
+	return char == ',' || char == ' ' || char == '\t'
 }
@@ -3,6 +3,7 @@ package sqlexec_test
 import (
 	"bytes"
 	"fmt"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -54,4 +55,98 @@ var _ = Describe("Scanner", func() {
 			Expect(queries).To(HaveKeyWithValue("save-user", "SELECT * FROM users;"))
 		})
 	})
+
+	Context("when the tag is used twice", func() {
+		It("joins the tagged statements", func() {
+			buffer := &bytes.Buffer{}
+			fmt.Fprintln(buffer, "-- name: up")
+			fmt.Fprintln(buffer, "CREATE TABLE users (id INT);")
+			fmt.Fprintln(buffer, "-- name: down")
+			fmt.Fprintln(buffer, "DROP TABLE users;")
+			fmt.Fprintln(buffer, "-- name: up")
+			fmt.Fprintln(buffer, "CREATE TABLE roles (id INT);")
+
+			queries := scanner.Scan(buffer)
+
+			Expect(queries).To(HaveLen(2))
+			Expect(queries).To(HaveKeyWithValue("up", "CREATE TABLE users (id INT);\nCREATE TABLE roles (id INT);"))
+		})
+	})
+
+	Describe("ScanRoutines", func() {
+		It("returns the annotated routines successfully", func() {
+			buffer := &bytes.Buffer{}
+			fmt.Fprintln(buffer, "-- name: select-user")
+			fmt.Fprintln(buffer, "-- doc: returns the user")
+			fmt.Fprintln(buffer, "-- doc: for given id")
+			fmt.Fprintln(buffer, "-- param: id int64")
+			fmt.Fprintln(buffer, "-- returns: one")
+			fmt.Fprintln(buffer, "-- timeout: 5s")
+			fmt.Fprintln(buffer, "-- tags: admin, report")
+			fmt.Fprintln(buffer, "SELECT * FROM users")
+			fmt.Fprintln(buffer, "-- doc: not an annotation")
+			fmt.Fprintln(buffer, "WHERE id = ?;")
+
+			routines, err := scanner.ScanRoutines(buffer)
+			Expect(err).To(BeNil())
+			Expect(routines).To(HaveLen(1))
+
+			routine := routines[0]
+			Expect(routine.Name).To(Equal("select-user"))
+			Expect(routine.Doc).To(Equal("returns the user\nfor given id"))
+			Expect(routine.Query).To(Equal("SELECT * FROM users\n-- doc: not an annotation\nWHERE id = ?;"))
+			Expect(routine.Params).To(Equal([]sqlexec.RoutineParam{{Name: "id", Type: "int64"}}))
+			Expect(routine.Returns).To(Equal(sqlexec.ReturnsOne))
+			Expect(routine.Timeout).To(Equal(5 * time.Second))
+			Expect(routine.Tags).To(Equal([]string{"admin", "report"}))
+			Expect(routine.HasTag("ADMIN")).To(BeTrue())
+			Expect(routine.HasTag("user")).To(BeFalse())
+		})
+
+		It("omits the annotations from the scanned statements", func() {
+			buffer := &bytes.Buffer{}
+			fmt.Fprintln(buffer, "-- name: save-user")
+			fmt.Fprintln(buffer, "-- returns: exec")
+			fmt.Fprintln(buffer, "INSERT INTO users VALUES (?);")
+
+			queries := scanner.Scan(buffer)
+			Expect(queries).To(HaveKeyWithValue("save-user", "INSERT INTO users VALUES (?);"))
+		})
+
+		Context("when the param annotation is invalid", func() {
+			It("returns an error", func() {
+				buffer := &bytes.Buffer{}
+				fmt.Fprintln(buffer, "-- name: select-user")
+				fmt.Fprintln(buffer, "-- param: id")
+				fmt.Fprintln(buffer, "SELECT * FROM users WHERE id = ?;")
+
+				_, err := scanner.ScanRoutines(buffer)
+				Expect(err).To(MatchError("routine 'select-user' has invalid param 'id'"))
+			})
+		})
+
+		Context("when the returns annotation is invalid", func() {
+			It("returns an error", func() {
+				buffer := &bytes.Buffer{}
+				fmt.Fprintln(buffer, "-- name: select-user")
+				fmt.Fprintln(buffer, "-- returns: all")
+				fmt.Fprintln(buffer, "SELECT * FROM users;")
+
+				_, err := scanner.ScanRoutines(buffer)
+				Expect(err).To(MatchError("routine 'select-user' has invalid returns 'all'"))
+			})
+		})
+
+		Context("when the timeout annotation is invalid", func() {
+			It("returns an error", func() {
+				buffer := &bytes.Buffer{}
+				fmt.Fprintln(buffer, "-- name: select-user")
+				fmt.Fprintln(buffer, "-- timeout: -1s")
+				fmt.Fprintln(buffer, "SELECT * FROM users;")
+
+				_, err := scanner.ScanRoutines(buffer)
+				Expect(err).To(MatchError("routine 'select-user' has invalid timeout '-1s'"))
+			})
+		})
+	})
 })
@@ -0,0 +1,208 @@
+package sqlexec
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+var (
+	whereJoin  = regexp.MustCompile(`(?i)\bWHERE\s+(?:AND|OR)\s+`)
+	whereEmpty = regexp.MustCompile(`(?i)\s+WHERE\s*(\)|;|\z|(?:GROUP|ORDER|LIMIT|OFFSET|HAVING|UNION)\b)`)
+)
+
+// actions are the template functions whose output is safe, because the
+// values are never written into the query. They are replaced by the functions
+// of the renderer before the execution.
+var actions = template.FuncMap{
+	"bind":    func(Param) string { return "" },
+	"in":      func(Param) string { return "" },
+	"include": func(string) (string, error) { return "", nil },
+}
+
+// IsTemplate returns true if the routine contains template actions.
+func (r *Routine) IsTemplate() bool {
+	return strings.Contains(r.Query, "{{")
+}
+
+// template parses the query of the routine. It fails if an action writes a
+// value into the query instead of binding it.
+func (r *Routine) template() (*template.Template, error) {
+	tmpl, err := template.New(r.Name).
+		Funcs(actions).
+		Option("missingkey=zero").
+		Parse(r.Query)
+
+	if err != nil {
+		return nil, fmt.Errorf("has invalid template: %v", err)
+	}
+
+	if len(tmpl.Templates()) > 1 {
+		return nil, fmt.Errorf("has invalid template: define action is not supported, use include instead")
+	}
+
+	if err := safe(tmpl.Tree.Root); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// renderer renders the routine templates and collects their arguments
+type renderer struct {
+	provider *Provider
+	data     Param
+	args     []Param
+	stack    []string
+}
+
+func (r *renderer) funcs() template.FuncMap {
+	return template.FuncMap{
+		"bind":    r.bind,
+		"in":      r.in,
+		"include": r.include,
+	}
+}
+
+// render renders a routine. The routines without template actions are
+// returned as they are.
+func (r *renderer) render(routine *Routine) (string, error) {
+	for _, name := range r.stack {
+		if name == routine.Name {
+			return "", fmt.Errorf("routine '%s' includes itself", routine.Name)
+		}
+	}
+
+	if !routine.IsTemplate() {
+		return routine.Query, nil
+	}
+
+	r.stack = append(r.stack, routine.Name)
+	defer func() { r.stack = r.stack[:len(r.stack)-1] }()
+
+	tmpl, err := routine.template()
+	if err != nil {
+		return "", fmt.Errorf("routine '%s' %v", routine.Name, err)
+	}
+
+	buffer := &bytes.Buffer{}
+
+	if err := tmpl.Funcs(r.funcs()).Execute(buffer, r.data); err != nil {
+		return "", err
+	}
+
+	return buffer.String(), nil
+}
+
+// bind binds a value as parameter
+func (r *renderer) bind(value Param) string {
+	r.args = append(r.args, value)
+	return "?"
+}
+
+// in binds each item of a slice as parameter of IN list. The empty slice
+// results in a list that does not match any value.
+func (r *renderer) in(value Param) string {
+	items := reflect.ValueOf(value)
+
+	switch items.Kind() {
+	case reflect.Slice, reflect.Array:
+		if _, ok := value.([]byte); ok {
+			break
+		}
+
+		if items.Len() == 0 {
+			return "(NULL)"
+		}
+
+		placeholders := make([]string, items.Len())
+
+		for index := range placeholders {
+			placeholders[index] = r.bind(items.Index(index).Interface())
+		}
+
+		return "(" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	return "(" + r.bind(value) + ")"
+}
+
+// include renders another routine as fragment of the current one
+func (r *renderer) include(name string) (string, error) {
+	routine, ok := r.provider.repository[name]
+	if !ok {
+		return "", nonExistQueryErr(name)
+	}
+
+	return r.render(routine)
+}
+
+// safe returns an error if an action of the template writes a value into the
+// query without binding it
+func safe(node parse.Node) error {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return nil
+		}
+
+		for _, item := range node.Nodes {
+			if err := safe(item); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		// the variable declarations do not produce output
+		if len(node.Pipe.Decl) > 0 {
+			return nil
+		}
+
+		cmds := node.Pipe.Cmds
+		last := cmds[len(cmds)-1]
+
+		if ident, ok := last.Args[0].(*parse.IdentifierNode); ok && actions[ident.Ident] != nil {
+			if ident.Ident != "include" {
+				return nil
+			}
+
+			// the fragments are included by constant names only
+			if len(last.Args) == 2 {
+				if _, ok := last.Args[1].(*parse.StringNode); ok {
+					return nil
+				}
+			}
+		}
+
+		return fmt.Errorf("has unsafe action %s: the values should be passed to bind or in", node)
+	case *parse.IfNode:
+		return safeBranch(&node.BranchNode)
+	case *parse.RangeNode:
+		return safeBranch(&node.BranchNode)
+	case *parse.WithNode:
+		return safeBranch(&node.BranchNode)
+	case *parse.TemplateNode:
+		return fmt.Errorf("has unsafe action %s: use include instead", node)
+	}
+
+	return nil
+}
+
+func safeBranch(node *parse.BranchNode) error {
+	if err := safe(node.List); err != nil {
+		return err
+	}
+
+	return safe(node.ElseList)
+}
+
+// clean removes the dangling AND and OR operators after WHERE and the WHERE
+// clauses whose conditions are all omitted
+func clean(query string) string {
+	query = whereJoin.ReplaceAllString(query, "WHERE ")
+	query = whereEmpty.ReplaceAllString(query, " $1")
+	return strings.TrimRight(query, " ")
+}
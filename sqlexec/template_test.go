@@ -0,0 +1,279 @@
+package sqlexec_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/prana/sqlexec"
+)
+
+var _ = Describe("Template", func() {
+	var (
+		provider *sqlexec.Provider
+		script   *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		script = &bytes.Buffer{}
+		fmt.Fprintln(script, "-- name: user-columns")
+		fmt.Fprintln(script, "id, name")
+		fmt.Fprintln(script)
+		fmt.Fprintln(script, "-- name: search-users")
+		fmt.Fprintln(script, `SELECT {{ include "user-columns" }} FROM users`)
+		fmt.Fprintln(script, "WHERE")
+		fmt.Fprintln(script, "{{ if .name }}AND name = {{ bind .name }}{{ end }}")
+		fmt.Fprintln(script, "{{ if .ids }}AND id IN {{ in .ids }}{{ end }}")
+		fmt.Fprintln(script, "ORDER BY id")
+
+		provider = &sqlexec.Provider{DriverName: "sqlite3"}
+	})
+
+	JustBeforeEach(func() {
+		_, err := provider.ReadFrom(script)
+		Expect(err).To(BeNil())
+	})
+
+	It("renders the conditional clauses", func() {
+		query, args, err := provider.Render("search-users", map[string]interface{}{
+			"name": "John",
+			"ids":  []int64{1, 2},
+		})
+
+		Expect(err).To(BeNil())
+		Expect(query).To(Equal("SELECT id, name FROM users\nWHERE name = ?\nAND id IN (?, ?)\nORDER BY id"))
+		Expect(args).To(Equal([]sqlexec.Param{"John", int64(1), int64(2)}))
+	})
+
+	It("renders the struct arguments", func() {
+		type params struct {
+			Name string
+		}
+
+		Expect(provider.ReadFrom(bytes.NewBufferString("-- name: user\nSELECT * FROM users WHERE name = {{ bind .Name }}"))).To(BeNumerically("==", 1))
+
+		query, args, err := provider.Render("user", &params{Name: "John"})
+		Expect(err).To(BeNil())
+		Expect(query).To(Equal("SELECT * FROM users WHERE name = ?"))
+		Expect(args).To(Equal([]sqlexec.Param{"John"}))
+	})
+
+	It("removes the empty where clause", func() {
+		query, args, err := provider.Render("search-users", map[string]interface{}{})
+		Expect(err).To(BeNil())
+		Expect(query).To(Equal("SELECT id, name FROM users ORDER BY id"))
+		Expect(args).To(BeEmpty())
+	})
+
+	It("renders the empty list that does not match any value", func() {
+		fmt.Fprintln(script)
+		fmt.Fprintln(script, "-- name: select-users")
+		fmt.Fprintln(script, "SELECT * FROM users WHERE id IN {{ in .ids }}")
+
+		_, err := provider.ReadFrom(script)
+		Expect(err).To(BeNil())
+
+		query, args, err := provider.Render("select-users", map[string]interface{}{"ids": []int{}})
+		Expect(err).To(BeNil())
+		Expect(query).To(Equal("SELECT * FROM users WHERE id IN (NULL)"))
+		Expect(args).To(BeEmpty())
+	})
+
+	It("returns true for the templates", func() {
+		Expect(provider.IsTemplate("search-users")).To(BeTrue())
+		Expect(provider.IsTemplate("user-columns")).To(BeFalse())
+		Expect(provider.IsTemplate("unknown")).To(BeFalse())
+	})
+
+	Context("when the driver is postgres", func() {
+		BeforeEach(func() {
+			provider.DriverName = "postgres"
+		})
+
+		It("rebinds the parameters", func() {
+			query, _, err := provider.Render("search-users", map[string]interface{}{
+				"name": "John",
+				"ids":  []int64{1, 2},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(query).To(ContainSubstring("WHERE name = $1\nAND id IN ($2, $3)"))
+		})
+	})
+
+	Context("when the template is queried without parameters", func() {
+		It("returns an error", func() {
+			_, err := provider.Query("search-users")
+			Expect(err).To(MatchError("routine 'search-users' is a template that should be rendered with named parameters"))
+		})
+	})
+
+	Context("when the template writes a value", func() {
+		BeforeEach(func() {
+			script = bytes.NewBufferString("-- name: select-user\nSELECT * FROM users WHERE name = '{{ .name }}'")
+		})
+
+		It("returns an error", func() {
+			_, _, err := provider.Render("select-user", map[string]interface{}{"name": "' OR 1 = 1 --"})
+			Expect(err).To(MatchError("routine 'select-user' has unsafe action {{.name}}: the values should be passed to bind or in"))
+		})
+	})
+
+	Context("when the template pipes a bound value", func() {
+		BeforeEach(func() {
+			script = bytes.NewBufferString("-- name: select-user\nSELECT * FROM users WHERE name = {{ bind .name | printf \"%s\" }}")
+		})
+
+		It("returns an error", func() {
+			_, _, err := provider.Render("select-user", map[string]interface{}{"name": "John"})
+			Expect(err.Error()).To(HavePrefix("routine 'select-user' has unsafe action"))
+		})
+	})
+
+	Context("when the fragment name is not constant", func() {
+		BeforeEach(func() {
+			script = bytes.NewBufferString("-- name: select-user\nSELECT {{ include .columns }} FROM users")
+		})
+
+		It("returns an error", func() {
+			_, _, err := provider.Render("select-user", map[string]interface{}{"columns": "password"})
+			Expect(err).To(MatchError(`routine 'select-user' has unsafe action {{include .columns}}: the values should be passed to bind or in`))
+		})
+	})
+
+	Context("when the template defines templates", func() {
+		BeforeEach(func() {
+			script = bytes.NewBufferString(`-- name: select-user` + "\n" + `SELECT * FROM users {{ define "x" }}{{ .name }}{{ end }}{{ template "x" . }}`)
+		})
+
+		It("returns an error", func() {
+			_, _, err := provider.Render("select-user", map[string]interface{}{})
+			Expect(err).To(MatchError("routine 'select-user' has invalid template: define action is not supported, use include instead"))
+		})
+	})
+
+	Context("when the template is not valid", func() {
+		BeforeEach(func() {
+			script = bytes.NewBufferString("-- name: select-user\nSELECT * FROM users {{ if .name }}")
+		})
+
+		It("returns an error", func() {
+			_, _, err := provider.Render("select-user", map[string]interface{}{})
+			Expect(err.Error()).To(HavePrefix("routine 'select-user' has invalid template"))
+		})
+	})
+
+	Context("when the fragment includes itself", func() {
+		BeforeEach(func() {
+			script = &bytes.Buffer{}
+			fmt.Fprintln(script, "-- name: columns")
+			fmt.Fprintln(script, `id{{ include "other-columns" }}`)
+			fmt.Fprintln(script)
+			fmt.Fprintln(script, "-- name: other-columns")
+			fmt.Fprintln(script, `, name{{ include "columns" }}`)
+		})
+
+		It("returns an error", func() {
+			_, _, err := provider.Render("columns", nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("routine 'columns' includes itself"))
+		})
+	})
+
+	Context("when the fragment does not exist", func() {
+		BeforeEach(func() {
+			script = bytes.NewBufferString(`-- name: select-user` + "\n" + `SELECT {{ include "unknown" }} FROM users`)
+		})
+
+		It("returns an error", func() {
+			_, _, err := provider.Render("select-user", nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("query 'unknown' not found"))
+		})
+	})
+
+	Context("when the routine does not exist", func() {
+		It("returns an error", func() {
+			_, _, err := provider.Render("unknown", nil)
+			Expect(err).To(MatchError("query 'unknown' not found"))
+		})
+	})
+
+	Describe("Runner", func() {
+		var (
+			runner *sqlexec.Runner
+			dir    string
+		)
+
+		BeforeEach(func() {
+			var err error
+
+			dir, err = ioutil.TempDir("", "prana_template")
+			Expect(err).To(BeNil())
+
+			fmt.Fprintln(script)
+			fmt.Fprintln(script, "-- name: rename-users")
+			fmt.Fprintln(script, "UPDATE users SET name = {{ bind .name }} WHERE id IN {{ in .ids }}")
+
+			Expect(ioutil.WriteFile(filepath.Join(dir, "users.sql"), script.Bytes(), 0700)).To(Succeed())
+
+			db, err := sqlx.Open("sqlite3", filepath.Join(dir, "prana.db"))
+			Expect(err).To(BeNil())
+
+			_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+			Expect(err).To(BeNil())
+
+			_, err = db.Exec("INSERT INTO users VALUES (1, 'John'), (2, 'Jane'), (3, 'Peter')")
+			Expect(err).To(BeNil())
+
+			runner = &sqlexec.Runner{
+				FileSystem: parcello.Dir(dir),
+				DB:         db,
+			}
+		})
+
+		AfterEach(func() {
+			Expect(runner.DB.Close()).To(Succeed())
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("runs the template", func() {
+			rows, err := runner.RunNamed("search-users", map[string]interface{}{"ids": []int{1, 3}})
+			Expect(err).To(BeNil())
+
+			names := []string{}
+
+			for rows.Next() {
+				var (
+					id   int
+					name string
+				)
+
+				Expect(rows.Scan(&id, &name)).To(Succeed())
+				names = append(names, name)
+			}
+
+			Expect(rows.Close()).To(Succeed())
+			Expect(names).To(Equal([]string{"John", "Peter"}))
+		})
+
+		It("executes the template", func() {
+			result, err := runner.ExecNamed("rename-users", map[string]interface{}{"name": "Jack", "ids": []int{1, 2}})
+			Expect(err).To(BeNil())
+			Expect(result.RowsAffected()).To(BeEquivalentTo(2))
+		})
+
+		Context("when the template is run with positional parameters", func() {
+			It("returns an error", func() {
+				_, err := runner.Run("search-users", "John")
+				Expect(err).To(MatchError("routine 'search-users' is a template that should be rendered with named parameters"))
+			})
+		})
+	})
+})
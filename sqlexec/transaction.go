@@ -0,0 +1,213 @@
+package sqlexec
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// DefaultTxRetries is the number of times a transaction is retried on
+// serialization failure by default.
+const DefaultTxRetries = 3
+
+// TxOptions represents the options of a transaction.
+type TxOptions struct {
+	// Isolation is the isolation level of the transaction. The zero value
+	// is the default level of the driver.
+	Isolation sql.IsolationLevel
+	// ReadOnly determines whether the transaction is read-only.
+	ReadOnly bool
+	// MaxRetries is the number of times the transaction is retried on
+	// serialization failure.
+	MaxRetries int
+}
+
+// TxFunc is a function that runs routines in a transaction.
+type TxFunc func(tx *Tx) error
+
+// Tx runs the routines of a gateway in a transaction.
+type Tx struct {
+	ctx     context.Context
+	gateway *Gateway
+	tx      *sqlx.Tx
+	depth   int
+}
+
+// Run runs a given routine with provided parameters.
+func (t *Tx) Run(name string, args ...Param) (*Rows, error) {
+	call, err := t.gateway.bind(name, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.query(call)
+}
+
+// RunNamed runs a given routine that has named parameters. The argument can
+// be a struct or map[string]interface{}.
+func (t *Tx) RunNamed(name string, arg Param) (*Rows, error) {
+	call, err := t.gateway.bindNamed(name, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.query(call)
+}
+
+// Exec executes a given routine that does not return rows.
+func (t *Tx) Exec(name string, args ...Param) (sql.Result, error) {
+	call, err := t.gateway.bind(name, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.exec(call)
+}
+
+// ExecNamed executes a given routine that has named parameters and does not
+// return rows. The argument can be a struct or map[string]interface{}.
+func (t *Tx) ExecNamed(name string, arg Param) (sql.Result, error) {
+	call, err := t.gateway.bindNamed(name, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.exec(call)
+}
+
+func (t *Tx) query(call *call) (*Rows, error) {
+	defer call.release()
+
+	if call.stmt == nil {
+		return t.tx.QueryxContext(t.ctx, call.query, call.args...)
+	}
+
+	return t.tx.StmtxContext(t.ctx, call.stmt).QueryxContext(t.ctx, call.args...)
+}
+
+func (t *Tx) exec(call *call) (sql.Result, error) {
+	defer call.release()
+
+	if call.stmt == nil {
+		return t.tx.ExecContext(t.ctx, call.query, call.args...)
+	}
+
+	return t.tx.StmtxContext(t.ctx, call.stmt).ExecContext(t.ctx, call.args...)
+}
+
+// Transaction runs a given function in a nested transaction by using a
+// savepoint. The savepoint is rolled back if the function fails.
+func (t *Tx) Transaction(fn TxFunc) (err error) {
+	nested := &Tx{
+		ctx:     t.ctx,
+		gateway: t.gateway,
+		tx:      t.tx,
+		depth:   t.depth + 1,
+	}
+
+	savepoint := fmt.Sprintf("sp_%d", nested.depth)
+
+	if _, err = t.tx.ExecContext(t.ctx, "SAVEPOINT "+savepoint); err != nil {
+		return err
+	}
+
+	defer func() {
+		if reason := recover(); reason != nil {
+			t.tx.ExecContext(t.ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
+			panic(reason)
+		}
+	}()
+
+	if err = fn(nested); err != nil {
+		if _, rbErr := t.tx.ExecContext(t.ctx, "ROLLBACK TO SAVEPOINT "+savepoint); rbErr != nil {
+			return rbErr
+		}
+
+		return err
+	}
+
+	_, err = t.tx.ExecContext(t.ctx, "RELEASE SAVEPOINT "+savepoint)
+	return err
+}
+
+// Transaction runs a given function in a transaction with the default
+// options. The transaction is committed if the function succeeds, otherwise
+// it is rolled back.
+func (g *Gateway) Transaction(ctx context.Context, fn TxFunc) error {
+	return g.TransactionWith(ctx, nil, fn)
+}
+
+// TransactionWith runs a given function in a transaction with given
+// options. The function is run again if the transaction fails due to
+// serialization failure. Therefore it should not have side effects outside
+// of the transaction. The default options are used if the options are nil.
+func (g *Gateway) TransactionWith(ctx context.Context, opts *TxOptions, fn TxFunc) error {
+	if opts == nil {
+		opts = &TxOptions{
+			MaxRetries: DefaultTxRetries,
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := g.transaction(ctx, opts, fn)
+
+		if err == nil || attempt >= opts.MaxRetries || !IsSerializationFailure(err) {
+			return err
+		}
+	}
+}
+
+func (g *Gateway) transaction(ctx context.Context, opts *TxOptions, fn TxFunc) (err error) {
+	if err = g.check(); err != nil {
+		return err
+	}
+
+	tx, err := g.DB.BeginTxx(ctx, &sql.TxOptions{
+		Isolation: opts.Isolation,
+		ReadOnly:  opts.ReadOnly,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if reason := recover(); reason != nil {
+			tx.Rollback()
+			panic(reason)
+		}
+	}()
+
+	current := &Tx{
+		ctx:     ctx,
+		gateway: g,
+		tx:      tx,
+	}
+
+	if err = fn(current); err != nil {
+		// the error of the function is more relevant than the rollback one
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// IsSerializationFailure returns true if the error is caused by a
+// serialization failure or a deadlock in PostgreSQL.
+func IsSerializationFailure(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	if !ok {
+		return false
+	}
+
+	switch pqErr.Code {
+	case "40001", "40P01":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,254 @@
+package sqlexec_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/prana/sqlexec"
+)
+
+var _ = Describe("Transaction", func() {
+	var (
+		gateway *sqlexec.Gateway
+		ctx     context.Context
+	)
+
+	count := func() int {
+		total := 0
+		Expect(gateway.DB.Get(&total, "SELECT count(*) FROM users")).To(Succeed())
+		return total
+	}
+
+	BeforeEach(func() {
+		dir, err := ioutil.TempDir("", "prana_tx")
+		Expect(err).To(BeNil())
+
+		db, err := sqlx.Open("sqlite3", filepath.Join(dir, "prana.db"))
+		Expect(err).To(BeNil())
+
+		_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)")
+		Expect(err).To(BeNil())
+
+		buffer := &bytes.Buffer{}
+		fmt.Fprintln(buffer, "-- name: select-users")
+		fmt.Fprintln(buffer, "SELECT * FROM users WHERE name = ?")
+		fmt.Fprintln(buffer)
+		fmt.Fprintln(buffer, "-- name: insert-user")
+		fmt.Fprintln(buffer, "INSERT INTO users (name) VALUES (?)")
+		fmt.Fprintln(buffer)
+		fmt.Fprintln(buffer, "-- name: insert-named-user")
+		fmt.Fprintln(buffer, "INSERT INTO users (name) VALUES (:name)")
+		fmt.Fprintln(buffer)
+		fmt.Fprintln(buffer, "-- name: delete-users")
+		fmt.Fprintln(buffer, "DELETE FROM users WHERE name IN (?)")
+
+		provider := &sqlexec.Provider{DriverName: "sqlite3"}
+		_, err = provider.ReadFrom(buffer)
+		Expect(err).To(BeNil())
+
+		gateway = &sqlexec.Gateway{
+			Provider: provider,
+			DB:       db,
+		}
+
+		ctx = context.Background()
+	})
+
+	AfterEach(func() {
+		Expect(gateway.Close()).To(Succeed())
+		Expect(gateway.DB.Close()).To(Succeed())
+	})
+
+	It("commits the transaction", func() {
+		err := gateway.Transaction(ctx, func(tx *sqlexec.Tx) error {
+			if _, err := tx.Exec("insert-user", "John"); err != nil {
+				return err
+			}
+
+			if _, err := tx.ExecNamed("insert-named-user", map[string]interface{}{"name": "Jane"}); err != nil {
+				return err
+			}
+
+			rows, err := tx.Run("select-users", "John")
+			if err != nil {
+				return err
+			}
+
+			Expect(rows.Next()).To(BeTrue())
+			return rows.Close()
+		})
+
+		Expect(err).To(Succeed())
+		Expect(count()).To(Equal(2))
+	})
+
+	It("expands the slice parameters", func() {
+		err := gateway.Transaction(ctx, func(tx *sqlexec.Tx) error {
+			for _, name := range []string{"John", "Jane", "Peter"} {
+				if _, err := tx.Exec("insert-user", name); err != nil {
+					return err
+				}
+			}
+
+			_, err := tx.Exec("delete-users", []string{"John", "Jane"})
+			return err
+		})
+
+		Expect(err).To(Succeed())
+		Expect(count()).To(Equal(1))
+	})
+
+	Context("when the function fails", func() {
+		It("rolls back the transaction", func() {
+			err := gateway.Transaction(ctx, func(tx *sqlexec.Tx) error {
+				if _, err := tx.Exec("insert-user", "John"); err != nil {
+					return err
+				}
+
+				return fmt.Errorf("oh no!")
+			})
+
+			Expect(err).To(MatchError("oh no!"))
+			Expect(count()).To(BeZero())
+		})
+	})
+
+	Context("when the function panics", func() {
+		It("rolls back the transaction", func() {
+			Expect(func() {
+				gateway.Transaction(ctx, func(tx *sqlexec.Tx) error {
+					tx.Exec("insert-user", "John")
+					panic("oh no!")
+				})
+			}).To(Panic())
+
+			Expect(count()).To(BeZero())
+		})
+	})
+
+	Context("when the routine does not exist", func() {
+		It("returns an error", func() {
+			err := gateway.Transaction(ctx, func(tx *sqlexec.Tx) error {
+				_, err := tx.Exec("unknown")
+				return err
+			})
+
+			Expect(err).To(MatchError("query 'unknown' not found"))
+		})
+	})
+
+	Describe("savepoints", func() {
+		It("rolls back the nested transaction only", func() {
+			err := gateway.Transaction(ctx, func(tx *sqlexec.Tx) error {
+				if _, err := tx.Exec("insert-user", "John"); err != nil {
+					return err
+				}
+
+				nestedErr := tx.Transaction(func(nested *sqlexec.Tx) error {
+					if _, err := nested.Exec("insert-user", "Jane"); err != nil {
+						return err
+					}
+
+					return fmt.Errorf("oh no!")
+				})
+
+				Expect(nestedErr).To(MatchError("oh no!"))
+
+				return tx.Transaction(func(nested *sqlexec.Tx) error {
+					_, err := nested.Exec("insert-user", "Peter")
+					return err
+				})
+			})
+
+			Expect(err).To(Succeed())
+			Expect(count()).To(Equal(2))
+		})
+	})
+
+	Describe("TransactionWith", func() {
+		It("runs a read-only transaction", func() {
+			opts := &sqlexec.TxOptions{
+				Isolation: sql.LevelSerializable,
+				ReadOnly:  true,
+			}
+
+			err := gateway.TransactionWith(ctx, opts, func(tx *sqlexec.Tx) error {
+				rows, err := tx.Run("select-users", "John")
+				if err != nil {
+					return err
+				}
+
+				return rows.Close()
+			})
+
+			Expect(err).To(Succeed())
+		})
+
+		It("retries the transaction on serialization failure", func() {
+			attempts := 0
+			opts := &sqlexec.TxOptions{MaxRetries: 2}
+
+			err := gateway.TransactionWith(ctx, opts, func(tx *sqlexec.Tx) error {
+				attempts++
+				return &pq.Error{Code: "40001", Message: "could not serialize access due to concurrent update"}
+			})
+
+			Expect(err).To(MatchError("pq: could not serialize access due to concurrent update"))
+			Expect(attempts).To(Equal(3))
+		})
+
+		It("uses the default options when they are not provided", func() {
+			attempts := 0
+
+			err := gateway.TransactionWith(ctx, nil, func(tx *sqlexec.Tx) error {
+				attempts++
+				return &pq.Error{Code: "40P01", Message: "deadlock detected"}
+			})
+
+			Expect(err).To(MatchError("pq: deadlock detected"))
+			Expect(attempts).To(Equal(sqlexec.DefaultTxRetries + 1))
+		})
+
+		It("does not retry the transaction on other failures", func() {
+			attempts := 0
+			opts := &sqlexec.TxOptions{MaxRetries: 2}
+
+			err := gateway.TransactionWith(ctx, opts, func(tx *sqlexec.Tx) error {
+				attempts++
+				return fmt.Errorf("oh no!")
+			})
+
+			Expect(err).To(MatchError("oh no!"))
+			Expect(attempts).To(Equal(1))
+		})
+	})
+
+	Context("when the gateway is closed", func() {
+		It("returns an error", func() {
+			Expect(gateway.Close()).To(Succeed())
+
+			err := gateway.Transaction(ctx, func(tx *sqlexec.Tx) error {
+				return nil
+			})
+
+			Expect(err).To(MatchError("gateway is closed"))
+		})
+	})
+
+	Describe("IsSerializationFailure", func() {
+		It("returns true for serialization failures", func() {
+			Expect(sqlexec.IsSerializationFailure(&pq.Error{Code: "40001"})).To(BeTrue())
+			Expect(sqlexec.IsSerializationFailure(&pq.Error{Code: "40P01"})).To(BeTrue())
+			Expect(sqlexec.IsSerializationFailure(&pq.Error{Code: "23505"})).To(BeFalse())
+			Expect(sqlexec.IsSerializationFailure(fmt.Errorf("pq: deadlock detected"))).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,221 @@
+package sqlfixture
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phogolabs/prana/sqlmodel"
+)
+
+var layouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"15:04:05",
+}
+
+// Convert converts a fixture value to the type of a given column.
+func Convert(column *sqlmodel.Column, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	kind := strings.TrimPrefix(column.ScanType, "null.")
+	kind = strings.ToLower(kind)
+
+	var (
+		result interface{}
+		err    error
+	)
+
+	switch kind {
+	case "int", "int8", "int16", "int32", "int64":
+		result, err = toInt(value)
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		result, err = toUint(value)
+	case "float32", "float64":
+		result, err = toFloat(value)
+	case "bool":
+		result, err = toBool(value)
+	case "time.time", "time":
+		result, err = toTime(value)
+	case "[]byte", "bytes":
+		result, err = toBytes(column, value)
+	case "json":
+		result, err = toJSON(value)
+	default:
+		result, err = toString(value)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("column '%s' of type '%s': %v", column.Name, column.Type.Name, err)
+	}
+
+	return result, nil
+}
+
+func toInt(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint64:
+		return int64(v), nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("cannot convert '%v' to integer", v)
+		}
+		return int64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	default:
+		return 0, fmt.Errorf("cannot convert '%v' to integer", v)
+	}
+}
+
+func toUint(value interface{}) (uint64, error) {
+	switch v := value.(type) {
+	case uint64:
+		return v, nil
+	case string:
+		return strconv.ParseUint(v, 10, 64)
+	default:
+		n, err := toInt(value)
+		if err != nil {
+			return 0, err
+		}
+
+		if n < 0 {
+			return 0, fmt.Errorf("cannot convert '%v' to unsigned integer", v)
+		}
+
+		return uint64(n), nil
+	}
+}
+
+func toFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("cannot convert '%v' to float", v)
+	}
+}
+
+func toBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(v)
+	default:
+		n, err := toFloat(value)
+		return n != 0, err
+	}
+}
+
+func toTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot parse '%s' as time", v)
+	default:
+		return time.Time{}, fmt.Errorf("cannot convert '%v' to time", v)
+	}
+}
+
+func toBytes(column *sqlmodel.Column, value interface{}) (interface{}, error) {
+	switch strings.ToLower(column.Type.Name) {
+	case "json", "jsonb":
+		return toJSON(value)
+	}
+
+	switch v := value.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("cannot convert '%v' to bytes", v)
+	}
+}
+
+func toJSON(value interface{}) (interface{}, error) {
+	if text, ok := value.(string); ok {
+		return text, nil
+	}
+
+	data, err := json.Marshal(normalize(value))
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+func toString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case map[interface{}]interface{}, map[string]interface{}, []interface{}:
+		data, err := json.Marshal(normalize(v))
+		return string(data), err
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// normalize converts the YAML maps to maps that can be encoded as JSON
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalize(item)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = normalize(item)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for index, item := range v {
+			s[index] = normalize(item)
+		}
+		return s
+	default:
+		return v
+	}
+}
@@ -0,0 +1,104 @@
+package sqlfixture_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/prana/sqlfixture"
+	"github.com/phogolabs/prana/sqlmodel"
+)
+
+var _ = Describe("Convert", func() {
+	column := func(name, scanType string) *sqlmodel.Column {
+		return &sqlmodel.Column{
+			Name:     "field",
+			Type:     sqlmodel.ColumnType{Name: name},
+			ScanType: scanType,
+		}
+	}
+
+	It("converts nil value", func() {
+		value, err := sqlfixture.Convert(column("int", "null.Int"), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(BeNil())
+	})
+
+	It("converts integer value", func() {
+		value, err := sqlfixture.Convert(column("int", "int"), float64(42))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal(int64(42)))
+
+		value, err = sqlfixture.Convert(column("int", "null.Int"), "42")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal(int64(42)))
+	})
+
+	It("converts unsigned integer value", func() {
+		value, err := sqlfixture.Convert(column("int", "Uint"), 42)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal(uint64(42)))
+	})
+
+	It("converts float value", func() {
+		value, err := sqlfixture.Convert(column("real", "float32"), 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal(float64(1)))
+	})
+
+	It("converts bool value", func() {
+		value, err := sqlfixture.Convert(column("boolean", "bool"), "true")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal(true))
+
+		value, err = sqlfixture.Convert(column("tinyint", "null.Bool"), 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal(false))
+	})
+
+	It("converts time value", func() {
+		value, err := sqlfixture.Convert(column("timestamp", "time.Time"), "2006-01-02 15:04:05")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal(time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)))
+
+		value, err = sqlfixture.Convert(column("date", "null.Time"), "2006-01-02")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal(time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)))
+	})
+
+	It("converts json value", func() {
+		value, err := sqlfixture.Convert(column("jsonb", "[]byte"), map[interface{}]interface{}{"name": "John"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal(`{"name":"John"}`))
+
+		value, err = sqlfixture.Convert(column("json", "null.JSON"), []interface{}{1, 2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal(`[1,2]`))
+	})
+
+	It("converts bytes value", func() {
+		value, err := sqlfixture.Convert(column("bytea", "[]byte"), "data")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal([]byte("data")))
+	})
+
+	It("converts string value", func() {
+		value, err := sqlfixture.Convert(column("text", "string"), 42)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal("42"))
+	})
+
+	Context("when the value cannot be converted", func() {
+		It("returns an error", func() {
+			value, err := sqlfixture.Convert(column("int", "int"), 4.2)
+			Expect(value).To(BeNil())
+			Expect(err).To(MatchError("column 'field' of type 'int': cannot convert '4.2' to integer"))
+		})
+
+		It("returns an error for time", func() {
+			value, err := sqlfixture.Convert(column("date", "time.Time"), "yesterday")
+			Expect(value).To(BeNil())
+			Expect(err).To(MatchError("column 'field' of type 'date': cannot parse 'yesterday' as time"))
+		})
+	})
+})
@@ -0,0 +1,110 @@
+package sqlfixture
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// References returns the tables referenced by the foreign keys of a given
+// table.
+func References(db *sqlx.DB, schema, table string) ([]string, error) {
+	query := &bytes.Buffer{}
+	args := []interface{}{}
+
+	switch db.DriverName() {
+	case "sqlite3":
+		query.WriteString("SELECT DISTINCT \"table\" FROM pragma_foreign_key_list(?)")
+		args = append(args, table)
+	case "postgres":
+		if schema == "" {
+			schema = "public"
+		}
+
+		query.WriteString("SELECT DISTINCT ccu.table_name ")
+		query.WriteString("FROM information_schema.table_constraints AS tc ")
+		query.WriteString("JOIN information_schema.constraint_column_usage AS ccu ")
+		query.WriteString("ON ccu.constraint_name = tc.constraint_name AND ccu.constraint_schema = tc.constraint_schema ")
+		query.WriteString("WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = ? AND tc.table_name = ?")
+		args = append(args, schema, table)
+	case "mysql":
+		query.WriteString("SELECT DISTINCT referenced_table_name ")
+		query.WriteString("FROM information_schema.key_column_usage ")
+		query.WriteString("WHERE referenced_table_name IS NOT NULL AND table_name = ? ")
+		args = append(args, table)
+
+		if schema == "" {
+			query.WriteString("AND table_schema = DATABASE()")
+		} else {
+			query.WriteString("AND table_schema = ?")
+			args = append(args, schema)
+		}
+	default:
+		return nil, fmt.Errorf("cannot find foreign keys for database driver '%s'", db.DriverName())
+	}
+
+	tables := []string{}
+
+	if err := db.Select(&tables, db.Rebind(query.String()), args...); err != nil {
+		return nil, err
+	}
+
+	return tables, nil
+}
+
+// Order orders the tables, so each table is after the tables that it
+// references. The references to tables outside the list are ignored.
+func Order(tables []string, references map[string][]string) ([]string, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	var (
+		names  = append([]string{}, tables...)
+		state  = make(map[string]int, len(tables))
+		result = []string{}
+		visit  func(table string) error
+	)
+
+	for _, table := range tables {
+		state[table] = 0
+	}
+
+	visit = func(table string) error {
+		switch state[table] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("table '%s' has a circular foreign key dependency", table)
+		}
+
+		state[table] = visiting
+
+		for _, ref := range references[table] {
+			if _, ok := state[ref]; !ok || ref == table {
+				continue
+			}
+
+			if err := visit(ref); err != nil {
+				return err
+			}
+		}
+
+		state[table] = visited
+		result = append(result, table)
+		return nil
+	}
+
+	sort.Strings(names)
+
+	for _, table := range names {
+		if err := visit(table); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package sqlfixture_test
+
+import (
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/prana/sqlfixture"
+)
+
+var _ = Describe("Dependency", func() {
+	Describe("References", func() {
+		var db *sqlx.DB
+
+		BeforeEach(func() {
+			var err error
+
+			db, err = sqlx.Open("sqlite3", ":memory:")
+			Expect(err).NotTo(HaveOccurred())
+			db.SetMaxOpenConns(1)
+
+			_, err = db.Exec("CREATE TABLE users (id INT PRIMARY KEY)")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = db.Exec("CREATE TABLE orders (id INT PRIMARY KEY, user_id INT REFERENCES users(id))")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(db.Close()).To(Succeed())
+		})
+
+		It("returns the referenced tables", func() {
+			tables, err := sqlfixture.References(db, "", "orders")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tables).To(Equal([]string{"users"}))
+		})
+
+		It("returns no tables", func() {
+			tables, err := sqlfixture.References(db, "", "users")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tables).To(BeEmpty())
+		})
+	})
+
+	Describe("Order", func() {
+		It("orders the tables by their references", func() {
+			tables, err := sqlfixture.Order([]string{"orders", "items", "users"}, map[string][]string{
+				"orders": {"users"},
+				"items":  {"orders", "products"},
+				"users":  {"users"},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tables).To(Equal([]string{"users", "orders", "items"}))
+		})
+
+		Context("when the references are circular", func() {
+			It("returns an error", func() {
+				tables, err := sqlfixture.Order([]string{"orders", "users"}, map[string][]string{
+					"orders": {"users"},
+					"users":  {"orders"},
+				})
+
+				Expect(tables).To(BeNil())
+				Expect(err).To(MatchError("table 'orders' has a circular foreign key dependency"))
+			})
+		})
+	})
+})
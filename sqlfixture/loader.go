@@ -0,0 +1,319 @@
+package sqlfixture
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phogolabs/prana/sqlmodel"
+)
+
+// Loader loads fixtures into the database.
+type Loader struct {
+	// FileSystem represents the fixture directory file system.
+	FileSystem FileSystem
+	// DB is a client to underlying database.
+	DB *sqlx.DB
+	// Provider provides the column metadata of the tables. It defaults to
+	// the provider of the database driver.
+	Provider sqlmodel.SchemaProvider
+	// Schema is the database schema name.
+	Schema string
+
+	tables []string
+}
+
+// Read reads the fixtures with given names. If no names are provided, it
+// reads all fixtures in the file system root.
+func (l *Loader) Read(names ...string) (Fixture, error) {
+	if len(names) == 0 {
+		var err error
+
+		if names, err = l.files(); err != nil {
+			return nil, err
+		}
+	}
+
+	fixture := Fixture{}
+
+	for _, name := range names {
+		item, err := l.read(name)
+		if err != nil {
+			return nil, err
+		}
+
+		fixture.Merge(item)
+	}
+
+	return fixture, nil
+}
+
+// Load reads the fixtures with given names and inserts their rows in the
+// database. If no names are provided, it loads all fixtures in the file
+// system root.
+func (l *Loader) Load(names ...string) error {
+	fixture, err := l.Read(names...)
+	if err != nil {
+		return err
+	}
+
+	return l.Insert(fixture)
+}
+
+// Insert inserts the rows of a given fixture in a single transaction. The
+// tables are filled in their foreign key order.
+func (l *Loader) Insert(fixture Fixture) error {
+	if len(fixture.Tables()) == 0 {
+		return nil
+	}
+
+	tables, err := l.transaction(fixture, func(tx *sqlx.Tx) error {
+		return tx.Commit()
+	})
+
+	if err != nil {
+		return err
+	}
+
+	l.track(tables)
+	return nil
+}
+
+// Try inserts the rows of a given fixture and runs a given function in the
+// same transaction. The transaction is always rolled back, so the database
+// is not changed.
+func (l *Loader) Try(fixture Fixture, fn func(tx *sqlx.Tx) error) error {
+	_, err := l.transaction(fixture, func(tx *sqlx.Tx) error {
+		err := fn(tx)
+
+		if rbErr := tx.Rollback(); err == nil {
+			err = rbErr
+		}
+
+		return err
+	})
+
+	return err
+}
+
+// transaction inserts the rows of a given fixture in a transaction that is
+// completed by the finish function. The schema is read before the
+// transaction is started.
+func (l *Loader) transaction(fixture Fixture, finish func(tx *sqlx.Tx) error) ([]string, error) {
+	tables, err := l.order(fixture.Tables())
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &sqlmodel.Schema{}
+
+	if len(tables) > 0 {
+		provider, err := l.provider()
+		if err != nil {
+			return nil, err
+		}
+
+		if schema, err = provider.Schema(l.Schema, tables...); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := l.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	for index, table := range schema.Tables {
+		if err := l.insert(tx, &schema.Tables[index], fixture[table.Name]); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	return tables, finish(tx)
+}
+
+// Truncate deletes all rows of the loaded tables in reverse foreign key
+// order. It is useful to clean up the database between the tests.
+func (l *Loader) Truncate() error {
+	tables, err := l.order(l.tables)
+	if err != nil {
+		return err
+	}
+
+	tx, err := l.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	for index := len(tables) - 1; index >= 0; index-- {
+		query := fmt.Sprintf("DELETE FROM %s", tables[index])
+
+		if _, err := tx.Exec(query); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	l.tables = nil
+	return nil
+}
+
+func (l *Loader) files() ([]string, error) {
+	names := []string{}
+	root := true
+
+	err := l.FileSystem.Walk("/", func(path string, info os.FileInfo, err error) error {
+		if info == nil {
+			return os.ErrNotExist
+		}
+
+		if info.IsDir() {
+			if root {
+				root = false
+				return nil
+			}
+
+			return filepath.SkipDir
+		}
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yml", ".yaml", ".json":
+			names = append(names, path)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return []string{}, err
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func (l *Loader) read(name string) (fixture Fixture, err error) {
+	file, err := l.FileSystem.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if ioErr := file.Close(); err == nil {
+			err = ioErr
+		}
+	}()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(name, data)
+}
+
+func (l *Loader) order(tables []string) ([]string, error) {
+	references := make(map[string][]string, len(tables))
+
+	for _, table := range tables {
+		names, err := References(l.DB, l.Schema, table)
+		if err != nil {
+			return nil, err
+		}
+
+		references[table] = names
+	}
+
+	return Order(tables, references)
+}
+
+func (l *Loader) insert(tx *sqlx.Tx, table *sqlmodel.Table, rows []Row) error {
+	if len(table.Columns) == 0 {
+		return fmt.Errorf("table '%s' not found", table.Name)
+	}
+
+	columns := make(map[string]*sqlmodel.Column, len(table.Columns))
+
+	for index, column := range table.Columns {
+		columns[column.Name] = &table.Columns[index]
+	}
+
+	for _, row := range rows {
+		names := []string{}
+
+		for name := range row {
+			if _, ok := columns[name]; !ok {
+				return fmt.Errorf("column '%s' not found in table '%s'", name, table.Name)
+			}
+
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		args := []interface{}{}
+
+		for _, name := range names {
+			value, err := Convert(columns[name], row[name])
+			if err != nil {
+				return fmt.Errorf("table '%s': %v", table.Name, err)
+			}
+
+			args = append(args, value)
+		}
+
+		query := &bytes.Buffer{}
+		fmt.Fprintf(query, "INSERT INTO %s (%s) ", table.Name, strings.Join(names, ", "))
+		fmt.Fprintf(query, "VALUES (%s)", strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", "))
+
+		if _, err := tx.Exec(tx.Rebind(query.String()), args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *Loader) track(tables []string) {
+	for _, table := range tables {
+		found := false
+
+		for _, name := range l.tables {
+			if name == table {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			l.tables = append(l.tables, table)
+		}
+	}
+}
+
+func (l *Loader) provider() (sqlmodel.SchemaProvider, error) {
+	if l.Provider != nil {
+		return l.Provider, nil
+	}
+
+	switch l.DB.DriverName() {
+	case "sqlite3":
+		return &sqlmodel.SQLiteProvider{DB: l.DB}, nil
+	case "postgres":
+		return &sqlmodel.PostgreSQLProvider{DB: l.DB}, nil
+	case "mysql":
+		return &sqlmodel.MySQLProvider{DB: l.DB}, nil
+	default:
+		return nil, fmt.Errorf("cannot find provider for database driver '%s'", l.DB.DriverName())
+	}
+}
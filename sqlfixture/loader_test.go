@@ -0,0 +1,182 @@
+package sqlfixture_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/prana/sqlfixture"
+)
+
+var _ = Describe("Loader", func() {
+	var (
+		loader *sqlfixture.Loader
+		dir    string
+	)
+
+	count := func(table string) int {
+		count := 0
+		Expect(loader.DB.Get(&count, fmt.Sprintf("SELECT count(*) FROM %s", table))).To(Succeed())
+		return count
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "prana_fixture")
+		Expect(err).NotTo(HaveOccurred())
+
+		db, err := sqlx.Open("sqlite3", filepath.Join(dir, "prana.db"))
+		Expect(err).NotTo(HaveOccurred())
+		db.SetMaxOpenConns(1)
+
+		_, err = db.Exec("PRAGMA foreign_keys = ON")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = db.Exec("CREATE TABLE users (id INT PRIMARY KEY, name TEXT NOT NULL, active BOOLEAN, created_at TIMESTAMP)")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = db.Exec("CREATE TABLE orders (id INT PRIMARY KEY, user_id INT NOT NULL REFERENCES users(id), total REAL)")
+		Expect(err).NotTo(HaveOccurred())
+
+		content := &bytes.Buffer{}
+		fmt.Fprintln(content, "orders:")
+		fmt.Fprintln(content, "  - id: 1")
+		fmt.Fprintln(content, "    user_id: 1")
+		fmt.Fprintln(content, "    total: 9.99")
+		fmt.Fprintln(content, "users:")
+		fmt.Fprintln(content, "  - id: 1")
+		fmt.Fprintln(content, "    name: John")
+		fmt.Fprintln(content, "    active: true")
+		fmt.Fprintln(content, "    created_at: 2006-01-02 15:04:05")
+
+		path := filepath.Join(dir, "orders.yml")
+		Expect(ioutil.WriteFile(path, content.Bytes(), 0700)).To(Succeed())
+
+		path = filepath.Join(dir, "users.json")
+		Expect(ioutil.WriteFile(path, []byte(`{"users": [{"id": 2, "name": "Jack", "active": false}]}`), 0700)).To(Succeed())
+
+		loader = &sqlfixture.Loader{
+			FileSystem: parcello.Dir(dir),
+			DB:         db,
+		}
+	})
+
+	AfterEach(func() {
+		Expect(loader.DB.Close()).To(Succeed())
+	})
+
+	It("loads all fixtures", func() {
+		Expect(loader.Load()).To(Succeed())
+		Expect(count("users")).To(Equal(2))
+		Expect(count("orders")).To(Equal(1))
+
+		createdAt := time.Time{}
+		Expect(loader.DB.Get(&createdAt, "SELECT created_at FROM users WHERE id = 1")).To(Succeed())
+		Expect(createdAt.Equal(time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC))).To(BeTrue())
+	})
+
+	It("loads the given fixtures", func() {
+		Expect(loader.Load("users.json")).To(Succeed())
+		Expect(count("users")).To(Equal(1))
+		Expect(count("orders")).To(BeZero())
+	})
+
+	It("truncates the loaded tables", func() {
+		Expect(loader.Load()).To(Succeed())
+		Expect(loader.Truncate()).To(Succeed())
+		Expect(count("users")).To(BeZero())
+		Expect(count("orders")).To(BeZero())
+	})
+
+	Describe("Try", func() {
+		It("runs the function with the fixture and rolls it back", func() {
+			fixture, err := loader.Read("users.json")
+			Expect(err).To(Succeed())
+
+			err = loader.Try(fixture, func(tx *sqlx.Tx) error {
+				rows := 0
+				Expect(tx.Get(&rows, "SELECT count(*) FROM users")).To(Succeed())
+				Expect(rows).To(Equal(1))
+
+				_, err := tx.Exec("DELETE FROM users")
+				return err
+			})
+
+			Expect(err).To(Succeed())
+			Expect(count("users")).To(BeZero())
+		})
+
+		It("does not change the existing rows", func() {
+			Expect(loader.Load("users.json")).To(Succeed())
+
+			err := loader.Try(sqlfixture.Fixture{}, func(tx *sqlx.Tx) error {
+				_, err := tx.Exec("DELETE FROM users")
+				return err
+			})
+
+			Expect(err).To(Succeed())
+			Expect(count("users")).To(Equal(1))
+		})
+
+		Context("when the function fails", func() {
+			It("returns its error", func() {
+				err := loader.Try(sqlfixture.Fixture{}, func(tx *sqlx.Tx) error {
+					return fmt.Errorf("oh no")
+				})
+
+				Expect(err).To(MatchError("oh no"))
+			})
+		})
+	})
+
+	Context("when the column does not exist", func() {
+		It("returns an error", func() {
+			err := loader.Insert(sqlfixture.Fixture{
+				"users": []sqlfixture.Row{{"id": 1, "email": "john@example.com"}},
+			})
+
+			Expect(err).To(MatchError("column 'email' not found in table 'users'"))
+		})
+	})
+
+	Context("when the table does not exist", func() {
+		It("returns an error", func() {
+			err := loader.Insert(sqlfixture.Fixture{
+				"roles": []sqlfixture.Row{{"id": 1}},
+			})
+
+			Expect(err).To(MatchError("table 'roles' not found"))
+		})
+	})
+
+	Context("when the value cannot be converted", func() {
+		It("rollbacks the fixture", func() {
+			err := loader.Insert(sqlfixture.Fixture{
+				"users":  []sqlfixture.Row{{"id": 1, "name": "John"}},
+				"orders": []sqlfixture.Row{{"id": 1, "user_id": "one"}},
+			})
+
+			Expect(err).To(HaveOccurred())
+			Expect(count("users")).To(BeZero())
+		})
+	})
+
+	Context("when the directory does not exist", func() {
+		BeforeEach(func() {
+			loader.FileSystem = parcello.Dir(filepath.Join(dir, "fixture"))
+		})
+
+		It("returns an error", func() {
+			err := loader.Load()
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+})
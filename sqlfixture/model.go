@@ -0,0 +1,61 @@
+// Package sqlfixture provides primitives and functions to load YAML and JSON
+// fixtures into the database.
+package sqlfixture
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/phogolabs/parcello"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// FileSystem provides with primitives to work with the underlying file system
+type FileSystem = parcello.FileSystem
+
+// Row represents a single table row as column values.
+type Row map[string]interface{}
+
+// Fixture represents the rows of the database tables keyed by table name.
+type Fixture map[string][]Row
+
+// Tables returns the names of the fixture tables.
+func (f Fixture) Tables() []string {
+	tables := []string{}
+
+	for table := range f {
+		tables = append(tables, table)
+	}
+
+	return tables
+}
+
+// Merge appends the rows of a given fixture.
+func (f Fixture) Merge(fixture Fixture) {
+	for table, rows := range fixture {
+		f[table] = append(f[table], rows...)
+	}
+}
+
+// Parse parses a fixture content. The format is determined by the file
+// extension, which should be one of '.yml', '.yaml' or '.json'.
+func Parse(path string, data []byte) (Fixture, error) {
+	fixture := Fixture{}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yml", ".yaml":
+		if err := yaml.Unmarshal(data, &fixture); err != nil {
+			return nil, err
+		}
+	case ".json":
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("fixture '%s' has unsupported format '%s'", path, ext)
+	}
+
+	return fixture, nil
+}
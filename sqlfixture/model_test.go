@@ -0,0 +1,69 @@
+package sqlfixture_test
+
+import (
+	"bytes"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/prana/sqlfixture"
+)
+
+var _ = Describe("Model", func() {
+	Describe("Parse", func() {
+		It("parses YAML fixture", func() {
+			content := &bytes.Buffer{}
+			fmt.Fprintln(content, "users:")
+			fmt.Fprintln(content, "  - id: 1")
+			fmt.Fprintln(content, "    name: John")
+
+			fixture, err := sqlfixture.Parse("users.yml", content.Bytes())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fixture).To(HaveKey("users"))
+			Expect(fixture["users"]).To(HaveLen(1))
+			Expect(fixture["users"][0]).To(HaveKeyWithValue("id", 1))
+			Expect(fixture["users"][0]).To(HaveKeyWithValue("name", "John"))
+		})
+
+		It("parses JSON fixture", func() {
+			content := []byte(`{"users": [{"id": 1, "name": "John"}]}`)
+
+			fixture, err := sqlfixture.Parse("users.json", content)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fixture["users"][0]).To(HaveKeyWithValue("id", float64(1)))
+			Expect(fixture["users"][0]).To(HaveKeyWithValue("name", "John"))
+		})
+
+		Context("when the format is not supported", func() {
+			It("returns an error", func() {
+				fixture, err := sqlfixture.Parse("users.xml", []byte{})
+				Expect(fixture).To(BeNil())
+				Expect(err).To(MatchError("fixture 'users.xml' has unsupported format '.xml'"))
+			})
+		})
+
+		Context("when the content is not valid", func() {
+			It("returns an error", func() {
+				fixture, err := sqlfixture.Parse("users.json", []byte("{"))
+				Expect(fixture).To(BeNil())
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Fixture", func() {
+		It("merges the rows", func() {
+			fixture := sqlfixture.Fixture{
+				"users": []sqlfixture.Row{{"id": 1}},
+			}
+
+			fixture.Merge(sqlfixture.Fixture{
+				"users": []sqlfixture.Row{{"id": 2}},
+				"roles": []sqlfixture.Row{{"id": 1}},
+			})
+
+			Expect(fixture.Tables()).To(ConsistOf("users", "roles"))
+			Expect(fixture["users"]).To(HaveLen(2))
+		})
+	})
+})
@@ -0,0 +1,188 @@
+package sqlmigr
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CheckStatus represents the state of the database compared to the project
+// migrations.
+type CheckStatus string
+
+const (
+	// StatusUpToDate is reported when all migrations have been applied.
+	StatusUpToDate CheckStatus = "up-to-date"
+	// StatusPending is reported when some migrations have not been applied.
+	StatusPending CheckStatus = "pending"
+	// StatusUnknown is reported when the database has applied migrations that
+	// the project does not have.
+	StatusUnknown CheckStatus = "unknown"
+	// StatusMismatch is reported when an applied migration does not match the
+	// project migration with the same id.
+	StatusMismatch CheckStatus = "mismatch"
+)
+
+// CheckResult is the result of comparing the project migrations with the
+// applied ones.
+type CheckResult struct {
+	// Status is the overall status of the database.
+	Status CheckStatus
+	// Pending are the migrations that have not been applied.
+	Pending []*Migration
+	// Unknown are the applied migrations that the project does not have.
+	Unknown []*Migration
+	// Mismatched are the applied migrations whose description or checksum
+	// differs from the project migration with the same id. The checksum is
+	// not verified for the migrations applied before it was tracked.
+	Mismatched []*Migration
+}
+
+// IsUpToDate returns true if all migrations have been applied.
+func (r *CheckResult) IsUpToDate() bool {
+	return r.Status == StatusUpToDate
+}
+
+// Checker compares the project migrations with the applied ones.
+type Checker struct {
+	// FileSystem represents the project directory file system.
+	FileSystem FileSystem
+	// DB is a client to underlying database.
+	DB *sqlx.DB
+	// Table is the name of the table that tracks the applied migrations. It
+	// defaults to 'migrations'.
+	Table string
+	// Modules are the modules whose migrations are compared as well. Each
+	// module is compared with its own tracking table.
+	Modules []*Module
+}
+
+// Check compares the project migrations with the applied ones.
+func (c *Checker) Check() (*CheckResult, error) {
+	result := &CheckResult{}
+
+	providers := []*Provider{
+		{
+			FileSystem: c.FileSystem,
+			DB:         c.DB,
+			Table:      c.Table,
+		},
+	}
+
+	for _, module := range c.Modules {
+		providers = append(providers, &Provider{
+			FileSystem: module.FileSystem,
+			DB:         c.DB,
+			Table:      module.TableName(),
+		})
+	}
+
+	for _, provider := range providers {
+		local, err := provider.files()
+		if err != nil {
+			return nil, err
+		}
+
+		remote, err := provider.query()
+		if err != nil {
+			return nil, err
+		}
+
+		c.compare(result, remote, local)
+	}
+
+	switch {
+	case len(result.Mismatched) > 0:
+		result.Status = StatusMismatch
+	case len(result.Unknown) > 0:
+		result.Status = StatusUnknown
+	case len(result.Pending) > 0:
+		result.Status = StatusPending
+	default:
+		result.Status = StatusUpToDate
+	}
+
+	return result, nil
+}
+
+func (c *Checker) compare(result *CheckResult, remote, local []*Migration) {
+	applied := make(map[string]*Migration, len(remote))
+
+	for _, r := range remote {
+		applied[r.ID] = r
+	}
+
+	for _, l := range local {
+		r, ok := applied[l.ID]
+
+		switch {
+		case !ok:
+			result.Pending = append(result.Pending, l)
+		case r.Description != l.Description:
+			result.Mismatched = append(result.Mismatched, r)
+		case r.Checksum != "" && r.Checksum != l.Checksum:
+			result.Mismatched = append(result.Mismatched, r)
+		}
+
+		delete(applied, l.ID)
+	}
+
+	for _, r := range remote {
+		if _, ok := applied[r.ID]; ok {
+			result.Unknown = append(result.Unknown, r)
+		}
+	}
+}
+
+// CheckHandler reports the migration status in a readiness probe format. It
+// responds with 200 OK when the database is up to date and with 503 Service
+// Unavailable otherwise.
+type CheckHandler struct {
+	// Checker checks the migrations.
+	Checker *Checker
+}
+
+type checkReport struct {
+	Status     string   `json:"status"`
+	Error      string   `json:"error,omitempty"`
+	Pending    []string `json:"pending,omitempty"`
+	Unknown    []string `json:"unknown,omitempty"`
+	Mismatched []string `json:"mismatched,omitempty"`
+}
+
+// ServeHTTP serves the migration status.
+func (h *CheckHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := &checkReport{}
+	code := http.StatusServiceUnavailable
+
+	result, err := h.Checker.Check()
+
+	if err != nil {
+		report.Status = "error"
+		report.Error = err.Error()
+	} else {
+		report.Status = string(result.Status)
+		report.Pending = names(result.Pending)
+		report.Unknown = names(result.Unknown)
+		report.Mismatched = names(result.Mismatched)
+
+		if result.IsUpToDate() {
+			code = http.StatusOK
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+func names(migrations []*Migration) []string {
+	result := []string{}
+
+	for _, m := range migrations {
+		result = append(result, m.String())
+	}
+
+	return result
+}
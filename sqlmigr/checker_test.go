@@ -0,0 +1,289 @@
+package sqlmigr_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/prana/sqlmigr"
+)
+
+var _ = Describe("Checker", func() {
+	var (
+		checker *sqlmigr.Checker
+		dir     string
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "prana_checker")
+		Expect(err).To(BeNil())
+
+		conn := filepath.Join(dir, "prana.db")
+		db, err := sqlx.Open("sqlite3", conn)
+		Expect(err).To(BeNil())
+
+		checker = &sqlmigr.Checker{
+			FileSystem: parcello.Dir(dir),
+			DB:         db,
+		}
+
+		query := &bytes.Buffer{}
+		fmt.Fprintln(query, "CREATE TABLE migrations (")
+		fmt.Fprintln(query, " id          TEXT      NOT NULL PRIMARY KEY,")
+		fmt.Fprintln(query, " description TEXT      NOT NULL,")
+		fmt.Fprintln(query, " created_at  TIMESTAMP NOT NULL")
+		fmt.Fprintln(query, ");")
+
+		_, err = db.Exec(query.String())
+		Expect(err).To(BeNil())
+
+		path := filepath.Join(dir, "20060102150405_schema.sql")
+		Expect(ioutil.WriteFile(path, []byte{}, 0700)).To(Succeed())
+
+		insert := "INSERT INTO migrations(id, description, created_at) VALUES(?,?,?)"
+		_, err = db.Exec(insert, "20060102150405", "schema", time.Now())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		checker.DB.Close()
+	})
+
+	Describe("Check", func() {
+		It("returns up-to-date status", func() {
+			result, err := checker.Check()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Status).To(Equal(sqlmigr.StatusUpToDate))
+			Expect(result.IsUpToDate()).To(BeTrue())
+			Expect(result.Pending).To(BeEmpty())
+			Expect(result.Unknown).To(BeEmpty())
+			Expect(result.Mismatched).To(BeEmpty())
+		})
+
+		Context("when there are pending migrations", func() {
+			BeforeEach(func() {
+				path := filepath.Join(dir, "20070102150405_users.sql")
+				Expect(ioutil.WriteFile(path, []byte{}, 0700)).To(Succeed())
+			})
+
+			It("returns the pending migrations", func() {
+				result, err := checker.Check()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Status).To(Equal(sqlmigr.StatusPending))
+				Expect(result.Pending).To(HaveLen(1))
+				Expect(result.Pending[0].ID).To(Equal("20070102150405"))
+				Expect(result.Pending[0].Description).To(Equal("users"))
+			})
+		})
+
+		Context("when the database has unknown migrations", func() {
+			BeforeEach(func() {
+				insert := "INSERT INTO migrations(id, description, created_at) VALUES(?,?,?)"
+				_, err := checker.DB.Exec(insert, "20080102150405", "tables", time.Now())
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns the unknown migrations", func() {
+				result, err := checker.Check()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Status).To(Equal(sqlmigr.StatusUnknown))
+				Expect(result.Unknown).To(HaveLen(1))
+				Expect(result.Unknown[0].ID).To(Equal("20080102150405"))
+			})
+		})
+
+		Context("when the migration does not match", func() {
+			BeforeEach(func() {
+				old := filepath.Join(dir, "20060102150405_schema.sql")
+				new := filepath.Join(dir, "20060102150405_tables.sql")
+				Expect(os.Rename(old, new)).To(Succeed())
+			})
+
+			It("returns the mismatched migrations", func() {
+				result, err := checker.Check()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Status).To(Equal(sqlmigr.StatusMismatch))
+				Expect(result.Mismatched).To(HaveLen(1))
+				Expect(result.Mismatched[0].Description).To(Equal("schema"))
+			})
+		})
+
+		Context("when the migration checksum is tracked", func() {
+			BeforeEach(func() {
+				_, err := checker.DB.Exec("ALTER TABLE migrations ADD COLUMN checksum TEXT NOT NULL DEFAULT ''")
+				Expect(err).NotTo(HaveOccurred())
+
+				checksum := sha256.Sum256([]byte{})
+
+				_, err = checker.DB.Exec("UPDATE migrations SET checksum = ?", hex.EncodeToString(checksum[:]))
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns up-to-date status", func() {
+				result, err := checker.Check()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Status).To(Equal(sqlmigr.StatusUpToDate))
+			})
+
+			Context("when the migration file is changed", func() {
+				BeforeEach(func() {
+					path := filepath.Join(dir, "20060102150405_schema.sql")
+					Expect(ioutil.WriteFile(path, []byte("-- name: up\nSELECT 1;\n"), 0700)).To(Succeed())
+				})
+
+				It("returns the mismatched migrations", func() {
+					result, err := checker.Check()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.Status).To(Equal(sqlmigr.StatusMismatch))
+					Expect(result.Mismatched).To(HaveLen(1))
+					Expect(result.Mismatched[0].ID).To(Equal("20060102150405"))
+				})
+			})
+		})
+
+		Context("when the migration table does not exist", func() {
+			BeforeEach(func() {
+				_, err := checker.DB.Exec("DROP TABLE migrations")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns all migrations as pending", func() {
+				result, err := checker.Check()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Status).To(Equal(sqlmigr.StatusPending))
+				Expect(result.Pending).To(HaveLen(1))
+			})
+		})
+
+		Context("when the table is provided", func() {
+			BeforeEach(func() {
+				_, err := checker.DB.Exec("ALTER TABLE migrations RENAME TO users_migrations")
+				Expect(err).NotTo(HaveOccurred())
+
+				path := filepath.Join(dir, "20070102150405_users.sql")
+				Expect(ioutil.WriteFile(path, []byte{}, 0700)).To(Succeed())
+
+				checker.Table = "users_migrations"
+			})
+
+			It("compares the migrations of the table", func() {
+				result, err := checker.Check()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Status).To(Equal(sqlmigr.StatusPending))
+				Expect(result.Pending).To(HaveLen(1))
+				Expect(result.Pending[0].ID).To(Equal("20070102150405"))
+			})
+		})
+
+		Context("when the modules are provided", func() {
+			BeforeEach(func() {
+				module := &sqlmigr.Module{
+					Name:       "billing",
+					FileSystem: parcello.Dir(filepath.Join(dir, "billing")),
+				}
+
+				Expect(os.MkdirAll(filepath.Join(dir, "billing"), 0700)).To(Succeed())
+				Expect(sqlmigr.WriteModule(module)).To(Succeed())
+
+				path := filepath.Join(dir, "billing", "20070102150405_invoices.sql")
+				Expect(ioutil.WriteFile(path, []byte{}, 0700)).To(Succeed())
+
+				checker.Modules = []*sqlmigr.Module{module}
+			})
+
+			It("returns the pending migrations of the modules", func() {
+				result, err := checker.Check()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Status).To(Equal(sqlmigr.StatusPending))
+				Expect(result.Pending).To(HaveLen(1))
+				Expect(result.Pending[0].Description).To(Equal("invoices"))
+			})
+		})
+
+		Context("when the database is not available", func() {
+			BeforeEach(func() {
+				Expect(checker.DB.Close()).To(Succeed())
+			})
+
+			It("returns an error", func() {
+				result, err := checker.Check()
+				Expect(result).To(BeNil())
+				Expect(err).To(MatchError("sql: database is closed"))
+			})
+		})
+	})
+
+	Describe("CheckHandler", func() {
+		var handler *sqlmigr.CheckHandler
+
+		BeforeEach(func() {
+			handler = &sqlmigr.CheckHandler{Checker: checker}
+		})
+
+		It("responds with status OK", func() {
+			r := httptest.NewRequest("GET", "/ready", nil)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Get("Content-Type")).To(Equal("application/json"))
+
+			report := map[string]interface{}{}
+			Expect(json.NewDecoder(w.Body).Decode(&report)).To(Succeed())
+			Expect(report).To(HaveKeyWithValue("status", "up-to-date"))
+		})
+
+		Context("when there are pending migrations", func() {
+			BeforeEach(func() {
+				path := filepath.Join(dir, "20070102150405_users.sql")
+				Expect(ioutil.WriteFile(path, []byte{}, 0700)).To(Succeed())
+			})
+
+			It("responds with status service unavailable", func() {
+				r := httptest.NewRequest("GET", "/ready", nil)
+				w := httptest.NewRecorder()
+
+				handler.ServeHTTP(w, r)
+				Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+
+				report := map[string]interface{}{}
+				Expect(json.NewDecoder(w.Body).Decode(&report)).To(Succeed())
+				Expect(report).To(HaveKeyWithValue("status", "pending"))
+				Expect(report).To(HaveKeyWithValue("pending", ConsistOf("20070102150405_users")))
+			})
+		})
+
+		Context("when the check fails", func() {
+			BeforeEach(func() {
+				Expect(checker.DB.Close()).To(Succeed())
+			})
+
+			It("responds with the error", func() {
+				r := httptest.NewRequest("GET", "/ready", nil)
+				w := httptest.NewRecorder()
+
+				handler.ServeHTTP(w, r)
+				Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+
+				report := map[string]interface{}{}
+				Expect(json.NewDecoder(w.Body).Decode(&report)).To(Succeed())
+				Expect(report).To(HaveKeyWithValue("status", "error"))
+				Expect(report).To(HaveKeyWithValue("error", "sql: database is closed"))
+			})
+		})
+	})
+})
@@ -20,6 +20,9 @@ type Executor struct {
 	Runner MigrationRunner
 	// Generator generates a migration file.
 	Generator MigrationGenerator
+	// Table is the name of the table that tracks the applied migrations. It
+	// defaults to 'migrations'.
+	Table string
 }
 
 // Setup setups the current project for database migrations by creating
@@ -33,14 +36,17 @@ func (m *Executor) Setup() error {
 	}
 
 	up := &bytes.Buffer{}
-	fmt.Fprintln(up, "CREATE TABLE IF NOT EXISTS migrations (")
+	fmt.Fprintf(up, "CREATE TABLE IF NOT EXISTS %s (", m.table())
+	fmt.Fprintln(up)
 	fmt.Fprintln(up, " id          VARCHAR(15) NOT NULL PRIMARY KEY,")
 	fmt.Fprintln(up, " description TEXT        NOT NULL,")
-	fmt.Fprintln(up, " created_at  TIMESTAMP   NOT NULL")
+	fmt.Fprintln(up, " created_at  TIMESTAMP   NOT NULL,")
+	fmt.Fprintln(up, " checksum    VARCHAR(64) NOT NULL DEFAULT ''")
 	fmt.Fprintln(up, ");")
 	fmt.Fprintln(up)
 
-	down := bytes.NewBufferString("DROP TABLE IF EXISTS migrations;")
+	down := &bytes.Buffer{}
+	fmt.Fprintf(down, "DROP TABLE IF EXISTS %s;", m.table())
 	fmt.Fprintln(down)
 
 	content := &Content{
@@ -140,7 +146,7 @@ func (m *Executor) Revert(step int) (int, error) {
 		}
 
 		if err := m.Provider.Delete(migrations[index]); err != nil {
-			if IsNotExist(err) {
+			if IsTableNotExist(err, m.table()) {
 				err = nil
 			}
 			return reverted, err
@@ -163,6 +169,13 @@ func (m *Executor) Migrations() ([]*Migration, error) {
 	return m.Provider.Migrations()
 }
 
+func (m *Executor) table() string {
+	if m.Table == "" {
+		return table
+	}
+	return m.Table
+}
+
 func (m *Executor) logf(text string, args ...interface{}) {
 	if m.Logger != nil {
 		m.Logger.Infof(text, args...)
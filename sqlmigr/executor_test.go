@@ -55,7 +55,8 @@ var _ = Describe("Executor", func() {
 			fmt.Fprintln(up, "CREATE TABLE IF NOT EXISTS migrations (")
 			fmt.Fprintln(up, " id          VARCHAR(15) NOT NULL PRIMARY KEY,")
 			fmt.Fprintln(up, " description TEXT        NOT NULL,")
-			fmt.Fprintln(up, " created_at  TIMESTAMP   NOT NULL")
+			fmt.Fprintln(up, " created_at  TIMESTAMP   NOT NULL,")
+			fmt.Fprintln(up, " checksum    VARCHAR(64) NOT NULL DEFAULT ''")
 			fmt.Fprintln(up, ");")
 			fmt.Fprintln(up)
 			Expect(string(data)).To(Equal(up.String()))
@@ -65,6 +66,23 @@ var _ = Describe("Executor", func() {
 			Expect(string(data)).To(Equal("DROP TABLE IF EXISTS migrations;\n"))
 		})
 
+		Context("when the table is provided", func() {
+			It("setups the migrations successfully", func() {
+				executor.Table = "billing_migrations"
+				Expect(executor.Setup()).To(Succeed())
+
+				_, content := generator.WriteArgsForCall(0)
+
+				data, err := ioutil.ReadAll(content.UpCommand)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).To(HavePrefix("CREATE TABLE IF NOT EXISTS billing_migrations ("))
+
+				data, err = ioutil.ReadAll(content.DownCommand)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).To(Equal("DROP TABLE IF EXISTS billing_migrations;\n"))
+			})
+		})
+
 		Context("when the migration exists", func() {
 			It("does not setup the project", func() {
 				provider.ExistsReturns(true)
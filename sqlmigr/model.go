@@ -21,6 +21,7 @@ var (
 	format = "20060102150405"
 	min    = time.Date(1, time.January, 1970, 0, 0, 0, 0, time.UTC)
 	every  = "sql"
+	table  = "migrations"
 )
 
 // FileSystem provides with primitives to work with the underlying file system
@@ -84,6 +85,9 @@ type Migration struct {
 	Description string `db:"description"`
 	// CreatedAt returns the time of sqlmigr execution.
 	CreatedAt time.Time `db:"created_at"`
+	// Checksum is the SHA-256 checksum of the migration files. It is empty
+	// for the migrations applied before the checksums were tracked.
+	Checksum string `db:"checksum"`
 	// Drivers return all supported drivers
 	Drivers []string `db:"-"`
 }
@@ -151,17 +155,46 @@ func Parse(path string) (*Migration, error) {
 
 // IsNotExist reports if the error is because of migration table not exists
 func IsNotExist(err error) bool {
+	return IsTableNotExist(err, table)
+}
+
+// IsTableNotExist reports if the error is because a given table not exists
+func IsTableNotExist(err error, name string) bool {
 	msg := err.Error()
 
 	switch {
 	// SQLite
-	case msg == "no such table: migrations":
+	case msg == fmt.Sprintf("no such table: %s", name):
+		return true
+		// PostgreSQL
+	case msg == fmt.Sprintf(`pq: relation "%s" does not exist`, name):
+		return true
+		// MySQL
+	case strings.HasSuffix(msg, fmt.Sprintf("%s' doesn't exist", name)):
+		return true
+	default:
+		return false
+	}
+}
+
+// isMissingChecksum reports if the error is because the migration table was
+// created without the checksum column
+func isMissingChecksum(err error) bool {
+	msg := err.Error()
+
+	if !strings.Contains(msg, "checksum") {
+		return false
+	}
+
+	switch {
+	// SQLite
+	case strings.HasPrefix(msg, "no such column: "), strings.Contains(msg, " has no column named "):
 		return true
 		// PostgreSQL
-	case msg == `pq: relation "migrations" does not exist`:
+	case strings.HasPrefix(msg, `pq: column "`) && strings.HasSuffix(msg, " does not exist"):
 		return true
 		// MySQL
-	case strings.HasSuffix(msg, "migrations' doesn't exist"):
+	case strings.HasPrefix(msg, "Error 1054: Unknown column "):
 		return true
 	default:
 		return false
@@ -114,3 +114,16 @@ var _ = Describe("IsNotExist", func() {
 		})
 	})
 })
+
+var _ = Describe("IsTableNotExist", func() {
+	It("returns true for the given table", func() {
+		err := fmt.Errorf("no such table: billing_migrations")
+		Expect(sqlmigr.IsTableNotExist(err, "billing_migrations")).To(BeTrue())
+	})
+
+	It("returns false for another table", func() {
+		err := fmt.Errorf("no such table: users")
+		Expect(sqlmigr.IsTableNotExist(err, "migrations")).To(BeFalse())
+		Expect(sqlmigr.IsNotExist(err)).To(BeFalse())
+	})
+})
@@ -0,0 +1,261 @@
+package sqlmigr
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/apex/log"
+	"github.com/go-openapi/inflect"
+	"github.com/jmoiron/sqlx"
+)
+
+// definition is the name of the file that marks a directory as module
+const definition = "module.json"
+
+// Module represents a named group of migrations that is kept in its own
+// directory and tracked in its own table.
+type Module struct {
+	// Name is the name of the module.
+	Name string `json:"-"`
+	// Table is the name of the table that tracks the applied migrations of
+	// the module. It defaults to '<name>_migrations'.
+	Table string `json:"table,omitempty"`
+	// Depends are the names of the modules that should be migrated before
+	// this one.
+	Depends []string `json:"depends,omitempty"`
+	// FileSystem represents the module directory file system.
+	FileSystem FileSystem `json:"-"`
+}
+
+// IsModule returns true if a given directory contains a 'module.json' file.
+// The other directories are not modules.
+func IsModule(fileSystem FileSystem, dir string) bool {
+	file, err := fileSystem.OpenFile(filepath.Join(dir, definition), os.O_RDONLY, 0)
+	if err != nil {
+		return false
+	}
+
+	file.Close()
+	return true
+}
+
+// ReadModule reads a module from its directory. The dependencies and the
+// tracking table can be declared in an optional 'module.json' file.
+func ReadModule(name string, fileSystem FileSystem) (module *Module, err error) {
+	module = &Module{
+		Name:       name,
+		FileSystem: fileSystem,
+	}
+
+	file, err := fileSystem.OpenFile(definition, os.O_RDONLY, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return module, err
+	}
+
+	defer func() {
+		if ioErr := file.Close(); err == nil {
+			err = ioErr
+		}
+	}()
+
+	if err = json.NewDecoder(file).Decode(module); err != nil {
+		return nil, fmt.Errorf("module '%s' has an invalid definition: %v", name, err)
+	}
+
+	return module, nil
+}
+
+// WriteModule writes the 'module.json' file of a module, so its directory is
+// discovered as module.
+func WriteModule(module *Module) (err error) {
+	file, err := module.FileSystem.OpenFile(definition, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if ioErr := file.Close(); err == nil {
+			err = ioErr
+		}
+	}()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(module)
+}
+
+// TableName returns the name of the table that tracks the applied migrations
+// of the module.
+func (m *Module) TableName() string {
+	if m.Table == "" {
+		return fmt.Sprintf("%s_%s", inflect.Underscore(m.Name), table)
+	}
+	return m.Table
+}
+
+// ModuleExecutor runs the migrations of a group of modules in the order of
+// their dependencies.
+type ModuleExecutor struct {
+	// Logger logs each execution step
+	Logger log.Interface
+	// DB is a client to underlying database.
+	DB *sqlx.DB
+	// Modules are all modules of the project.
+	Modules []*Module
+}
+
+// Executor returns the migration executor of a given module.
+func (e *ModuleExecutor) Executor(name string) (*Executor, error) {
+	module, err := e.module(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.executor(module), nil
+}
+
+// Order returns the given modules and their dependencies in the order in
+// which they should be migrated. If no names are provided, it returns all
+// modules.
+func (e *ModuleExecutor) Order(names ...string) ([]*Module, error) {
+	if len(names) == 0 {
+		for _, module := range e.Modules {
+			names = append(names, module.Name)
+		}
+	}
+
+	var (
+		ordered []*Module
+		visit   func(name string, path []string) error
+	)
+
+	state := make(map[string]int)
+
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("module '%s' has a circular dependency on '%s'", path[len(path)-1], name)
+		case 2:
+			return nil
+		}
+
+		module, err := e.module(name)
+		if err != nil {
+			return err
+		}
+
+		state[name] = 1
+
+		for _, dependency := range module.Depends {
+			if err := visit(dependency, append(path, name)); err != nil {
+				return err
+			}
+		}
+
+		state[name] = 2
+		ordered = append(ordered, module)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, []string{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+// Run runs all pending migrations of the dependencies of a given module and
+// then the pending migrations of the module for given count. If the count is
+// negative number, it will execute all pending migrations of the module.
+func (e *ModuleExecutor) Run(name string, step int) (int, error) {
+	run := 0
+
+	modules, err := e.Order(name)
+	if err != nil {
+		return run, err
+	}
+
+	for _, module := range modules {
+		count := -1
+
+		if module.Name == name {
+			count = step
+		}
+
+		e.logf("Running migrations of module '%s'", module.Name)
+
+		n, err := e.executor(module).Run(count)
+		run = run + n
+
+		if err != nil {
+			return run, err
+		}
+	}
+
+	return run, nil
+}
+
+// RunAll runs all pending migrations of all modules.
+func (e *ModuleExecutor) RunAll() (int, error) {
+	run := 0
+
+	modules, err := e.Order()
+	if err != nil {
+		return run, err
+	}
+
+	for _, module := range modules {
+		e.logf("Running migrations of module '%s'", module.Name)
+
+		n, err := e.executor(module).RunAll()
+		run = run + n
+
+		if err != nil {
+			return run, err
+		}
+	}
+
+	return run, nil
+}
+
+func (e *ModuleExecutor) module(name string) (*Module, error) {
+	for _, module := range e.Modules {
+		if module.Name == name {
+			return module, nil
+		}
+	}
+
+	return nil, fmt.Errorf("module '%s' not found", name)
+}
+
+func (e *ModuleExecutor) executor(module *Module) *Executor {
+	return &Executor{
+		Logger: e.Logger,
+		Table:  module.TableName(),
+		Provider: &Provider{
+			FileSystem: module.FileSystem,
+			DB:         e.DB,
+			Table:      module.TableName(),
+		},
+		Runner: &Runner{
+			FileSystem: module.FileSystem,
+			DB:         e.DB,
+		},
+		Generator: &Generator{
+			FileSystem: module.FileSystem,
+		},
+	}
+}
+
+func (e *ModuleExecutor) logf(text string, args ...interface{}) {
+	if e.Logger != nil {
+		e.Logger.Infof(text, args...)
+	}
+}
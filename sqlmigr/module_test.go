@@ -0,0 +1,266 @@
+package sqlmigr_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/prana/sqlmigr"
+)
+
+var _ = Describe("Module", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "prana_module")
+		Expect(err).To(BeNil())
+	})
+
+	Describe("ReadModule", func() {
+		It("reads the module successfully", func() {
+			module, err := sqlmigr.ReadModule("billing", parcello.Dir(dir))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(module.Name).To(Equal("billing"))
+			Expect(module.Depends).To(BeEmpty())
+			Expect(module.TableName()).To(Equal("billing_migrations"))
+		})
+
+		Context("when the module has a definition", func() {
+			BeforeEach(func() {
+				definition := []byte(`{"table": "billing_versions", "depends": ["accounts"]}`)
+				path := filepath.Join(dir, "module.json")
+				Expect(ioutil.WriteFile(path, definition, 0700)).To(Succeed())
+			})
+
+			It("reads the module successfully", func() {
+				module, err := sqlmigr.ReadModule("billing", parcello.Dir(dir))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(module.Name).To(Equal("billing"))
+				Expect(module.Depends).To(ConsistOf("accounts"))
+				Expect(module.TableName()).To(Equal("billing_versions"))
+			})
+		})
+
+		Context("when the module definition is not valid", func() {
+			BeforeEach(func() {
+				path := filepath.Join(dir, "module.json")
+				Expect(ioutil.WriteFile(path, []byte("{"), 0700)).To(Succeed())
+			})
+
+			It("returns an error", func() {
+				module, err := sqlmigr.ReadModule("billing", parcello.Dir(dir))
+				Expect(module).To(BeNil())
+				Expect(err).To(MatchError("module 'billing' has an invalid definition: unexpected EOF"))
+			})
+		})
+	})
+
+	Describe("IsModule", func() {
+		It("returns false", func() {
+			Expect(sqlmigr.IsModule(parcello.Dir(dir), "/")).To(BeFalse())
+		})
+
+		Context("when the directory has a definition", func() {
+			BeforeEach(func() {
+				path := filepath.Join(dir, "module.json")
+				Expect(ioutil.WriteFile(path, []byte("{}"), 0700)).To(Succeed())
+			})
+
+			It("returns true", func() {
+				Expect(sqlmigr.IsModule(parcello.Dir(dir), "/")).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("WriteModule", func() {
+		It("writes the module definition", func() {
+			module := &sqlmigr.Module{
+				Name:       "billing",
+				Depends:    []string{"accounts"},
+				FileSystem: parcello.Dir(filepath.Join(dir, "billing")),
+			}
+
+			Expect(sqlmigr.WriteModule(module)).To(Succeed())
+			Expect(sqlmigr.IsModule(parcello.Dir(dir), "billing")).To(BeTrue())
+
+			module, err := sqlmigr.ReadModule("billing", module.FileSystem)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(module.Depends).To(ConsistOf("accounts"))
+			Expect(module.TableName()).To(Equal("billing_migrations"))
+		})
+	})
+
+	Describe("ModuleExecutor", func() {
+		var (
+			executor *sqlmigr.ModuleExecutor
+			db       *sqlx.DB
+		)
+
+		module := func(name string, depends ...string) *sqlmigr.Module {
+			path := filepath.Join(dir, name)
+			Expect(os.MkdirAll(path, 0700)).To(Succeed())
+
+			script := &bytes.Buffer{}
+			fmt.Fprintln(script, "-- name: up")
+			fmt.Fprintf(script, "CREATE TABLE %s (id INT);", name)
+			fmt.Fprintln(script)
+			fmt.Fprintln(script, "-- name: down")
+			fmt.Fprintf(script, "DROP TABLE %s;", name)
+			fmt.Fprintln(script)
+
+			file := filepath.Join(path, "20060102150405_tables.sql")
+			Expect(ioutil.WriteFile(file, script.Bytes(), 0700)).To(Succeed())
+
+			return &sqlmigr.Module{
+				Name:       name,
+				Depends:    depends,
+				FileSystem: parcello.Dir(path),
+			}
+		}
+
+		BeforeEach(func() {
+			var err error
+
+			db, err = sqlx.Open("sqlite3", filepath.Join(dir, "prana.db"))
+			Expect(err).To(BeNil())
+
+			executor = &sqlmigr.ModuleExecutor{
+				DB: db,
+				Modules: []*sqlmigr.Module{
+					module("billing", "accounts"),
+					module("accounts"),
+					module("reports", "billing", "accounts"),
+				},
+			}
+
+			for _, module := range executor.Modules {
+				migrator, err := executor.Executor(module.Name)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(migrator.Setup()).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			Expect(db.Close()).To(Succeed())
+		})
+
+		Describe("Order", func() {
+			It("returns the modules in order of their dependencies", func() {
+				modules, err := executor.Order()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(modules).To(HaveLen(3))
+				Expect(modules[0].Name).To(Equal("accounts"))
+				Expect(modules[1].Name).To(Equal("billing"))
+				Expect(modules[2].Name).To(Equal("reports"))
+			})
+
+			It("returns the module with its dependencies", func() {
+				modules, err := executor.Order("billing")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(modules).To(HaveLen(2))
+				Expect(modules[0].Name).To(Equal("accounts"))
+				Expect(modules[1].Name).To(Equal("billing"))
+			})
+
+			Context("when the dependencies are circular", func() {
+				BeforeEach(func() {
+					executor.Modules[1].Depends = []string{"reports"}
+				})
+
+				It("returns an error", func() {
+					modules, err := executor.Order("billing")
+					Expect(modules).To(BeEmpty())
+					Expect(err).To(MatchError("module 'reports' has a circular dependency on 'billing'"))
+				})
+			})
+
+			Context("when the dependency is not found", func() {
+				BeforeEach(func() {
+					executor.Modules[1].Depends = []string{"users"}
+				})
+
+				It("returns an error", func() {
+					modules, err := executor.Order("billing")
+					Expect(modules).To(BeEmpty())
+					Expect(err).To(MatchError("module 'users' not found"))
+				})
+			})
+		})
+
+		Describe("Executor", func() {
+			Context("when the module is not found", func() {
+				It("returns an error", func() {
+					migrator, err := executor.Executor("users")
+					Expect(migrator).To(BeNil())
+					Expect(err).To(MatchError("module 'users' not found"))
+				})
+			})
+		})
+
+		Describe("Run", func() {
+			It("runs the module and its dependencies", func() {
+				count, err := executor.Run("billing", -1)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(count).To(Equal(4))
+
+				for _, table := range []string{"accounts", "billing"} {
+					count := 0
+					query := fmt.Sprintf("SELECT count(*) FROM %s_migrations", table)
+					Expect(db.Get(&count, query)).To(Succeed())
+					Expect(count).To(Equal(2))
+				}
+
+				count = 0
+				Expect(db.Get(&count, "SELECT count(*) FROM sqlite_master WHERE name = 'reports'")).To(Succeed())
+				Expect(count).To(BeZero())
+			})
+
+			Context("when the count is provided", func() {
+				It("runs all migrations of the dependencies", func() {
+					count, err := executor.Run("billing", 1)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(count).To(Equal(3))
+				})
+			})
+		})
+
+		Describe("RunAll", func() {
+			It("runs all modules", func() {
+				count, err := executor.RunAll()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(count).To(Equal(6))
+
+				migrator, err := executor.Executor("reports")
+				Expect(err).NotTo(HaveOccurred())
+
+				migrations, err := migrator.Migrations()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(migrations).To(HaveLen(2))
+
+				for _, migration := range migrations {
+					Expect(migration.CreatedAt.IsZero()).To(BeFalse())
+				}
+			})
+
+			Context("when the database is not available", func() {
+				BeforeEach(func() {
+					Expect(db.Close()).To(Succeed())
+				})
+
+				It("returns an error", func() {
+					count, err := executor.RunAll()
+					Expect(count).To(BeZero())
+					Expect(err).To(MatchError("sql: database is closed"))
+				})
+			})
+		})
+	})
+})
@@ -58,3 +58,25 @@ func Ftable(w io.Writer, migrations []*Migration) {
 
 	fmt.Fprintln(w, table)
 }
+
+// Ftenants prints the results of tenant schema migrations as table
+func Ftenants(w io.Writer, results TenantResults) {
+	table := uitable.New()
+	table.MaxColWidth = 80
+
+	table.AddRow("SCHEMA", "APPLIED", "STATUS", "ERROR")
+
+	for _, result := range results {
+		status := color.GreenString("migrated")
+		reason := "--"
+
+		if result.Err != nil {
+			status = color.RedString("failed")
+			reason = result.Err.Error()
+		}
+
+		table.AddRow(result.Schema, result.Count, status, reason)
+	}
+
+	fmt.Fprintln(w, table)
+}
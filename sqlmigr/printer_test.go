@@ -2,6 +2,7 @@ package sqlmigr_test
 
 import (
 	"bytes"
+	"fmt"
 	"time"
 
 	"github.com/apex/log"
@@ -86,4 +87,23 @@ var _ = Describe("Printer", func() {
 			})
 		})
 	})
+	Context("Ftenants", func() {
+		It("prints the results", func() {
+			results := sqlmigr.TenantResults{
+				{Schema: "tenant_a", Count: 2},
+				{Schema: "tenant_b", Err: fmt.Errorf("oh no")},
+			}
+
+			w := &bytes.Buffer{}
+			sqlmigr.Ftenants(w, results)
+
+			content := w.String()
+			Expect(content).To(ContainSubstring("SCHEMA"))
+			Expect(content).To(ContainSubstring("tenant_a"))
+			Expect(content).To(ContainSubstring("migrated"))
+			Expect(content).To(ContainSubstring("tenant_b"))
+			Expect(content).To(ContainSubstring("failed"))
+			Expect(content).To(ContainSubstring("oh no"))
+		})
+	})
 })
@@ -2,7 +2,10 @@ package sqlmigr
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
@@ -18,6 +21,9 @@ type Provider struct {
 	FileSystem FileSystem
 	// DB is a client to underlying database.
 	DB *sqlx.DB
+	// Table is the name of the table that tracks the applied migrations. It
+	// defaults to 'migrations'.
+	Table string
 }
 
 // Migrations returns the project migrations.
@@ -37,8 +43,19 @@ func (m *Provider) Migrations() ([]*Migration, error) {
 
 func (m *Provider) files() ([]*Migration, error) {
 	local := []*Migration{}
+	paths := map[*Migration][]string{}
+	root := true
 
 	err := m.FileSystem.Walk("/", func(path string, info os.FileInfo, err error) error {
+		if info != nil && info.IsDir() {
+			// the modules track their migrations on their own
+			if !root && IsModule(m.FileSystem, path) {
+				return filepath.SkipDir
+			}
+
+			root = false
+		}
+
 		if ferr := m.filter(info); ferr != nil {
 			if ferr.Error() == "skip" {
 				ferr = nil
@@ -60,11 +77,13 @@ func (m *Provider) files() ([]*Migration, error) {
 			if prev := local[index]; migration.Equal(prev) {
 				prev.Drivers = append(prev.Drivers, migration.Drivers...)
 				local[index] = prev
+				paths[prev] = append(paths[prev], path)
 				return nil
 			}
 		}
 
 		local = append(local, migration)
+		paths[migration] = []string{path}
 		return nil
 	})
 
@@ -72,9 +91,45 @@ func (m *Provider) files() ([]*Migration, error) {
 		return []*Migration{}, err
 	}
 
+	for _, migration := range local {
+		if migration.Checksum, err = m.checksum(paths[migration]); err != nil {
+			return []*Migration{}, err
+		}
+	}
+
 	return local, nil
 }
 
+// checksum returns the checksum of the migration files that are executed for
+// the current driver
+func (m *Provider) checksum(paths []string) (string, error) {
+	hash := sha256.New()
+
+	for _, path := range paths {
+		if err := m.digest(hash, path); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (m *Provider) digest(w io.Writer, name string) (err error) {
+	file, err := m.FileSystem.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if ioErr := file.Close(); err == nil {
+			err = ioErr
+		}
+	}()
+
+	_, err = io.Copy(w, file)
+	return err
+}
+
 func (m *Provider) filter(info os.FileInfo) error {
 	skip := fmt.Errorf("skip")
 
@@ -106,14 +161,29 @@ func (m *Provider) supported(drivers []string) bool {
 }
 
 func (m *Provider) query() ([]*Migration, error) {
+	remote, err := m.selectColumns("id, description, created_at, checksum")
+
+	// the tables created before the checksums were tracked
+	if err != nil && isMissingChecksum(err) {
+		remote, err = m.selectColumns("id, description, created_at")
+	}
+
+	if err != nil && !IsTableNotExist(err, m.table()) {
+		return []*Migration{}, err
+	}
+
+	return remote, nil
+}
+
+func (m *Provider) selectColumns(columns string) ([]*Migration, error) {
 	query := &bytes.Buffer{}
-	query.WriteString("SELECT id, description, created_at ")
-	query.WriteString("FROM migrations ")
+	fmt.Fprintf(query, "SELECT %s ", columns)
+	fmt.Fprintf(query, "FROM %s ", m.table())
 	query.WriteString("ORDER BY id ASC")
 
 	remote := []*Migration{}
 
-	if err := m.DB.Select(&remote, query.String()); err != nil && !IsNotExist(err) {
+	if err := m.DB.Select(&remote, query.String()); err != nil {
 		return []*Migration{}, err
 	}
 
@@ -125,21 +195,28 @@ func (m *Provider) Insert(item *Migration) error {
 	item.CreatedAt = time.Now()
 
 	builder := &bytes.Buffer{}
-	builder.WriteString("INSERT INTO migrations(id, description, created_at) ")
-	builder.WriteString("VALUES (?, ?, ?)")
+	fmt.Fprintf(builder, "INSERT INTO %s(id, description, created_at, checksum) ", m.table())
+	builder.WriteString("VALUES (?, ?, ?, ?)")
 
 	query := m.DB.Rebind(builder.String())
-	if _, err := m.DB.Exec(query, item.ID, item.Description, item.CreatedAt); err != nil {
-		return err
+	_, err := m.DB.Exec(query, item.ID, item.Description, item.CreatedAt, item.Checksum)
+
+	// the tables created before the checksums were tracked get the column
+	if err != nil && isMissingChecksum(err) {
+		alter := fmt.Sprintf("ALTER TABLE %s ADD COLUMN checksum VARCHAR(64) NOT NULL DEFAULT ''", m.table())
+
+		if _, err = m.DB.Exec(alter); err == nil {
+			_, err = m.DB.Exec(query, item.ID, item.Description, item.CreatedAt, item.Checksum)
+		}
 	}
 
-	return nil
+	return err
 }
 
 // Delete deletes applied sqlmigr item from sqlmigrs table.
 func (m *Provider) Delete(item *Migration) error {
 	builder := &bytes.Buffer{}
-	builder.WriteString("DELETE FROM migrations ")
+	fmt.Fprintf(builder, "DELETE FROM %s ", m.table())
 	builder.WriteString("WHERE id = ?")
 
 	query := m.DB.Rebind(builder.String())
@@ -153,14 +230,22 @@ func (m *Provider) Delete(item *Migration) error {
 // Exists returns true if the sqlmigr exists
 func (m *Provider) Exists(item *Migration) bool {
 	count := 0
+	query := fmt.Sprintf("SELECT count(id) FROM %s WHERE id = ?", m.table())
 
-	if err := m.DB.Get(&count, "SELECT count(id) FROM migrations WHERE id = ?", item.ID); err != nil {
+	if err := m.DB.Get(&count, m.DB.Rebind(query), item.ID); err != nil {
 		return false
 	}
 
 	return count == 1
 }
 
+func (m *Provider) table() string {
+	if m.Table == "" {
+		return table
+	}
+	return m.Table
+}
+
 func (m *Provider) merge(remote, local []*Migration) ([]*Migration, error) {
 	result := local
 
@@ -120,6 +120,22 @@ var _ = Describe("Provider", func() {
 			Expect(items[1].Description).To(Equal("trigger"))
 		})
 
+		It("adds the checksum column and records the checksum", func() {
+			item := sqlmigr.Migration{
+				ID:          "20070102150405",
+				Description: "trigger",
+				Checksum:    "cafe",
+			}
+
+			Expect(provider.Insert(&item)).To(Succeed())
+
+			checksum := ""
+			query := "SELECT checksum FROM migrations WHERE id = '20070102150405'"
+
+			Expect(provider.DB.Get(&checksum, query)).To(Succeed())
+			Expect(checksum).To(Equal("cafe"))
+		})
+
 		Context("when the database is not available", func() {
 			JustBeforeEach(func() {
 				Expect(provider.DB.Close()).To(Succeed())
@@ -190,6 +206,68 @@ var _ = Describe("Provider", func() {
 			Expect(items[1].Drivers).To(ContainElement("sqlite3"))
 		})
 
+		Context("when the directory has sub-directories", func() {
+			var path string
+
+			BeforeEach(func() {
+				path = filepath.Join(dir, "billing")
+				Expect(os.MkdirAll(path, 0700)).To(Succeed())
+
+				file := filepath.Join(path, "20070102150405_setup.sql")
+				Expect(ioutil.WriteFile(file, []byte{}, 0700)).To(Succeed())
+			})
+
+			It("returns their migrations", func() {
+				items, err := provider.Migrations()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(items).To(HaveLen(2))
+				Expect(items[0].ID).To(Equal("20060102150405"))
+				Expect(items[1].ID).To(Equal("20070102150405"))
+			})
+
+			Context("when the sub-directory is a module", func() {
+				BeforeEach(func() {
+					file := filepath.Join(path, "module.json")
+					Expect(ioutil.WriteFile(file, []byte("{}"), 0700)).To(Succeed())
+				})
+
+				It("skips its migrations", func() {
+					items, err := provider.Migrations()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(items).To(HaveLen(1))
+					Expect(items[0].ID).To(Equal("20060102150405"))
+				})
+			})
+		})
+
+		Context("when the table is provided", func() {
+			JustBeforeEach(func() {
+				_, err := provider.DB.Exec("ALTER TABLE migrations RENAME TO billing_migrations")
+				Expect(err).NotTo(HaveOccurred())
+				provider.Table = "billing_migrations"
+			})
+
+			It("returns the sqlmigrs successfully", func() {
+				items, err := provider.Migrations()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(items).To(HaveLen(1))
+				Expect(items[0].ID).To(Equal("20060102150405"))
+				Expect(items[0].CreatedAt.IsZero()).To(BeFalse())
+			})
+
+			It("inserts a sqlmigr item successfully", func() {
+				item := sqlmigr.Migration{
+					ID:          "20070102150405",
+					Description: "trigger",
+				}
+
+				Expect(provider.Insert(&item)).To(Succeed())
+				Expect(provider.Exists(&item)).To(BeTrue())
+				Expect(provider.Delete(&item)).To(Succeed())
+				Expect(provider.Exists(&item)).To(BeFalse())
+			})
+		})
+
 		Context("when the directory does not exist", func() {
 			JustBeforeEach(func() {
 				path := dir + "_old"
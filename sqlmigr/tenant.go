@@ -0,0 +1,174 @@
+package sqlmigr
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/apex/log"
+	"github.com/jmoiron/sqlx"
+)
+
+// Connector opens a connection to the database of a given tenant schema.
+type Connector func(schema string) (*sqlx.DB, error)
+
+// SearchPath returns a connector that opens PostgreSQL connections whose
+// search_path is set to the tenant schema.
+func SearchPath(conn string) Connector {
+	return func(schema string) (*sqlx.DB, error) {
+		uri, err := url.Parse(conn)
+		if err != nil {
+			return nil, err
+		}
+
+		query := uri.Query()
+		query.Set("search_path", schema)
+		uri.RawQuery = query.Encode()
+
+		return sqlx.Open("postgres", uri.String())
+	}
+}
+
+// Schemas returns the tenant schemas returned by a given query.
+func Schemas(db *sqlx.DB, query string) ([]string, error) {
+	schemas := []string{}
+
+	if err := db.Select(&schemas, query); err != nil {
+		return []string{}, err
+	}
+
+	return schemas, nil
+}
+
+// TenantResult is the result of migrating a tenant schema.
+type TenantResult struct {
+	// Schema is the tenant schema.
+	Schema string
+	// Count is the number of applied migrations.
+	Count int
+	// Err is the error that stopped the migration of the schema.
+	Err error
+}
+
+// TenantResults are the results of migrating many tenant schemas.
+type TenantResults []*TenantResult
+
+// Failed returns the schemas whose migration failed. Each schema tracks its
+// migrations, so running them again resumes from the failed migration.
+func (r TenantResults) Failed() []string {
+	schemas := []string{}
+
+	for _, result := range r {
+		if result.Err != nil {
+			schemas = append(schemas, result.Schema)
+		}
+	}
+
+	return schemas
+}
+
+// Err returns an error if the migration of any schema failed.
+func (r TenantResults) Err() error {
+	if failed := r.Failed(); len(failed) > 0 {
+		return fmt.Errorf("%d of %d tenant schemas failed to migrate", len(failed), len(r))
+	}
+
+	return nil
+}
+
+// TenantExecutor applies the pending migrations to many tenant schemas of
+// identical layout. The applied migrations are tracked in each schema.
+type TenantExecutor struct {
+	// Logger logs each execution step
+	Logger log.Interface
+	// FileSystem represents the project directory file system.
+	FileSystem FileSystem
+	// Connector opens a connection to the database of a tenant schema.
+	Connector Connector
+	// Concurrency is the maximum number of schemas migrated at the same time.
+	// It defaults to 1.
+	Concurrency int
+}
+
+// Run runs the pending migrations of each schema for given count. If the
+// count is negative number, it will execute all pending migrations. The
+// results are in the order of the schemas.
+func (e *TenantExecutor) Run(schemas []string, step int) TenantResults {
+	results := make(TenantResults, len(schemas))
+	concurrency := e.Concurrency
+
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		group     sync.WaitGroup
+		semaphore = make(chan struct{}, concurrency)
+	)
+
+	for index, schema := range schemas {
+		group.Add(1)
+		semaphore <- struct{}{}
+
+		go func(index int, schema string) {
+			defer func() {
+				<-semaphore
+				group.Done()
+			}()
+
+			results[index] = e.run(schema, step)
+		}(index, schema)
+	}
+
+	group.Wait()
+	return results
+}
+
+// RunAll runs all pending migrations of each schema.
+func (e *TenantExecutor) RunAll(schemas []string) TenantResults {
+	return e.Run(schemas, -1)
+}
+
+func (e *TenantExecutor) run(schema string, step int) *TenantResult {
+	result := &TenantResult{Schema: schema}
+
+	e.logf("Migrating schema '%s'", schema)
+
+	db, err := e.Connector(schema)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	defer func() {
+		if ioErr := db.Close(); result.Err == nil {
+			result.Err = ioErr
+		}
+	}()
+
+	executor := &Executor{
+		Logger: e.Logger,
+		Provider: &Provider{
+			FileSystem: e.FileSystem,
+			DB:         db,
+		},
+		Runner: &Runner{
+			FileSystem: e.FileSystem,
+			DB:         db,
+		},
+	}
+
+	result.Count, result.Err = executor.Run(step)
+
+	if result.Err != nil {
+		e.logf("Migrating schema '%s' failed: %v", schema, result.Err)
+	}
+
+	return result
+}
+
+func (e *TenantExecutor) logf(text string, args ...interface{}) {
+	if e.Logger != nil {
+		e.Logger.Infof(text, args...)
+	}
+}
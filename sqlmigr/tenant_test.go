@@ -0,0 +1,201 @@
+package sqlmigr_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/prana/sqlmigr"
+)
+
+var _ = Describe("TenantExecutor", func() {
+	var (
+		executor *sqlmigr.TenantExecutor
+		dir      string
+		mu       sync.Mutex
+		failing  map[string]bool
+	)
+
+	count := func(schema string) int {
+		db, err := sqlx.Open("sqlite3", filepath.Join(dir, schema+".db"))
+		Expect(err).NotTo(HaveOccurred())
+		defer db.Close()
+
+		count := 0
+		Expect(db.Get(&count, "SELECT count(*) FROM migrations")).To(Succeed())
+		return count
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "prana_tenant")
+		Expect(err).To(BeNil())
+
+		migrationDir := filepath.Join(dir, "migration")
+		Expect(os.MkdirAll(migrationDir, 0700)).To(Succeed())
+
+		failing = map[string]bool{}
+
+		setup := &sqlmigr.Executor{
+			Generator: &sqlmigr.Generator{
+				FileSystem: parcello.Dir(migrationDir),
+			},
+		}
+
+		Expect(setup.Setup()).To(Succeed())
+
+		script := &bytes.Buffer{}
+		fmt.Fprintln(script, "-- name: up")
+		fmt.Fprintln(script, "CREATE TABLE users (id INT);")
+		fmt.Fprintln(script, "-- name: down")
+		fmt.Fprintln(script, "DROP TABLE users;")
+
+		path := filepath.Join(migrationDir, "20060102150405_users.sql")
+		Expect(ioutil.WriteFile(path, script.Bytes(), 0700)).To(Succeed())
+
+		executor = &sqlmigr.TenantExecutor{
+			FileSystem:  parcello.Dir(migrationDir),
+			Concurrency: 2,
+			Connector: func(schema string) (*sqlx.DB, error) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				if failing[schema] {
+					return nil, fmt.Errorf("schema '%s' is not available", schema)
+				}
+
+				return sqlx.Open("sqlite3", filepath.Join(dir, schema+".db"))
+			},
+		}
+	})
+
+	It("migrates all schemas", func() {
+		schemas := []string{"tenant_a", "tenant_b", "tenant_c"}
+
+		results := executor.RunAll(schemas)
+		Expect(results).To(HaveLen(3))
+		Expect(results.Err()).To(Succeed())
+		Expect(results.Failed()).To(BeEmpty())
+
+		for index, result := range results {
+			Expect(result.Schema).To(Equal(schemas[index]))
+			Expect(result.Count).To(Equal(2))
+			Expect(result.Err).To(BeNil())
+			Expect(count(result.Schema)).To(Equal(2))
+		}
+	})
+
+	Context("when the count is provided", func() {
+		It("migrates each schema for given count", func() {
+			results := executor.Run([]string{"tenant_a", "tenant_b"}, 1)
+			Expect(results.Err()).To(Succeed())
+
+			for _, result := range results {
+				Expect(result.Count).To(Equal(1))
+				Expect(count(result.Schema)).To(Equal(1))
+			}
+		})
+	})
+
+	Context("when a schema fails", func() {
+		BeforeEach(func() {
+			failing["tenant_b"] = true
+		})
+
+		It("migrates the other schemas", func() {
+			results := executor.RunAll([]string{"tenant_a", "tenant_b", "tenant_c"})
+			Expect(results.Err()).To(MatchError("1 of 3 tenant schemas failed to migrate"))
+			Expect(results.Failed()).To(ConsistOf("tenant_b"))
+
+			Expect(results[1].Err).To(MatchError("schema 'tenant_b' is not available"))
+			Expect(results[1].Count).To(BeZero())
+
+			Expect(count("tenant_a")).To(Equal(2))
+			Expect(count("tenant_c")).To(Equal(2))
+		})
+
+		It("resumes the failed schemas", func() {
+			results := executor.RunAll([]string{"tenant_a", "tenant_b"})
+			Expect(results.Failed()).To(ConsistOf("tenant_b"))
+
+			mu.Lock()
+			failing["tenant_b"] = false
+			mu.Unlock()
+
+			results = executor.RunAll([]string{"tenant_a", "tenant_b"})
+			Expect(results.Err()).To(Succeed())
+			Expect(results[0].Count).To(BeZero())
+			Expect(results[1].Count).To(Equal(2))
+		})
+	})
+
+	Context("when the migration fails", func() {
+		BeforeEach(func() {
+			script := &bytes.Buffer{}
+			fmt.Fprintln(script, "-- name: up")
+			fmt.Fprintln(script, "CREATE TABLE users (id INT);")
+			fmt.Fprintln(script, "-- name: down")
+			fmt.Fprintln(script, "DROP TABLE users;")
+
+			path := filepath.Join(dir, "migration", "20070102150405_tables.sql")
+			Expect(ioutil.WriteFile(path, script.Bytes(), 0700)).To(Succeed())
+		})
+
+		It("returns the number of applied migrations", func() {
+			results := executor.RunAll([]string{"tenant_a"})
+			Expect(results[0].Count).To(Equal(2))
+			Expect(results[0].Err).To(MatchError("table users already exists: CREATE TABLE users (id INT);"))
+			Expect(count("tenant_a")).To(Equal(2))
+		})
+	})
+
+	Describe("Schemas", func() {
+		It("returns the schemas", func() {
+			db, err := sqlx.Open("sqlite3", filepath.Join(dir, "prana.db"))
+			Expect(err).NotTo(HaveOccurred())
+			defer db.Close()
+
+			_, err = db.Exec("CREATE TABLE tenants (name TEXT)")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = db.Exec("INSERT INTO tenants VALUES ('tenant_a'), ('tenant_b')")
+			Expect(err).NotTo(HaveOccurred())
+
+			schemas, err := sqlmigr.Schemas(db, "SELECT name FROM tenants ORDER BY name")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(schemas).To(Equal([]string{"tenant_a", "tenant_b"}))
+		})
+
+		Context("when the query fails", func() {
+			It("returns an error", func() {
+				db, err := sqlx.Open("sqlite3", filepath.Join(dir, "prana.db"))
+				Expect(err).NotTo(HaveOccurred())
+				defer db.Close()
+
+				schemas, err := sqlmigr.Schemas(db, "SELECT name FROM tenants")
+				Expect(schemas).To(BeEmpty())
+				Expect(err).To(MatchError("no such table: tenants"))
+			})
+		})
+	})
+
+	Describe("SearchPath", func() {
+		It("sets the search path of the connection", func() {
+			connector := sqlmigr.SearchPath("postgres://localhost/prana?sslmode=disable")
+
+			db, err := connector("tenant_a")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(db.DriverName()).To(Equal("postgres"))
+			Expect(db.Close()).To(Succeed())
+		})
+	})
+})
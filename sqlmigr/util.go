@@ -21,3 +21,13 @@ func RunAll(db *sqlx.DB, fileSystem FileSystem) error {
 	_, err := executor.RunAll()
 	return err
 }
+
+// Check compares the project migrations with the applied ones
+func Check(db *sqlx.DB, fileSystem FileSystem) (*CheckResult, error) {
+	checker := &Checker{
+		FileSystem: fileSystem,
+		DB:         db,
+	}
+
+	return checker.Check()
+}
@@ -49,4 +49,42 @@ var _ = Describe("Util", func() {
 			})
 		})
 	})
+	Describe("Check", func() {
+		var (
+			db *sqlx.DB
+			fs sqlmigr.FileSystem
+		)
+
+		BeforeEach(func() {
+			dir, err := ioutil.TempDir("", "prana_checker")
+			Expect(err).To(BeNil())
+
+			conn := filepath.Join(dir, "prana.db")
+			db, err = sqlx.Open("sqlite3", conn)
+			Expect(err).To(BeNil())
+
+			fs = parcello.Dir(dir)
+		})
+
+		AfterEach(func() {
+			Expect(db.Close()).To(Succeed())
+		})
+
+		It("checks the migrations successfully", func() {
+			result, err := sqlmigr.Check(db, fs)
+			Expect(err).To(Succeed())
+			Expect(result.IsUpToDate()).To(BeTrue())
+		})
+
+		Context("when the file system fails", func() {
+			BeforeEach(func() {
+				fs = parcello.Dir("/file")
+			})
+
+			It("returns an error", func() {
+				_, err := sqlmigr.Check(db, fs)
+				Expect(err).To(MatchError(os.ErrNotExist))
+			})
+		})
+	})
 })
@@ -7,6 +7,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+
+	"github.com/phogolabs/prana/sqlexec"
 )
 
 // Executor executes the schema generation
@@ -15,6 +17,10 @@ type Executor struct {
 	ModelGenerator Generator
 	// QueryGenerator is the SQL script generator
 	QueryGenerator Generator
+	// RoutineGenerator is the SQL routine functions generator
+	RoutineGenerator Generator
+	// Inspector inspects the SQL routines
+	Inspector *RoutineInspector
 	// Provider provides information the database schema
 	Provider SchemaProvider
 }
@@ -99,6 +105,68 @@ func (e *Executor) CreateScript(spec *Spec) (string, error) {
 	return filepath, nil
 }
 
+// CreateRoutine creates a file of typed functions for given SQL routines in
+// the model package
+func (e *Executor) CreateRoutine(spec *Spec, routines []*sqlexec.Routine) (string, error) {
+	schema, err := e.schemaOf(spec)
+	if err != nil {
+		return "", err
+	}
+
+	reader := &bytes.Buffer{}
+	ctx := &GeneratorContext{
+		Writer:  reader,
+		Package: spec.Name,
+		Schema:  schema,
+	}
+
+	for _, def := range routines {
+		// the statement of a template depends on its parameters, so it
+		// cannot be inspected
+		if def.IsTemplate() {
+			continue
+		}
+
+		routine, err := e.Inspector.Inspect(schema, def)
+		if err != nil {
+			return "", err
+		}
+
+		ctx.Routines = append(ctx.Routines, routine)
+	}
+
+	if err = e.RoutineGenerator.Generate(ctx); err != nil {
+		return "", err
+	}
+
+	body, _ := ioutil.ReadAll(reader)
+	if len(body) == 0 {
+		return "", nil
+	}
+
+	filepath := "routine.go"
+	if name := e.nameOf(schema); name != "" {
+		filepath = fmt.Sprintf("%s_routine.go", name)
+	}
+
+	file, err := spec.FileSystem.OpenFile(filepath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		if ioErr := file.Close(); err == nil {
+			err = ioErr
+		}
+	}()
+
+	if _, err = file.Write(body); err != nil {
+		return "", err
+	}
+
+	return filepath, nil
+}
+
 func (e *Executor) writeSchema(w io.Writer, spec *Spec) (*Schema, error) {
 	schema, err := e.schemaOf(spec)
 	if err != nil {
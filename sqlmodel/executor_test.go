@@ -6,10 +6,12 @@ import (
 	"io/ioutil"
 	"path/filepath"
 
+	"github.com/jmoiron/sqlx"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/phogolabs/parcello"
 	"github.com/phogolabs/prana/fake"
+	"github.com/phogolabs/prana/sqlexec"
 	"github.com/phogolabs/prana/sqlmodel"
 )
 
@@ -490,4 +492,120 @@ var _ = Describe("Executor", func() {
 			})
 		})
 	})
+
+	Describe("CreateRoutine", func() {
+		var routines []*sqlexec.Routine
+
+		BeforeEach(func() {
+			db, err := sqlx.Open("sqlite3", ":memory:")
+			Expect(err).To(BeNil())
+			db.SetMaxOpenConns(1)
+
+			_, err = db.Exec("CREATE TABLE table1 (ID TEXT)")
+			Expect(err).To(BeNil())
+
+			routines = []*sqlexec.Routine{
+				{Name: "delete-all", Query: "DELETE FROM table1"},
+				{Name: "select-all", Query: "SELECT * FROM table1"},
+			}
+
+			composer.GenerateStub = func(ctx *sqlmodel.GeneratorContext) error {
+				ctx.Writer.Write([]byte("source"))
+				return nil
+			}
+
+			executor.RoutineGenerator = composer
+			executor.Inspector = &sqlmodel.RoutineInspector{DB: db}
+		})
+
+		AfterEach(func() {
+			Expect(executor.Inspector.DB.Close()).To(Succeed())
+		})
+
+		ItCreatesTheRoutines := func(filename string) {
+			It("creates a file with generated routines successfully", func() {
+				path, err := executor.CreateRoutine(spec, routines)
+				Expect(err).To(Succeed())
+				Expect(path).To(Equal(filename))
+
+				dir := fmt.Sprintf("%v", spec.FileSystem)
+				Expect(filepath.Join(dir, path)).To(BeARegularFile())
+
+				Expect(composer.GenerateCallCount()).To(Equal(1))
+				ctx := composer.GenerateArgsForCall(0)
+
+				Expect(ctx.Package).To(Equal("entity"))
+				Expect(ctx.Routines).To(HaveLen(2))
+				Expect(ctx.Routines[0].Name).To(Equal("delete-all"))
+				Expect(ctx.Routines[1].Name).To(Equal("select-all"))
+			})
+		}
+
+		ItCreatesTheRoutines("routine.go")
+
+		Context("when the schema is not default", func() {
+			BeforeEach(func() {
+				schemaDef.IsDefault = false
+			})
+
+			ItCreatesTheRoutines("public_routine.go")
+		})
+
+		Context("when the routine is a template", func() {
+			BeforeEach(func() {
+				routines = append(routines, &sqlexec.Routine{Name: "search", Query: "SELECT * FROM roles {{ if .id }}WHERE id = {{ bind .id }}{{ end }}"})
+			})
+
+			ItCreatesTheRoutines("routine.go")
+		})
+
+		Context("when the routine cannot be inspected", func() {
+			BeforeEach(func() {
+				routines = append(routines, &sqlexec.Routine{Name: "select-roles", Query: "SELECT * FROM roles"})
+			})
+
+			It("returns the error", func() {
+				path, err := executor.CreateRoutine(spec, routines)
+				Expect(err).To(MatchError("routine 'select-roles': no such table: roles"))
+				Expect(path).To(BeEmpty())
+			})
+		})
+
+		Context("when getting the schema fails", func() {
+			BeforeEach(func() {
+				provider.SchemaReturns(nil, fmt.Errorf("oh no!"))
+			})
+
+			It("returns the error", func() {
+				path, err := executor.CreateRoutine(spec, routines)
+				Expect(err).To(MatchError("oh no!"))
+				Expect(path).To(BeEmpty())
+			})
+		})
+
+		Context("when the generator fails", func() {
+			BeforeEach(func() {
+				composer.GenerateReturns(fmt.Errorf("oh no!"))
+				composer.GenerateStub = nil
+			})
+
+			It("returns the error", func() {
+				path, err := executor.CreateRoutine(spec, routines)
+				Expect(err).To(MatchError("oh no!"))
+				Expect(path).To(BeEmpty())
+			})
+		})
+
+		Context("when the generator writes nothing", func() {
+			BeforeEach(func() {
+				composer.GenerateStub = nil
+			})
+
+			It("does not create a file", func() {
+				path, err := executor.CreateRoutine(spec, routines)
+				Expect(err).To(Succeed())
+				Expect(path).To(BeEmpty())
+			})
+		})
+	})
 })
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 
 var _ Generator = &ModelGenerator{}
 var _ Generator = &QueryGenerator{}
+var _ Generator = &RoutineGenerator{}
 
 // ModelGenerator generates Golang structs from database schema
 type ModelGenerator struct {
@@ -335,3 +337,350 @@ func tables(ignore []string, schema *Schema) []Table {
 	}
 	return tables
 }
+
+// RoutineGenerator generates Golang functions from SQL routines
+type RoutineGenerator struct {
+	// TagBuilder builds struct tags from column type
+	TagBuilder TagBuilder
+	// Config controls how the code generation happens
+	Config *ModelGeneratorConfig
+}
+
+// Generate generates a typed golang function for each routine
+func (g *RoutineGenerator) Generate(ctx *GeneratorContext) error {
+	if len(ctx.Routines) == 0 {
+		return nil
+	}
+
+	buffer := &bytes.Buffer{}
+	models := &ModelGenerator{
+		TagBuilder: g.TagBuilder,
+		Config:     g.Config,
+	}
+
+	if g.Config.InlcudeDoc {
+		fmt.Fprintln(buffer, "// Code generated by prana; DO NOT EDIT.")
+		fmt.Fprintln(buffer)
+		fmt.Fprintln(buffer, "// Auto-generated at", time.Now().Format(time.RFC1123))
+		fmt.Fprintln(buffer)
+	}
+
+	fmt.Fprintf(buffer, "package %s", ctx.Package)
+	fmt.Fprintln(buffer)
+	fmt.Fprintln(buffer)
+	fmt.Fprintln(buffer, `import (`)
+	fmt.Fprintln(buffer, `"context"`)
+	fmt.Fprintln(buffer, `"database/sql"`)
+	fmt.Fprintln(buffer, `"time"`)
+	fmt.Fprintln(buffer)
+	fmt.Fprintln(buffer, `"github.com/jmoiron/sqlx"`)
+	fmt.Fprintln(buffer, `)`)
+
+	for _, routine := range ctx.Routines {
+		if g.rows(routine) && routine.Table == nil && len(routine.Columns) > 1 {
+			g.writeRow(routine, models, buffer)
+		}
+
+		g.writeRoutine(routine, buffer)
+	}
+
+	if err := models.format(buffer); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(ctx.Writer, buffer)
+	return err
+}
+
+func (g *RoutineGenerator) writeRoutine(routine *Routine, buffer io.Writer) {
+	name := identifier(routine.Name, true)
+	query := identifier(routine.Name, false) + "Query"
+	result := g.resultType(routine)
+
+	fmt.Fprintln(buffer)
+	fmt.Fprintf(buffer, "const %s = %s", query, g.quote(routine.Query))
+	fmt.Fprintln(buffer)
+	fmt.Fprintln(buffer)
+
+	if g.Config.InlcudeDoc || routine.Doc != "" {
+		fmt.Fprintf(buffer, "// %s runs the SQL routine '%s'", name, routine.Name)
+		fmt.Fprintln(buffer)
+
+		for _, line := range strings.Split(routine.Doc, "\n") {
+			if line != "" {
+				fmt.Fprintln(buffer, "//", line)
+			}
+		}
+	}
+
+	fmt.Fprintf(buffer, "func %s(ctx context.Context, db sqlx.ExtContext", name)
+
+	for _, param := range g.params(routine) {
+		fmt.Fprintf(buffer, ", %s %s", param.Name, param.ScanType)
+	}
+
+	switch routine.Kind {
+	case RoutineOne:
+		fmt.Fprintf(buffer, ") (%s, error) {", g.pointer(routine, result))
+	case RoutineMany:
+		fmt.Fprintf(buffer, ") ([]%s, error) {", result)
+	case RoutineAffected:
+		fmt.Fprint(buffer, ") (int64, error) {")
+	default:
+		fmt.Fprint(buffer, ") (sql.Result, error) {")
+	}
+
+	fmt.Fprintln(buffer)
+
+	if routine.Timeout > 0 {
+		fmt.Fprintf(buffer, "ctx, cancel := context.WithTimeout(ctx, %s)", g.duration(routine.Timeout))
+		fmt.Fprintln(buffer)
+		fmt.Fprintln(buffer, "defer cancel()")
+		fmt.Fprintln(buffer)
+	}
+
+	// the functions that do not return rows have no record
+	switch {
+	case routine.Kind == RoutineMany:
+		fmt.Fprintf(buffer, "records := []%s{}", result)
+		fmt.Fprintln(buffer)
+	case g.pointer(routine, result) == result:
+		fmt.Fprintf(buffer, "var record %s", result)
+		fmt.Fprintln(buffer)
+	case routine.Kind == RoutineOne:
+		fmt.Fprintf(buffer, "record := &%s{}", result)
+		fmt.Fprintln(buffer)
+	}
+
+	g.writeArgs(routine, query, buffer)
+
+	switch routine.Kind {
+	case RoutineOne:
+		if g.pointer(routine, result) == result {
+			fmt.Fprintln(buffer, "if err := sqlx.GetContext(ctx, db, &record, query, args...); err != nil {")
+		} else {
+			fmt.Fprintln(buffer, "if err := sqlx.GetContext(ctx, db, record, query, args...); err != nil {")
+		}
+
+		fmt.Fprintf(buffer, "return %s, err", g.zero(routine, result))
+		fmt.Fprintln(buffer)
+		fmt.Fprintln(buffer, "}")
+		fmt.Fprintln(buffer, "return record, nil")
+	case RoutineMany:
+		fmt.Fprintln(buffer, "if err := sqlx.SelectContext(ctx, db, &records, query, args...); err != nil {")
+		fmt.Fprintln(buffer, "return nil, err")
+		fmt.Fprintln(buffer, "}")
+		fmt.Fprintln(buffer, "return records, nil")
+	case RoutineAffected:
+		fmt.Fprintln(buffer, "result, err := db.ExecContext(ctx, query, args...)")
+		fmt.Fprintln(buffer, "if err != nil {")
+		fmt.Fprintln(buffer, "return 0, err")
+		fmt.Fprintln(buffer, "}")
+		fmt.Fprintln(buffer, "return result.RowsAffected()")
+	default:
+		fmt.Fprintln(buffer, "return db.ExecContext(ctx, query, args...)")
+	}
+
+	fmt.Fprintln(buffer, "}")
+}
+
+func (g *RoutineGenerator) writeRow(routine *Routine, models *ModelGenerator, buffer io.Writer) {
+	typeName := g.resultType(routine)
+
+	fmt.Fprintln(buffer)
+
+	if g.Config.InlcudeDoc {
+		fmt.Fprintf(buffer, "// %s represents a result row of the SQL routine '%s'", typeName, routine.Name)
+		fmt.Fprintln(buffer)
+	}
+
+	fmt.Fprintf(buffer, "type %s struct {", typeName)
+	fmt.Fprintln(buffer)
+
+	for _, column := range routine.Columns {
+		current := column
+
+		fmt.Fprint(buffer, models.fieldName(&current))
+		fmt.Fprint(buffer, " ")
+		fmt.Fprint(buffer, models.fieldType(&current))
+		fmt.Fprint(buffer, " ")
+		fmt.Fprint(buffer, g.TagBuilder.Build(&current))
+		fmt.Fprintln(buffer)
+	}
+
+	fmt.Fprintln(buffer, "}")
+}
+
+func (g *RoutineGenerator) writeArgs(routine *Routine, query string, buffer io.Writer) {
+	params := g.params(routine)
+	lists := g.lists(routine)
+
+	switch {
+	case !routine.Named && !lists:
+		fmt.Fprintf(buffer, "query := db.Rebind(%s)", query)
+		fmt.Fprintln(buffer)
+		fmt.Fprint(buffer, "args := []interface{}{")
+
+		for index, param := range params {
+			if index > 0 {
+				fmt.Fprint(buffer, ", ")
+			}
+			fmt.Fprint(buffer, param.Name)
+		}
+
+		fmt.Fprintln(buffer, "}")
+		fmt.Fprintln(buffer)
+		return
+	case !routine.Named:
+		fmt.Fprintf(buffer, "query, args, err := sqlx.In(%s", query)
+
+		for _, param := range params {
+			fmt.Fprintf(buffer, ", %s", param.Name)
+		}
+
+		fmt.Fprintln(buffer, ")")
+	default:
+		// the lists are expanded in the question mark bind type
+		if lists {
+			fmt.Fprintf(buffer, "query, args, err := sqlx.Named(%s, map[string]interface{}{", query)
+		} else {
+			fmt.Fprintf(buffer, "query, args, err := db.BindNamed(%s, map[string]interface{}{", query)
+		}
+
+		fmt.Fprintln(buffer)
+
+		for index, param := range params {
+			fmt.Fprintf(buffer, "%q: %s,", routine.Params[index].Name, param.Name)
+			fmt.Fprintln(buffer)
+		}
+
+		fmt.Fprintln(buffer, "})")
+	}
+
+	zero := g.zero(routine, g.resultType(routine))
+
+	fmt.Fprintln(buffer, "if err != nil {")
+	fmt.Fprintf(buffer, "return %s, err", zero)
+	fmt.Fprintln(buffer)
+	fmt.Fprintln(buffer, "}")
+
+	if lists && routine.Named {
+		fmt.Fprintln(buffer, "if query, args, err = sqlx.In(query, args...); err != nil {")
+		fmt.Fprintf(buffer, "return %s, err", zero)
+		fmt.Fprintln(buffer)
+		fmt.Fprintln(buffer, "}")
+	}
+
+	if lists {
+		fmt.Fprintln(buffer, "query = db.Rebind(query)")
+	}
+
+	fmt.Fprintln(buffer)
+}
+
+// lists returns true if the routine has parameters of IN lists, which are
+// expanded to a placeholder per item
+func (g *RoutineGenerator) lists(routine *Routine) bool {
+	for _, param := range routine.Params {
+		if isList(param.ScanType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g *RoutineGenerator) params(routine *Routine) []RoutineParam {
+	params := []RoutineParam{}
+	names := make(map[string]int)
+
+	for _, param := range routine.Params {
+		name := identifier(param.Name, false)
+
+		if count := names[name]; count > 0 {
+			names[name] = count + 1
+			name = fmt.Sprintf("%s%d", name, count+1)
+		} else {
+			names[name] = 1
+		}
+
+		params = append(params, RoutineParam{
+			Name:     name,
+			ScanType: param.ScanType,
+		})
+	}
+
+	return params
+}
+
+func (g *RoutineGenerator) resultType(routine *Routine) string {
+	models := &ModelGenerator{}
+
+	switch {
+	case routine.Table != nil:
+		return models.typeName("", true, routine.Table)
+	case len(routine.Columns) == 1:
+		return routine.Columns[0].ScanType
+	default:
+		return models.typeName("", true, &Table{Name: g.rowName(routine)})
+	}
+}
+
+func (g *RoutineGenerator) rowName(routine *Routine) string {
+	name := strings.Replace(routine.Name, "-", "_", -1)
+	return fmt.Sprintf("%s_row", name)
+}
+
+// pointer returns the result type of a single row routine. The structs are
+// returned by pointer, while the scalar values by value.
+func (g *RoutineGenerator) pointer(routine *Routine, result string) string {
+	if routine.Table == nil && len(routine.Columns) == 1 {
+		return result
+	}
+	return "*" + result
+}
+
+func (g *RoutineGenerator) zero(routine *Routine, result string) string {
+	switch {
+	case routine.Kind == RoutineAffected:
+		return "0"
+	case routine.Kind == RoutineOne && g.pointer(routine, result) == result:
+		return "record"
+	default:
+		return "nil"
+	}
+}
+
+// rows returns true if the routine returns rows
+func (g *RoutineGenerator) rows(routine *Routine) bool {
+	return routine.Kind == RoutineOne || routine.Kind == RoutineMany
+}
+
+// duration returns a Golang expression of given duration
+func (g *RoutineGenerator) duration(value time.Duration) string {
+	units := []struct {
+		unit time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+		{time.Microsecond, "time.Microsecond"},
+	}
+
+	for _, item := range units {
+		if value%item.unit == 0 {
+			return fmt.Sprintf("%d * %s", value/item.unit, item.name)
+		}
+	}
+
+	return fmt.Sprintf("time.Duration(%d)", int64(value))
+}
+
+func (g *RoutineGenerator) quote(query string) string {
+	if strings.Contains(query, "`") {
+		return strconv.Quote(query)
+	}
+	return fmt.Sprintf("`%s`", query)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"go/format"
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -417,3 +418,224 @@ var _ = Describe("QueryGenerator", func() {
 		})
 	})
 })
+
+var _ = Describe("RoutineGenerator", func() {
+	var (
+		generator *sqlmodel.RoutineGenerator
+		routines  []*sqlmodel.Routine
+		table     *sqlmodel.Table
+	)
+
+	BeforeEach(func() {
+		table = &sqlmodel.Table{
+			Name: "users",
+			Columns: []sqlmodel.Column{
+				{Name: "id", ScanType: "int"},
+				{Name: "name", ScanType: "string"},
+			},
+		}
+
+		routines = []*sqlmodel.Routine{
+			{
+				Name:    "select-user",
+				Query:   "SELECT * FROM users WHERE id = ?",
+				Kind:    sqlmodel.RoutineOne,
+				Params:  []sqlmodel.RoutineParam{{Name: "id", ScanType: "int"}},
+				Columns: table.Columns,
+				Table:   table,
+			},
+			{
+				Name:    "select-all-users",
+				Query:   "SELECT * FROM users",
+				Kind:    sqlmodel.RoutineMany,
+				Columns: table.Columns,
+				Table:   table,
+			},
+			{
+				Name:  "delete-user",
+				Query: "DELETE FROM users WHERE id = :id",
+				Kind:  sqlmodel.RoutineExec,
+				Named: true,
+				Params: []sqlmodel.RoutineParam{
+					{Name: "id", ScanType: "int"},
+				},
+			},
+			{
+				Name:    "count-users",
+				Query:   "SELECT count(*) FROM users",
+				Kind:    sqlmodel.RoutineOne,
+				Columns: []sqlmodel.Column{{Name: "count(*)", ScanType: "int64"}},
+			},
+			{
+				Name:  "user-names",
+				Query: "SELECT id, name FROM users WHERE name LIKE ? OR name LIKE ?",
+				Kind:  sqlmodel.RoutineMany,
+				Params: []sqlmodel.RoutineParam{
+					{Name: "name", ScanType: "string"},
+					{Name: "name", ScanType: "string"},
+				},
+				Columns: []sqlmodel.Column{
+					{Name: "id", ScanType: "int"},
+					{Name: "name", ScanType: "string"},
+				},
+			},
+		}
+
+		generator = &sqlmodel.RoutineGenerator{
+			TagBuilder: sqlmodel.CompositeTagBuilder{
+				sqlmodel.SQLXTagBuilder{},
+			},
+			Config: &sqlmodel.ModelGeneratorConfig{
+				InlcudeDoc: false,
+			},
+		}
+	})
+
+	It("generates the routines successfully", func() {
+		reader := &bytes.Buffer{}
+		ctx := &sqlmodel.GeneratorContext{
+			Writer:   reader,
+			Package:  "model",
+			Routines: routines,
+		}
+
+		Expect(generator.Generate(ctx)).To(Succeed())
+
+		source := reader.String()
+		Expect(source).To(HavePrefix("package model"))
+		Expect(source).To(ContainSubstring("const selectUserQuery = `SELECT * FROM users WHERE id = ?`"))
+		Expect(source).To(ContainSubstring("func SelectUser(ctx context.Context, db sqlx.ExtContext, id int) (*User, error) {"))
+		Expect(source).To(ContainSubstring("sqlx.GetContext(ctx, db, record, query, args...)"))
+		Expect(source).To(ContainSubstring("func SelectAllUsers(ctx context.Context, db sqlx.ExtContext) ([]User, error) {"))
+		Expect(source).To(ContainSubstring("sqlx.SelectContext(ctx, db, &records, query, args...)"))
+		Expect(source).To(ContainSubstring("func DeleteUser(ctx context.Context, db sqlx.ExtContext, id int) (sql.Result, error) {"))
+		Expect(source).To(ContainSubstring("db.BindNamed(deleteUserQuery, map[string]interface{}{"))
+		Expect(source).To(ContainSubstring("func CountUsers(ctx context.Context, db sqlx.ExtContext) (int64, error) {"))
+		Expect(source).To(ContainSubstring("type UserNamesRow struct {"))
+		Expect(source).To(ContainSubstring("func UserNames(ctx context.Context, db sqlx.ExtContext, name string, name2 string) ([]UserNamesRow, error) {"))
+		Expect(source).NotTo(ContainSubstring("// SelectUser runs the SQL routine 'select-user'"))
+		Expect(source).To(ContainSubstring("(sql.Result, error) {\n\tquery, args, err := db.BindNamed"))
+	})
+
+	Context("when the routine has IN lists", func() {
+		BeforeEach(func() {
+			routines = []*sqlmodel.Routine{
+				{
+					Name:    "select-users-by-ids",
+					Query:   "SELECT * FROM users WHERE id IN (?)",
+					Kind:    sqlmodel.RoutineMany,
+					Params:  []sqlmodel.RoutineParam{{Name: "ids", ScanType: "[]int"}},
+					Columns: table.Columns,
+					Table:   table,
+				},
+				{
+					Name:   "delete-users-by-ids",
+					Query:  "DELETE FROM users WHERE id IN (:ids)",
+					Kind:   sqlmodel.RoutineExec,
+					Named:  true,
+					Params: []sqlmodel.RoutineParam{{Name: "ids", ScanType: "[]int"}},
+				},
+			}
+		})
+
+		It("expands the lists", func() {
+			reader := &bytes.Buffer{}
+			ctx := &sqlmodel.GeneratorContext{
+				Writer:   reader,
+				Package:  "model",
+				Routines: routines,
+			}
+
+			Expect(generator.Generate(ctx)).To(Succeed())
+
+			source := reader.String()
+			Expect(source).To(ContainSubstring("func SelectUsersByIds(ctx context.Context, db sqlx.ExtContext, ids []int) ([]User, error) {"))
+			Expect(source).To(ContainSubstring("query, args, err := sqlx.In(selectUsersByIdsQuery, ids)"))
+			Expect(source).To(ContainSubstring("query, args, err := sqlx.Named(deleteUsersByIdsQuery, map[string]interface{}{"))
+			Expect(source).To(ContainSubstring("if query, args, err = sqlx.In(query, args...); err != nil {"))
+			Expect(source).To(ContainSubstring("query = db.Rebind(query)"))
+		})
+	})
+
+	Context("when including documentation is enabled", func() {
+		BeforeEach(func() {
+			generator.Config.InlcudeDoc = true
+		})
+
+		It("generates the routines successfully", func() {
+			reader := &bytes.Buffer{}
+			ctx := &sqlmodel.GeneratorContext{
+				Writer:   reader,
+				Package:  "model",
+				Routines: routines,
+			}
+
+			Expect(generator.Generate(ctx)).To(Succeed())
+			Expect(reader.String()).To(ContainSubstring("// Code generated by prana; DO NOT EDIT."))
+			Expect(reader.String()).To(ContainSubstring("// SelectUser runs the SQL routine 'select-user'"))
+			Expect(reader.String()).To(ContainSubstring("// UserNamesRow represents a result row of the SQL routine 'user-names'"))
+		})
+	})
+
+	Context("when the routines are annotated", func() {
+		BeforeEach(func() {
+			routines = []*sqlmodel.Routine{
+				{
+					Name:    "delete-users",
+					Doc:     "deletes all users",
+					Query:   "DELETE FROM users",
+					Kind:    sqlmodel.RoutineAffected,
+					Timeout: 5 * time.Second,
+				},
+			}
+		})
+
+		It("generates the routines successfully", func() {
+			reader := &bytes.Buffer{}
+			ctx := &sqlmodel.GeneratorContext{
+				Writer:   reader,
+				Package:  "model",
+				Routines: routines,
+			}
+
+			Expect(generator.Generate(ctx)).To(Succeed())
+
+			source := reader.String()
+			Expect(source).To(ContainSubstring("// DeleteUsers runs the SQL routine 'delete-users'\n// deletes all users\nfunc DeleteUsers(ctx context.Context, db sqlx.ExtContext) (int64, error) {"))
+			Expect(source).To(ContainSubstring("ctx, cancel := context.WithTimeout(ctx, 5*time.Second)"))
+			Expect(source).To(ContainSubstring("return result.RowsAffected()"))
+		})
+	})
+
+	Context("when the query contains back quotes", func() {
+		BeforeEach(func() {
+			routines = routines[1:2]
+			routines[0].Query = "SELECT * FROM `users`"
+		})
+
+		It("quotes the query", func() {
+			reader := &bytes.Buffer{}
+			ctx := &sqlmodel.GeneratorContext{
+				Writer:   reader,
+				Package:  "model",
+				Routines: routines,
+			}
+
+			Expect(generator.Generate(ctx)).To(Succeed())
+			Expect(reader.String()).To(ContainSubstring("const selectAllUsersQuery = \"SELECT * FROM `users`\""))
+		})
+	})
+
+	Context("when no routines are provided", func() {
+		It("generates nothing", func() {
+			reader := &bytes.Buffer{}
+			ctx := &sqlmodel.GeneratorContext{
+				Writer:  reader,
+				Package: "model",
+			}
+
+			Expect(generator.Generate(ctx)).To(Succeed())
+			Expect(reader.String()).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,618 @@
+package sqlmodel
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"regexp"
+	"strings"
+
+	"github.com/go-openapi/inflect"
+	"github.com/jmoiron/sqlx"
+	"github.com/phogolabs/prana/sqlexec"
+)
+
+var (
+	tablePattern     = regexp.MustCompile(`(?i)\b(?:FROM|JOIN|INTO|UPDATE)\s+([\w."]+)`)
+	insertPattern    = regexp.MustCompile(`(?is)^\s*INSERT\s+INTO\s+[\w."]+\s*\(([^)]*)\)\s*VALUES\s*\(([^)]*)\)`)
+	paramPattern     = regexp.MustCompile(`(?is)(?:([\w."]+)\s*(?:=|<>|!=|<=|>=|<|>|\bLIKE)\s*\(?\s*|\b(LIMIT|OFFSET)\s+)$`)
+	listPattern      = regexp.MustCompile(`(?is)([\w."]+)\s+(?:NOT\s+)?IN\s*\(\s*$`)
+	namedPattern     = regexp.MustCompile(`(?:^|[^:\w]):(\w+)`)
+	rowsPattern      = regexp.MustCompile(`(?is)^\s*(?:SELECT|WITH|PRAGMA|SHOW|VALUES)\b|\bRETURNING\b`)
+	queryPattern     = regexp.MustCompile(`(?is)^\s*(?:SELECT|WITH|VALUES)\b`)
+	returningPattern = regexp.MustCompile(`(?is)\bRETURNING\s+(.+)$`)
+	aliasItemPattern = regexp.MustCompile(`(?is)^(.+?)\s+(?:AS\s+)?([\w"]+)$`)
+	idPattern        = regexp.MustCompile(`Id([A-Z]|$)`)
+	countPattern     = regexp.MustCompile(`(?is)^\s*SELECT\s+(?:COUNT|SUM|MIN|MAX|AVG)\s*\([^)]*\)(?:\s+AS\s+\w+)?\s+FROM\b`)
+	groupPattern     = regexp.MustCompile(`(?i)\bGROUP\s+BY\b`)
+	aliasPattern     = regexp.MustCompile(`(?i)\bCOUNT\s*\([^)]*\)\s+(?:AS\s+)?(\w+)`)
+)
+
+// aliases are the short type names reported by the PostgreSQL driver
+var aliases = map[string]string{
+	"int2":        "smallint",
+	"int4":        "integer",
+	"int8":        "bigint",
+	"float4":      "real",
+	"float8":      "double precision",
+	"bool":        "boolean",
+	"varchar":     "character varying",
+	"bpchar":      "character",
+	"timestamptz": "timestamp with time zone",
+	"timetz":      "time with time zone",
+}
+
+// RoutineInspector discovers the parameter and result column types of SQL
+// routines. The routines are prepared in a transaction that is rolled back,
+// but they are never executed. The result columns of a query are read from
+// the query wrapped in a SELECT that returns no rows, while the columns of a
+// RETURNING clause are resolved from the schema. PostgreSQL provides the
+// types of the parameters, which are inferred from the schema for the other
+// drivers.
+type RoutineInspector struct {
+	// DB is a connection to the database
+	DB *sqlx.DB
+}
+
+// Inspect inspects a given routine. The schema is used to resolve the types
+// of the parameters and the result columns. The annotations of the routine
+// take precedence over the inspected types.
+func (i *RoutineInspector) Inspect(schema *Schema, def *sqlexec.Routine) (*Routine, error) {
+	query := strings.TrimSuffix(strings.TrimSpace(def.Query), ";")
+	tables := i.tables(schema, query)
+
+	routine := &Routine{
+		Name:    def.Name,
+		Doc:     def.Doc,
+		Query:   query,
+		Kind:    RoutineExec,
+		Timeout: def.Timeout,
+	}
+
+	stmt, args, refs, err := i.params(routine, tables)
+	if err != nil {
+		return nil, i.errorf(routine, err)
+	}
+
+	if rowsPattern.MatchString(query) {
+		routine.Kind = RoutineMany
+	}
+
+	if def.Returns != "" {
+		routine.Kind = def.Returns
+	}
+
+	if err = i.describe(routine, stmt, args, refs, tables); err != nil {
+		return nil, i.errorf(routine, err)
+	}
+
+	if err = i.declare(routine, def.Params); err != nil {
+		return nil, i.errorf(routine, err)
+	}
+
+	routine.Table = i.match(routine.Columns, tables)
+
+	if def.Returns == "" && routine.Kind == RoutineMany && i.single(routine, tables) {
+		routine.Kind = RoutineOne
+	}
+
+	return routine, nil
+}
+
+// declare applies the declared parameters. The named parameters are matched by
+// name, while the positional ones by their position.
+func (i *RoutineInspector) declare(routine *Routine, params []sqlexec.RoutineParam) error {
+	if !routine.Named && len(params) > len(routine.Params) {
+		return fmt.Errorf("declares %d params, but the query has %d", len(params), len(routine.Params))
+	}
+
+	for index, param := range params {
+		if !routine.Named {
+			routine.Params[index] = RoutineParam{Name: param.Name, ScanType: param.Type}
+			continue
+		}
+
+		found := false
+
+		for k := range routine.Params {
+			if routine.Params[k].Name == param.Name {
+				routine.Params[k].ScanType = param.Type
+				found = true
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("declares param '%s' that is not used by the query", param.Name)
+		}
+	}
+
+	return nil
+}
+
+// params resolves the parameters of the routine. It returns the statement
+// bound for the driver, its arguments and the index of the routine parameter
+// for each placeholder.
+func (i *RoutineInspector) params(routine *Routine, tables []Table) (string, []interface{}, []int, error) {
+	names := []string{}
+	types := []string{}
+	refs := []int{}
+
+	lists := make(map[string]string)
+
+	for _, match := range namedPattern.FindAllStringSubmatchIndex(routine.Query, -1) {
+		name := routine.Query[match[2]:match[3]]
+		names = append(names, name)
+
+		// the parameter is a list if any of its placeholders is
+		if column := listPattern.FindStringSubmatch(routine.Query[:match[2]-1]); column != nil {
+			lists[name] = unquote(column[1])
+		}
+	}
+
+	if len(names) > 0 {
+		routine.Named = true
+		arg := make(map[string]interface{}, len(names))
+
+		for _, name := range names {
+			if _, ok := arg[name]; !ok {
+				kind := i.paramType(name, tables)
+
+				if column, ok := lists[name]; ok {
+					kind = i.listType(column, tables)
+				}
+
+				arg[name] = nil
+				routine.Params = append(routine.Params, RoutineParam{
+					Name:     name,
+					ScanType: kind,
+				})
+			}
+
+			for index, param := range routine.Params {
+				if param.Name == name {
+					refs = append(refs, index)
+				}
+			}
+		}
+
+		stmt, args, err := sqlx.Named(routine.Query, arg)
+		if err != nil {
+			return "", nil, nil, err
+		}
+
+		return i.DB.Rebind(stmt), args, refs, nil
+	}
+
+	positions := placeholders(routine.Query)
+
+	if match := insertPattern.FindStringSubmatch(routine.Query); len(match) == 3 {
+		columns := strings.Split(match[1], ",")
+		values := strings.Split(match[2], ",")
+
+		for index, value := range values {
+			if strings.TrimSpace(value) == "?" && index < len(columns) {
+				name := unquote(columns[index])
+				names = append(names, name)
+				types = append(types, i.paramType(name, tables))
+			}
+		}
+	}
+
+	for index := len(names); index < len(positions); index++ {
+		name := fmt.Sprintf("arg%d", index+1)
+		kind := "interface{}"
+
+		if match := listPattern.FindStringSubmatch(routine.Query[:positions[index]]); match != nil {
+			column := unquote(match[1])
+			name = inflect.Pluralize(column)
+			kind = i.listType(column, tables)
+		} else if match := paramPattern.FindStringSubmatch(routine.Query[:positions[index]]); len(match) == 3 {
+			if match[1] != "" {
+				name = unquote(match[1])
+				kind = i.paramType(name, tables)
+			} else {
+				name = strings.ToLower(match[2])
+				kind = "int"
+			}
+		}
+
+		names = append(names, name)
+		types = append(types, kind)
+	}
+
+	for index := range positions {
+		refs = append(refs, index)
+		routine.Params = append(routine.Params, RoutineParam{
+			Name:     names[index],
+			ScanType: types[index],
+		})
+	}
+
+	return i.DB.Rebind(routine.Query), make([]interface{}, len(positions)), refs, nil
+}
+
+// describe validates the statement and resolves the parameter types and the
+// result columns without executing it
+func (i *RoutineInspector) describe(routine *Routine, stmt string, args []interface{}, refs []int, tables []Table) error {
+	tx, err := i.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	prepared, err := tx.Preparex(stmt)
+	if err != nil {
+		return err
+	}
+
+	defer prepared.Close()
+
+	if i.DB.DriverName() == "postgres" {
+		if err := i.paramTypes(tx, routine, stmt, refs, tables); err != nil {
+			return err
+		}
+	}
+
+	if routine.Kind == RoutineExec || routine.Kind == RoutineAffected {
+		return nil
+	}
+
+	if match := returningPattern.FindStringSubmatch(routine.Query); match != nil && !queryPattern.MatchString(routine.Query) {
+		routine.Columns = i.returning(match[1], tables)
+		return nil
+	}
+
+	routine.Columns, err = i.columns(tx, routine, stmt, args, tables)
+	return err
+}
+
+// paramTypes reads the parameter types that PostgreSQL infers for the
+// prepared statement
+func (i *RoutineInspector) paramTypes(tx *sqlx.Tx, routine *Routine, stmt string, refs []int, tables []Table) error {
+	// the prepared statements belong to the session, so they are not
+	// discarded by the rollback
+	if _, err := tx.Exec(fmt.Sprintf("PREPARE prana_routine AS %s", stmt)); err != nil {
+		return err
+	}
+
+	defer tx.Exec("DEALLOCATE prana_routine")
+
+	query := &bytes.Buffer{}
+	query.WriteString("SELECT p.kind::text FROM pg_prepared_statements, ")
+	query.WriteString("unnest(parameter_types) WITH ORDINALITY AS p(kind, position) ")
+	query.WriteString("WHERE name = 'prana_routine' ORDER BY p.position")
+
+	types := []string{}
+
+	if err := tx.Select(&types, query.String()); err != nil {
+		return err
+	}
+
+	for position, name := range types {
+		if position >= len(refs) || name == "unknown" {
+			continue
+		}
+
+		param := &routine.Params[refs[position]]
+		columnType := &ColumnType{Name: name, Underlying: name}
+
+		// the items of a list are never null
+		if isList(param.ScanType) {
+			param.ScanType = "[]" + translate(columnType)
+			continue
+		}
+
+		if column, ok := lookup(param.Name, tables); ok {
+			columnType.IsNullable = column.Type.IsNullable
+		}
+
+		param.ScanType = translate(columnType)
+	}
+
+	return nil
+}
+
+// returning resolves the columns of a RETURNING clause from the schema
+func (i *RoutineInspector) returning(clause string, tables []Table) []Column {
+	columns := []Column{}
+
+	for _, item := range split(clause) {
+		if item == "*" {
+			if len(tables) > 0 {
+				columns = append(columns, tables[0].Columns...)
+			}
+
+			continue
+		}
+
+		name := item
+
+		if match := aliasItemPattern.FindStringSubmatch(item); match != nil {
+			name = match[2]
+			item = match[1]
+		}
+
+		if column, ok := lookup(unquote(item), tables); ok {
+			column.Name = unquote(name)
+			columns = append(columns, column)
+			continue
+		}
+
+		column := Column{
+			Name: unquote(name),
+			Type: ColumnType{IsNullable: true},
+		}
+
+		column.ScanType = translate(&column.Type)
+		columns = append(columns, column)
+	}
+
+	return columns
+}
+
+// columns reads the result columns of a query. The query is wrapped in a
+// SELECT that returns no rows, while the statements that cannot be wrapped
+// such as PRAGMA and SHOW only read metadata.
+func (i *RoutineInspector) columns(tx *sqlx.Tx, routine *Routine, stmt string, args []interface{}, tables []Table) ([]Column, error) {
+	if queryPattern.MatchString(routine.Query) {
+		stmt = fmt.Sprintf("SELECT * FROM (%s) AS q LIMIT 0", stmt)
+	}
+
+	rows, err := tx.Queryx(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := []Column{}
+
+	for _, info := range types {
+		if column, ok := lookup(info.Name(), tables); ok {
+			columns = append(columns, column)
+			continue
+		}
+
+		nullable, ok := info.Nullable()
+		name := strings.ToLower(info.DatabaseTypeName())
+
+		if alias, ok := aliases[name]; ok {
+			name = alias
+		}
+
+		if i.counter(routine.Query, info.Name()) {
+			name, nullable, ok = "bigint", false, true
+		}
+
+		column := Column{
+			Name: info.Name(),
+			Type: ColumnType{
+				Name:       name,
+				Underlying: name,
+				IsNullable: nullable || !ok,
+			},
+		}
+
+		column.ScanType = translate(&column.Type)
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+// counter returns true if the column is a result of COUNT function
+func (i *RoutineInspector) counter(query, column string) bool {
+	if strings.HasPrefix(strings.ToLower(column), "count(") {
+		return true
+	}
+
+	for _, match := range aliasPattern.FindAllStringSubmatch(query, -1) {
+		if strings.EqualFold(match[1], column) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (i *RoutineInspector) tables(schema *Schema, query string) []Table {
+	tables := []Table{}
+
+	for _, match := range tablePattern.FindAllStringSubmatch(query, -1) {
+		name := unquote(match[1])
+
+		for _, table := range schema.Tables {
+			if table.Name == name {
+				tables = append(tables, table)
+				break
+			}
+		}
+	}
+
+	return tables
+}
+
+func (i *RoutineInspector) paramType(name string, tables []Table) string {
+	if column, ok := lookup(name, tables); ok {
+		return column.ScanType
+	}
+
+	return "interface{}"
+}
+
+// listType returns the slice type of the parameter of an IN list. The items
+// of the list are never null.
+func (i *RoutineInspector) listType(name string, tables []Table) string {
+	column, ok := lookup(name, tables)
+	if !ok {
+		return "[]interface{}"
+	}
+
+	columnType := column.Type
+	columnType.IsNullable = false
+	return "[]" + translate(&columnType)
+}
+
+func (i *RoutineInspector) match(columns []Column, tables []Table) *Table {
+	if len(columns) == 0 {
+		return nil
+	}
+
+	for index, table := range tables {
+		if len(table.Columns) != len(columns) {
+			continue
+		}
+
+		matched := true
+
+		for _, column := range columns {
+			if _, ok := lookup(column.Name, []Table{table}); !ok {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return &tables[index]
+		}
+	}
+
+	return nil
+}
+
+func (i *RoutineInspector) single(routine *Routine, tables []Table) bool {
+	if insertPattern.MatchString(routine.Query) {
+		return true
+	}
+
+	// a single aggregate without grouping returns a single row
+	if countPattern.MatchString(routine.Query) && !groupPattern.MatchString(routine.Query) {
+		return true
+	}
+
+	if len(tables) != 1 {
+		return false
+	}
+
+	keys := 0
+
+	for _, column := range tables[0].Columns {
+		if !column.Type.IsPrimaryKey {
+			continue
+		}
+
+		found := false
+
+		// a list of keys matches many rows
+		for _, param := range routine.Params {
+			if param.Name == column.Name && !isList(param.ScanType) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+
+		keys++
+	}
+
+	return keys > 0
+}
+
+func (i *RoutineInspector) errorf(routine *Routine, err error) error {
+	return fmt.Errorf("routine '%s': %v", routine.Name, err)
+}
+
+// isList returns true if the parameter is a list of values
+func isList(scanType string) bool {
+	return strings.HasPrefix(scanType, "[]") && scanType != "[]byte"
+}
+
+func lookup(name string, tables []Table) (Column, bool) {
+	for _, table := range tables {
+		for _, column := range table.Columns {
+			if strings.EqualFold(column.Name, name) {
+				return column, true
+			}
+		}
+	}
+
+	return Column{}, false
+}
+
+// split splits a list of expressions by the commas that are not nested in
+// parentheses
+func split(list string) []string {
+	items := []string{}
+	depth, start := 0, 0
+
+	for index, char := range list {
+		switch char {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, strings.TrimSpace(list[start:index]))
+				start = index + 1
+			}
+		}
+	}
+
+	return append(items, strings.TrimSpace(list[start:]))
+}
+
+func placeholders(query string) []int {
+	positions := []int{}
+	quoted := false
+
+	for index, char := range query {
+		switch {
+		case char == '\'':
+			quoted = !quoted
+		case char == '?' && !quoted:
+			positions = append(positions, index)
+		}
+	}
+
+	return positions
+}
+
+func unquote(name string) string {
+	name = strings.TrimSpace(name)
+	name = strings.Replace(name, `"`, "", -1)
+
+	if index := strings.LastIndex(name, "."); index >= 0 {
+		name = name[index+1:]
+	}
+
+	return name
+}
+
+func identifier(name string, exported bool) string {
+	name = strings.Replace(name, "-", "_", -1)
+	name = strings.Replace(name, ".", "_", -1)
+
+	if exported {
+		name = inflect.Camelize(name)
+	} else {
+		name = inflect.CamelizeDownFirst(name)
+	}
+
+	name = idPattern.ReplaceAllString(name, "ID$1")
+
+	if token.Lookup(name).IsKeyword() {
+		name = name + "Param"
+	}
+
+	return name
+}
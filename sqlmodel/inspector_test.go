@@ -0,0 +1,296 @@
+package sqlmodel_test
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/prana/sqlexec"
+	"github.com/phogolabs/prana/sqlmodel"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var _ = Describe("RoutineInspector", func() {
+	var (
+		inspector *sqlmodel.RoutineInspector
+		schemaDef *sqlmodel.Schema
+	)
+
+	BeforeEach(func() {
+		db, err := sqlx.Open("sqlite3", ":memory:")
+		Expect(err).To(BeNil())
+		db.SetMaxOpenConns(1)
+
+		_, err = db.Exec("CREATE TABLE users (id INT PRIMARY KEY NOT NULL, first_name TEXT NOT NULL, last_name TEXT)")
+		Expect(err).To(BeNil())
+
+		provider := &sqlmodel.SQLiteProvider{DB: db}
+
+		schemaDef, err = provider.Schema("", "users")
+		Expect(err).To(BeNil())
+
+		inspector = &sqlmodel.RoutineInspector{DB: db}
+	})
+
+	AfterEach(func() {
+		Expect(inspector.DB.Close()).To(Succeed())
+	})
+
+	It("inspects a select routine", func() {
+		routine, err := inspector.Inspect(schemaDef, &sqlexec.Routine{Name: "select-user", Query: "SELECT * FROM users WHERE id = ?;"})
+		Expect(err).To(BeNil())
+		Expect(routine.Name).To(Equal("select-user"))
+		Expect(routine.Query).To(Equal("SELECT * FROM users WHERE id = ?"))
+		Expect(routine.Kind).To(Equal(sqlmodel.RoutineOne))
+		Expect(routine.Named).To(BeFalse())
+		Expect(routine.Params).To(Equal([]sqlmodel.RoutineParam{
+			{Name: "id", ScanType: "int"},
+		}))
+		Expect(routine.Columns).To(HaveLen(3))
+		Expect(routine.Table).NotTo(BeNil())
+		Expect(routine.Table.Name).To(Equal("users"))
+	})
+
+	It("inspects a routine with an IN list", func() {
+		routine, err := inspector.Inspect(schemaDef, &sqlexec.Routine{Name: "select-users-by-ids", Query: "SELECT * FROM users WHERE id IN (?)"})
+		Expect(err).To(BeNil())
+		Expect(routine.Kind).To(Equal(sqlmodel.RoutineMany))
+		Expect(routine.Params).To(Equal([]sqlmodel.RoutineParam{
+			{Name: "ids", ScanType: "[]int"},
+		}))
+	})
+
+	It("inspects a routine with a named IN list", func() {
+		routine, err := inspector.Inspect(schemaDef, &sqlexec.Routine{Name: "select-users-by-ids", Query: "SELECT * FROM users WHERE id IN (:id)"})
+		Expect(err).To(BeNil())
+		Expect(routine.Kind).To(Equal(sqlmodel.RoutineMany))
+		Expect(routine.Params).To(Equal([]sqlmodel.RoutineParam{
+			{Name: "id", ScanType: "[]int"},
+		}))
+	})
+
+	It("inspects a select all routine", func() {
+		routine, err := inspector.Inspect(schemaDef, &sqlexec.Routine{Name: "select-all-users", Query: "SELECT * FROM users LIMIT ?"})
+		Expect(err).To(BeNil())
+		Expect(routine.Kind).To(Equal(sqlmodel.RoutineMany))
+		Expect(routine.Params).To(Equal([]sqlmodel.RoutineParam{
+			{Name: "limit", ScanType: "int"},
+		}))
+		Expect(routine.Table.Name).To(Equal("users"))
+	})
+
+	It("inspects a routine with partial columns", func() {
+		routine, err := inspector.Inspect(schemaDef, &sqlexec.Routine{Name: "user-names", Query: "SELECT id, first_name FROM users WHERE last_name LIKE ?"})
+		Expect(err).To(BeNil())
+		Expect(routine.Kind).To(Equal(sqlmodel.RoutineMany))
+		Expect(routine.Table).To(BeNil())
+		Expect(routine.Params).To(Equal([]sqlmodel.RoutineParam{
+			{Name: "last_name", ScanType: "null.String"},
+		}))
+		Expect(routine.Columns).To(HaveLen(2))
+		Expect(routine.Columns[0].Name).To(Equal("id"))
+		Expect(routine.Columns[1].ScanType).To(Equal("string"))
+	})
+
+	It("inspects a count routine", func() {
+		routine, err := inspector.Inspect(schemaDef, &sqlexec.Routine{Name: "count-users", Query: "SELECT count(*) AS count FROM users"})
+		Expect(err).To(BeNil())
+		Expect(routine.Kind).To(Equal(sqlmodel.RoutineOne))
+		Expect(routine.Columns).To(HaveLen(1))
+		Expect(routine.Columns[0].ScanType).To(Equal("int64"))
+	})
+
+	It("inspects an insert routine", func() {
+		routine, err := inspector.Inspect(schemaDef, &sqlexec.Routine{Name: "insert-user", Query: "INSERT INTO users (id, first_name, last_name) VALUES (?, ?, ?)"})
+		Expect(err).To(BeNil())
+		Expect(routine.Kind).To(Equal(sqlmodel.RoutineExec))
+		Expect(routine.Columns).To(BeEmpty())
+		Expect(routine.Params).To(Equal([]sqlmodel.RoutineParam{
+			{Name: "id", ScanType: "int"},
+			{Name: "first_name", ScanType: "string"},
+			{Name: "last_name", ScanType: "null.String"},
+		}))
+	})
+
+	It("inspects an update routine", func() {
+		routine, err := inspector.Inspect(schemaDef, &sqlexec.Routine{Name: "update-user", Query: "UPDATE users SET first_name = ?, last_name = ? WHERE id = ?"})
+		Expect(err).To(BeNil())
+		Expect(routine.Kind).To(Equal(sqlmodel.RoutineExec))
+		Expect(routine.Params).To(Equal([]sqlmodel.RoutineParam{
+			{Name: "first_name", ScanType: "string"},
+			{Name: "last_name", ScanType: "null.String"},
+			{Name: "id", ScanType: "int"},
+		}))
+	})
+
+	It("inspects an insert routine that returns columns without executing it", func() {
+		routine, err := inspector.Inspect(schemaDef, &sqlexec.Routine{Name: "insert-user", Query: "INSERT INTO users (id, first_name) VALUES (?, ?) RETURNING id, upper(first_name) AS name"})
+		Expect(err).To(BeNil())
+		Expect(routine.Kind).To(Equal(sqlmodel.RoutineOne))
+		Expect(routine.Columns).To(HaveLen(2))
+		Expect(routine.Columns[0].Name).To(Equal("id"))
+		Expect(routine.Columns[0].ScanType).To(Equal("int"))
+		Expect(routine.Columns[1].Name).To(Equal("name"))
+		Expect(routine.Columns[1].ScanType).To(Equal("null.String"))
+
+		count := 0
+		Expect(inspector.DB.Get(&count, "SELECT count(*) FROM users")).To(Succeed())
+		Expect(count).To(BeZero())
+	})
+
+	Context("when the driver is postgres", func() {
+		var mock sqlmock.Sqlmock
+
+		BeforeEach(func() {
+			db, m, err := sqlmock.New()
+			Expect(err).To(BeNil())
+
+			Expect(inspector.DB.Close()).To(Succeed())
+
+			mock = m
+			inspector.DB = sqlx.NewDb(db, "postgres")
+		})
+
+		It("reads the parameter types from the prepared statement", func() {
+			mock.ExpectBegin()
+			mock.ExpectPrepare(`SELECT \* FROM users WHERE id = \$1 OR first_name = \$2`)
+			mock.ExpectExec(`PREPARE prana_routine AS SELECT \* FROM users WHERE id = \$1 OR first_name = \$2`).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(`SELECT p.kind::text FROM pg_prepared_statements`).
+				WillReturnRows(sqlmock.NewRows([]string{"kind"}).AddRow("bigint").AddRow("unknown"))
+			mock.ExpectExec(`DEALLOCATE prana_routine`).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(`SELECT \* FROM \(SELECT \* FROM users WHERE id = \$1 OR first_name = \$2\) AS q LIMIT 0`).
+				WithArgs(nil, nil).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "first_name", "last_name"}))
+			mock.ExpectRollback()
+
+			routine, err := inspector.Inspect(schemaDef, &sqlexec.Routine{Name: "select-user", Query: "SELECT * FROM users WHERE id = ? OR first_name = ?"})
+			Expect(err).To(BeNil())
+			Expect(routine.Params).To(Equal([]sqlmodel.RoutineParam{
+				{Name: "id", ScanType: "int64"},
+				{Name: "first_name", ScanType: "string"},
+			}))
+			Expect(routine.Columns).To(HaveLen(3))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+
+			mock.ExpectClose()
+		})
+	})
+
+	Context("when the routine has named parameters", func() {
+		It("inspects the routine", func() {
+			routine, err := inspector.Inspect(schemaDef, &sqlexec.Routine{Name: "select-user", Query: "SELECT * FROM users WHERE id = :id OR :id IS NULL"})
+			Expect(err).To(BeNil())
+			Expect(routine.Named).To(BeTrue())
+			Expect(routine.Kind).To(Equal(sqlmodel.RoutineOne))
+			Expect(routine.Params).To(Equal([]sqlmodel.RoutineParam{
+				{Name: "id", ScanType: "int"},
+			}))
+		})
+	})
+
+	Context("when the parameter cannot be resolved", func() {
+		It("uses an empty interface", func() {
+			routine, err := inspector.Inspect(schemaDef, &sqlexec.Routine{Name: "select-user", Query: "SELECT * FROM users WHERE ? IS NULL"})
+			Expect(err).To(BeNil())
+			Expect(routine.Params).To(Equal([]sqlmodel.RoutineParam{
+				{Name: "arg1", ScanType: "interface{}"},
+			}))
+		})
+	})
+
+	Context("when the routine has annotations", func() {
+		It("applies the annotations", func() {
+			routine, err := inspector.Inspect(schemaDef, &sqlexec.Routine{
+				Name:    "select-users",
+				Doc:     "returns the users by their last name",
+				Query:   "SELECT * FROM users WHERE last_name = ?",
+				Returns: sqlexec.ReturnsOne,
+				Timeout: time.Second,
+				Params: []sqlexec.RoutineParam{
+					{Name: "name", Type: "string"},
+				},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(routine.Doc).To(Equal("returns the users by their last name"))
+			Expect(routine.Kind).To(Equal(sqlmodel.RoutineOne))
+			Expect(routine.Timeout).To(Equal(time.Second))
+			Expect(routine.Params).To(Equal([]sqlmodel.RoutineParam{
+				{Name: "name", ScanType: "string"},
+			}))
+		})
+
+		Context("when the routine returns the affected rows", func() {
+			It("does not inspect the columns", func() {
+				routine, err := inspector.Inspect(schemaDef, &sqlexec.Routine{
+					Name:    "delete-users",
+					Query:   "DELETE FROM users",
+					Returns: sqlexec.ReturnsAffected,
+				})
+
+				Expect(err).To(BeNil())
+				Expect(routine.Kind).To(Equal(sqlmodel.RoutineAffected))
+				Expect(routine.Columns).To(BeEmpty())
+			})
+		})
+
+		Context("when the named param is declared", func() {
+			It("overrides its type", func() {
+				routine, err := inspector.Inspect(schemaDef, &sqlexec.Routine{
+					Name:  "select-user",
+					Query: "SELECT * FROM users WHERE id = :id",
+					Params: []sqlexec.RoutineParam{
+						{Name: "id", Type: "int64"},
+					},
+				})
+
+				Expect(err).To(BeNil())
+				Expect(routine.Params).To(Equal([]sqlmodel.RoutineParam{
+					{Name: "id", ScanType: "int64"},
+				}))
+			})
+		})
+
+		Context("when the declared named param is not used", func() {
+			It("returns an error", func() {
+				routine, err := inspector.Inspect(schemaDef, &sqlexec.Routine{
+					Name:  "select-user",
+					Query: "SELECT * FROM users WHERE id = :id",
+					Params: []sqlexec.RoutineParam{
+						{Name: "name", Type: "string"},
+					},
+				})
+
+				Expect(routine).To(BeNil())
+				Expect(err).To(MatchError("routine 'select-user': declares param 'name' that is not used by the query"))
+			})
+		})
+
+		Context("when too many params are declared", func() {
+			It("returns an error", func() {
+				routine, err := inspector.Inspect(schemaDef, &sqlexec.Routine{
+					Name:  "select-user",
+					Query: "SELECT * FROM users WHERE id = ?",
+					Params: []sqlexec.RoutineParam{
+						{Name: "id", Type: "int"},
+						{Name: "name", Type: "string"},
+					},
+				})
+
+				Expect(routine).To(BeNil())
+				Expect(err).To(MatchError("routine 'select-user': declares 2 params, but the query has 1"))
+			})
+		})
+	})
+
+	Context("when the routine is not valid", func() {
+		It("returns an error", func() {
+			routine, err := inspector.Inspect(schemaDef, &sqlexec.Routine{Name: "select-role", Query: "SELECT * FROM roles"})
+			Expect(routine).To(BeNil())
+			Expect(err).To(MatchError("routine 'select-role': no such table: roles"))
+		})
+	})
+})
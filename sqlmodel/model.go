@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/phogolabs/parcello"
 )
@@ -126,6 +127,8 @@ type GeneratorContext struct {
 	Package string
 	// Schema definition
 	Schema *Schema
+	// Routines are the inspected SQL routines
+	Routines []*Routine
 }
 
 // ModelGeneratorConfig controls how the code generation happens
@@ -152,6 +155,48 @@ type Generator interface {
 	Generate(ctx *GeneratorContext) error
 }
 
+const (
+	// RoutineExec is the kind of routine that does not return rows
+	RoutineExec = "exec"
+	// RoutineOne is the kind of routine that returns a single row
+	RoutineOne = "one"
+	// RoutineMany is the kind of routine that returns many rows
+	RoutineMany = "many"
+	// RoutineAffected is the kind of routine that returns the number of
+	// affected rows
+	RoutineAffected = "affected"
+)
+
+// Routine represents a SQL routine and its parameter and result types
+type Routine struct {
+	// Name of the routine
+	Name string
+	// Doc is the documentation of the routine
+	Doc string
+	// Query is the SQL statement of the routine
+	Query string
+	// Kind is the kind of the routine: 'exec', 'affected', 'one' or 'many'
+	Kind string
+	// Timeout is the maximum duration of the routine execution
+	Timeout time.Duration
+	// Named determines whether the routine uses named parameters
+	Named bool
+	// Params are the parameters of the routine
+	Params []RoutineParam
+	// Columns are the result columns of the routine
+	Columns []Column
+	// Table is the table whose columns match the result columns
+	Table *Table
+}
+
+// RoutineParam represents a parameter of SQL routine
+type RoutineParam struct {
+	// Name of the parameter
+	Name string
+	// ScanType is the data type of the parameter
+	ScanType string
+}
+
 // Schema represents a database schema
 type Schema struct {
 	// Name of the schema
@@ -0,0 +1,56 @@
+package sqlseed
+
+import "github.com/apex/log"
+
+// Executor provides a group of operations that works with seeds.
+type Executor struct {
+	// Logger logs each execution step
+	Logger log.Interface
+	// Provider provides all seeds for the current project.
+	Provider *Provider
+	// Runner applies the seeds for the current project.
+	Runner *Runner
+}
+
+// Seeds returns the project seeds for given environment.
+func (e *Executor) Seeds(env string) ([]*Seed, error) {
+	return e.Provider.Seeds(env)
+}
+
+// Run applies the pending and changed seeds for given environment. If force
+// is true, all seeds are applied again. It returns the number of applied
+// seeds.
+func (e *Executor) Run(env string, force bool) (int, error) {
+	run := 0
+
+	if err := e.Runner.Setup(); err != nil {
+		return run, err
+	}
+
+	seeds, err := e.Seeds(env)
+	if err != nil {
+		return run, err
+	}
+
+	for _, seed := range seeds {
+		if seed.Status() == StatusApplied && !force {
+			continue
+		}
+
+		e.logf("Running seed '%v'", seed)
+
+		if err := e.Runner.Run(seed); err != nil {
+			return run, err
+		}
+
+		run = run + 1
+	}
+
+	return run, nil
+}
+
+func (e *Executor) logf(text string, args ...interface{}) {
+	if e.Logger != nil {
+		e.Logger.Infof(text, args...)
+	}
+}
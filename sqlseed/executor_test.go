@@ -0,0 +1,124 @@
+package sqlseed_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/prana/sqlseed"
+)
+
+var _ = Describe("Executor", func() {
+	var (
+		executor *sqlseed.Executor
+		dir      string
+	)
+
+	count := func() int {
+		count := 0
+		Expect(executor.Runner.DB.Get(&count, "SELECT count(*) FROM users")).To(Succeed())
+		return count
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "prana_seed_executor")
+		Expect(err).To(BeNil())
+
+		db, err := sqlx.Open("sqlite3", filepath.Join(dir, "prana.db"))
+		Expect(err).To(BeNil())
+
+		_, err = db.Exec("CREATE TABLE users (id INT PRIMARY KEY)")
+		Expect(err).To(BeNil())
+
+		script := &bytes.Buffer{}
+		fmt.Fprintln(script, "-- name: seed")
+		fmt.Fprintln(script, "DELETE FROM users;")
+		fmt.Fprintln(script, "-- name: seed_dev")
+		fmt.Fprintln(script, "INSERT INTO users VALUES (1);")
+
+		path := filepath.Join(dir, "users.sql")
+		Expect(ioutil.WriteFile(path, script.Bytes(), 0700)).To(Succeed())
+
+		executor = &sqlseed.Executor{
+			Provider: &sqlseed.Provider{
+				FileSystem: parcello.Dir(dir),
+				DB:         db,
+			},
+			Runner: &sqlseed.Runner{
+				DB: db,
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(executor.Runner.DB.Close()).To(Succeed())
+	})
+
+	It("runs the pending seeds", func() {
+		applied, err := executor.Run("dev", false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(applied).To(Equal(1))
+		Expect(count()).To(Equal(1))
+
+		seeds, err := executor.Seeds("dev")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(seeds[0].Status()).To(Equal(sqlseed.StatusApplied))
+	})
+
+	It("skips the applied seeds", func() {
+		_, err := executor.Run("dev", false)
+		Expect(err).NotTo(HaveOccurred())
+
+		applied, err := executor.Run("dev", false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(applied).To(BeZero())
+	})
+
+	It("runs the applied seeds when forced", func() {
+		_, err := executor.Run("dev", false)
+		Expect(err).NotTo(HaveOccurred())
+
+		applied, err := executor.Run("dev", true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(applied).To(Equal(1))
+		Expect(count()).To(Equal(1))
+	})
+
+	It("runs the seeds per environment", func() {
+		_, err := executor.Run("dev", false)
+		Expect(err).NotTo(HaveOccurred())
+
+		applied, err := executor.Run("prod", false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(applied).To(Equal(1))
+		Expect(count()).To(BeZero())
+	})
+
+	Context("when the seed is changed", func() {
+		It("runs the seed again", func() {
+			_, err := executor.Run("dev", false)
+			Expect(err).NotTo(HaveOccurred())
+
+			script := &bytes.Buffer{}
+			fmt.Fprintln(script, "-- name: seed")
+			fmt.Fprintln(script, "DELETE FROM users;")
+			fmt.Fprintln(script, "-- name: seed_dev")
+			fmt.Fprintln(script, "INSERT INTO users VALUES (1), (2);")
+
+			path := filepath.Join(dir, "users.sql")
+			Expect(ioutil.WriteFile(path, script.Bytes(), 0700)).To(Succeed())
+
+			applied, err := executor.Run("dev", false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(applied).To(Equal(1))
+			Expect(count()).To(Equal(2))
+		})
+	})
+})
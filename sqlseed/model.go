@@ -0,0 +1,104 @@
+// Package sqlseed provides primitives and functions to work with SQL seed
+// data.
+package sqlseed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/prana/sqlexec"
+)
+
+var (
+	every = "sql"
+	table = "seeds"
+)
+
+const (
+	// StatusPending is the status of a seed that has not been applied.
+	StatusPending = "pending"
+	// StatusApplied is the status of a seed that has been applied.
+	StatusApplied = "applied"
+	// StatusChanged is the status of a seed that has been changed after it
+	// was applied.
+	StatusChanged = "changed"
+)
+
+// FileSystem provides with primitives to work with the underlying file system
+type FileSystem = parcello.FileSystem
+
+// Seed represents a seed data script for given environment.
+type Seed struct {
+	// ID is the name of the seed file without the driver suffix.
+	ID string
+	// Environment is the environment for which the seed is applied.
+	Environment string
+	// Checksum is the checksum of the seed statements.
+	Checksum string
+	// Filenames are the files that contain the seed statements.
+	Filenames []string
+	// Statements are the statements that seed the data.
+	Statements []string
+	// AppliedChecksum is the checksum of the statements that have been
+	// applied.
+	AppliedChecksum string
+	// AppliedAt is the time of the seed execution.
+	AppliedAt time.Time
+}
+
+// Status returns the status of the seed.
+func (s *Seed) Status() string {
+	switch {
+	case s.AppliedAt.IsZero():
+		return StatusPending
+	case s.AppliedChecksum != s.Checksum:
+		return StatusChanged
+	default:
+		return StatusApplied
+	}
+}
+
+// String returns the seed as string
+func (s *Seed) String() string {
+	return s.ID
+}
+
+// Routines returns the names of the routines that seed the data for given
+// environment. The 'seed' routine is executed for all environments, while
+// the 'seed_<environment>' routine only for the given one.
+func Routines(env string) []string {
+	routines := []string{"seed"}
+
+	if env != "" {
+		routines = append(routines, fmt.Sprintf("seed_%s", strings.ToLower(env)))
+	}
+
+	return routines
+}
+
+// Parse parses a given file path to a seed id and driver name.
+func Parse(path string) (string, string) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	driver := sqlexec.PathDriver(path)
+
+	if driver != every {
+		name = strings.TrimSuffix(name, fmt.Sprintf("_%s", driver))
+	}
+
+	return name, driver
+}
+
+func checksum(statements []string) string {
+	hash := sha256.New()
+
+	for _, statement := range statements {
+		fmt.Fprintln(hash, statement)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,79 @@
+package sqlseed_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/prana/sqlseed"
+)
+
+var _ = Describe("Model", func() {
+	Describe("Seed", func() {
+		var seed *sqlseed.Seed
+
+		BeforeEach(func() {
+			seed = &sqlseed.Seed{
+				ID:       "countries",
+				Checksum: "1234",
+			}
+		})
+
+		It("returns the id as string", func() {
+			Expect(seed.String()).To(Equal("countries"))
+		})
+
+		It("is pending", func() {
+			Expect(seed.Status()).To(Equal(sqlseed.StatusPending))
+		})
+
+		Context("when the seed is applied", func() {
+			BeforeEach(func() {
+				seed.AppliedAt = time.Now()
+				seed.AppliedChecksum = "1234"
+			})
+
+			It("is applied", func() {
+				Expect(seed.Status()).To(Equal(sqlseed.StatusApplied))
+			})
+
+			Context("when the checksum is different", func() {
+				BeforeEach(func() {
+					seed.AppliedChecksum = "4321"
+				})
+
+				It("is changed", func() {
+					Expect(seed.Status()).To(Equal(sqlseed.StatusChanged))
+				})
+			})
+		})
+	})
+
+	Describe("Routines", func() {
+		It("returns the common routine", func() {
+			Expect(sqlseed.Routines("")).To(Equal([]string{"seed"}))
+		})
+
+		Context("when the environment is provided", func() {
+			It("returns the environment routine", func() {
+				Expect(sqlseed.Routines("Dev")).To(Equal([]string{"seed", "seed_dev"}))
+			})
+		})
+	})
+
+	Describe("Parse", func() {
+		It("parses the path successfully", func() {
+			id, driver := sqlseed.Parse("/countries.sql")
+			Expect(id).To(Equal("countries"))
+			Expect(driver).To(Equal("sql"))
+		})
+
+		Context("when the path has a driver suffix", func() {
+			It("parses the path successfully", func() {
+				id, driver := sqlseed.Parse("/countries_sqlite3.sql")
+				Expect(id).To(Equal("countries"))
+				Expect(driver).To(Equal("sqlite3"))
+			})
+		})
+	})
+})
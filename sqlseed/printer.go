@@ -0,0 +1,67 @@
+package sqlseed
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/fatih/color"
+	"github.com/gosuri/uitable"
+)
+
+// Flog prints the seeds as fields
+func Flog(logger log.Interface, seeds []*Seed) {
+	for _, seed := range seeds {
+		timestamp := ""
+
+		if !seed.AppliedAt.IsZero() {
+			timestamp = seed.AppliedAt.Format(time.UnixDate)
+		}
+
+		fields := log.Fields{
+			"Id":          seed.ID,
+			"Environment": seed.Environment,
+			"Status":      seed.Status(),
+			"AppliedAt":   timestamp,
+		}
+
+		logger.WithFields(fields).Info("Seed")
+	}
+}
+
+// Ftable prints the seeds as table
+func Ftable(w io.Writer, seeds []*Seed) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+
+	table.AddRow("ID", "ENVIRONMENT", "STATUS", "APPLIED AT")
+
+	for _, seed := range seeds {
+		env := seed.Environment
+		timestamp := "--"
+
+		if env == "" {
+			env = "--"
+		}
+
+		if !seed.AppliedAt.IsZero() {
+			timestamp = seed.AppliedAt.Format(time.UnixDate)
+		}
+
+		table.AddRow(seed.ID, env, status(seed), timestamp)
+	}
+
+	fmt.Fprintln(w, table)
+}
+
+func status(seed *Seed) string {
+	switch status := seed.Status(); status {
+	case StatusApplied:
+		return color.GreenString(status)
+	case StatusChanged:
+		return color.CyanString(status)
+	default:
+		return color.YellowString(status)
+	}
+}
@@ -0,0 +1,71 @@
+package sqlseed_test
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/apex/log"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/prana/fake"
+	"github.com/phogolabs/prana/sqlseed"
+)
+
+var _ = Describe("Printer", func() {
+	var seeds []*sqlseed.Seed
+
+	BeforeEach(func() {
+		seeds = []*sqlseed.Seed{
+			{
+				ID:              "users",
+				Environment:     "dev",
+				Checksum:        "1234",
+				AppliedChecksum: "1234",
+				AppliedAt:       time.Now(),
+			},
+		}
+	})
+
+	Context("Flog", func() {
+		var logger *fake.Logger
+
+		BeforeEach(func() {
+			logger = &fake.Logger{}
+			logger.WithFieldsReturns(log.NewEntry(log.Log.(*log.Logger)))
+		})
+
+		It("logs the seed", func() {
+			sqlseed.Flog(logger, seeds)
+			Expect(logger.WithFieldsCallCount()).To(Equal(1))
+
+			fields := logger.WithFieldsArgsForCall(0)
+			Expect(fields).To(HaveKeyWithValue("Id", "users"))
+			Expect(fields).To(HaveKeyWithValue("Environment", "dev"))
+			Expect(fields).To(HaveKeyWithValue("Status", "applied"))
+		})
+	})
+
+	Context("Ftable", func() {
+		It("prints the seeds", func() {
+			w := &bytes.Buffer{}
+			sqlseed.Ftable(w, seeds)
+
+			content := w.String()
+			Expect(content).To(ContainSubstring("ENVIRONMENT"))
+			Expect(content).To(ContainSubstring("users"))
+			Expect(content).To(ContainSubstring("applied"))
+		})
+
+		Context("when the seed is pending", func() {
+			BeforeEach(func() {
+				seeds[0].AppliedAt = time.Time{}
+			})
+
+			It("prints the seeds", func() {
+				w := &bytes.Buffer{}
+				sqlseed.Ftable(w, seeds)
+				Expect(w.String()).To(ContainSubstring("pending"))
+			})
+		})
+	})
+})
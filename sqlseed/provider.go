@@ -0,0 +1,175 @@
+package sqlseed
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phogolabs/prana/sqlexec"
+	"github.com/phogolabs/prana/sqlmigr"
+)
+
+type record struct {
+	ID        string    `db:"id"`
+	Checksum  string    `db:"checksum"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Provider provides all seeds for given project.
+type Provider struct {
+	// FileSystem represents the seed directory file system.
+	FileSystem FileSystem
+	// DB is a client to underlying database.
+	DB *sqlx.DB
+}
+
+// Seeds returns the project seeds for given environment.
+func (p *Provider) Seeds(env string) ([]*Seed, error) {
+	local, err := p.files(env)
+	if err != nil {
+		return local, err
+	}
+
+	remote, err := p.query(env)
+	if err != nil {
+		return []*Seed{}, err
+	}
+
+	for _, seed := range local {
+		if r, ok := remote[seed.ID]; ok {
+			seed.AppliedChecksum = r.Checksum
+			seed.AppliedAt = r.CreatedAt
+		}
+	}
+
+	return local, nil
+}
+
+func (p *Provider) files(env string) ([]*Seed, error) {
+	files := make(map[string][]string)
+	root := true
+
+	err := p.FileSystem.Walk("/", func(path string, info os.FileInfo, err error) error {
+		if info == nil {
+			return os.ErrNotExist
+		}
+
+		if info.IsDir() {
+			if root {
+				root = false
+				return nil
+			}
+
+			return filepath.SkipDir
+		}
+
+		if matched, _ := filepath.Match("*.sql", info.Name()); !matched {
+			return nil
+		}
+
+		id, driver := Parse(path)
+
+		if driver != every && driver != p.DB.DriverName() {
+			return nil
+		}
+
+		// the statements of the common file are executed first
+		if driver == every {
+			files[id] = append([]string{path}, files[id]...)
+		} else {
+			files[id] = append(files[id], path)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return []*Seed{}, err
+	}
+
+	local := []*Seed{}
+
+	for id, filenames := range files {
+		statements, err := p.statements(env, filenames)
+		if err != nil {
+			return []*Seed{}, err
+		}
+
+		if len(statements) == 0 {
+			continue
+		}
+
+		local = append(local, &Seed{
+			ID:          id,
+			Environment: env,
+			Checksum:    checksum(statements),
+			Filenames:   filenames,
+			Statements:  statements,
+		})
+	}
+
+	sort.Slice(local, func(i, j int) bool {
+		return local[i].ID < local[j].ID
+	})
+
+	return local, nil
+}
+
+func (p *Provider) statements(env string, filenames []string) ([]string, error) {
+	statements := []string{}
+
+	for _, filename := range filenames {
+		routines, err := p.scan(filename)
+		if err != nil {
+			return []string{}, err
+		}
+
+		for _, name := range Routines(env) {
+			if query, ok := routines[name]; ok {
+				statements = append(statements, query)
+			}
+		}
+	}
+
+	return statements, nil
+}
+
+func (p *Provider) scan(filename string) (routines map[string]string, err error) {
+	file, err := p.FileSystem.OpenFile(filename, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if ioErr := file.Close(); err == nil {
+			err = ioErr
+		}
+	}()
+
+	scanner := &sqlexec.Scanner{}
+	return scanner.Scan(file), nil
+}
+
+func (p *Provider) query(env string) (map[string]*record, error) {
+	query := &bytes.Buffer{}
+	query.WriteString("SELECT id, checksum, created_at ")
+	fmt.Fprintf(query, "FROM %s ", table)
+	query.WriteString("WHERE environment = ?")
+
+	records := []*record{}
+	remote := make(map[string]*record)
+
+	if err := p.DB.Select(&records, p.DB.Rebind(query.String()), env); err != nil && !sqlmigr.IsTableNotExist(err, table) {
+		return remote, err
+	}
+
+	for _, r := range records {
+		remote[r.ID] = r
+	}
+
+	return remote, nil
+}
@@ -0,0 +1,171 @@
+package sqlseed_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/prana/sqlseed"
+)
+
+var _ = Describe("Provider", func() {
+	var (
+		provider *sqlseed.Provider
+		dir      string
+	)
+
+	write := func(name string, script *bytes.Buffer) {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, script.Bytes(), 0700)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "prana_seed_provider")
+		Expect(err).To(BeNil())
+
+		db, err := sqlx.Open("sqlite3", filepath.Join(dir, "prana.db"))
+		Expect(err).To(BeNil())
+
+		provider = &sqlseed.Provider{
+			FileSystem: parcello.Dir(dir),
+			DB:         db,
+		}
+
+		script := &bytes.Buffer{}
+		fmt.Fprintln(script, "-- name: seed")
+		fmt.Fprintln(script, "INSERT INTO users VALUES (1);")
+		fmt.Fprintln(script, "-- name: seed_dev")
+		fmt.Fprintln(script, "INSERT INTO users VALUES (2);")
+
+		write("users.sql", script)
+
+		script = &bytes.Buffer{}
+		fmt.Fprintln(script, "-- name: seed_dev")
+		fmt.Fprintln(script, "INSERT INTO users VALUES (3);")
+
+		write("users_sqlite3.sql", script)
+		write("users_postgres.sql", script)
+
+		script = &bytes.Buffer{}
+		fmt.Fprintln(script, "-- name: seed_prod")
+		fmt.Fprintln(script, "INSERT INTO countries VALUES ('BG');")
+
+		write("countries.sql", script)
+	})
+
+	AfterEach(func() {
+		Expect(provider.DB.Close()).To(Succeed())
+	})
+
+	It("returns the seeds for the environment", func() {
+		seeds, err := provider.Seeds("dev")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(seeds).To(HaveLen(1))
+
+		seed := seeds[0]
+		Expect(seed.ID).To(Equal("users"))
+		Expect(seed.Environment).To(Equal("dev"))
+		Expect(seed.Filenames).To(Equal([]string{"users.sql", "users_sqlite3.sql"}))
+		Expect(seed.Checksum).NotTo(BeEmpty())
+		Expect(seed.Status()).To(Equal(sqlseed.StatusPending))
+		Expect(seed.Statements).To(Equal([]string{
+			"INSERT INTO users VALUES (1);",
+			"INSERT INTO users VALUES (2);",
+			"INSERT INTO users VALUES (3);",
+		}))
+	})
+
+	It("returns the seeds for another environment", func() {
+		seeds, err := provider.Seeds("prod")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(seeds).To(HaveLen(2))
+		Expect(seeds[0].ID).To(Equal("countries"))
+		Expect(seeds[1].ID).To(Equal("users"))
+		Expect(seeds[1].Statements).To(Equal([]string{"INSERT INTO users VALUES (1);"}))
+		Expect(seeds[1].Checksum).NotTo(Equal(seeds[0].Checksum))
+	})
+
+	Context("when the seed is applied", func() {
+		BeforeEach(func() {
+			runner := &sqlseed.Runner{DB: provider.DB}
+			Expect(runner.Setup()).To(Succeed())
+
+			_, err := provider.DB.Exec("CREATE TABLE users (id INT)")
+			Expect(err).NotTo(HaveOccurred())
+
+			seeds, err := provider.Seeds("dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runner.Run(seeds[0])).To(Succeed())
+		})
+
+		It("returns the applied seed", func() {
+			seeds, err := provider.Seeds("dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(seeds).To(HaveLen(1))
+			Expect(seeds[0].Status()).To(Equal(sqlseed.StatusApplied))
+			Expect(seeds[0].AppliedAt).NotTo(BeZero())
+		})
+
+		It("returns pending seed for another environment", func() {
+			seeds, err := provider.Seeds("prod")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(seeds[1].Status()).To(Equal(sqlseed.StatusPending))
+		})
+
+		Context("when the seed is changed", func() {
+			BeforeEach(func() {
+				script := &bytes.Buffer{}
+				fmt.Fprintln(script, "-- name: seed_dev")
+				fmt.Fprintln(script, "INSERT INTO users VALUES (4);")
+
+				write("users_sqlite3.sql", script)
+			})
+
+			It("returns the changed seed", func() {
+				seeds, err := provider.Seeds("dev")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(seeds[0].Status()).To(Equal(sqlseed.StatusChanged))
+			})
+		})
+	})
+
+	Context("when the directory has sub-directories", func() {
+		BeforeEach(func() {
+			Expect(os.MkdirAll(filepath.Join(dir, "archive"), 0700)).To(Succeed())
+
+			script := &bytes.Buffer{}
+			fmt.Fprintln(script, "-- name: seed")
+			fmt.Fprintln(script, "INSERT INTO roles VALUES (1);")
+
+			path := filepath.Join(dir, "archive", "roles.sql")
+			Expect(ioutil.WriteFile(path, script.Bytes(), 0700)).To(Succeed())
+		})
+
+		It("skips them", func() {
+			seeds, err := provider.Seeds("dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(seeds).To(HaveLen(1))
+			Expect(seeds[0].ID).To(Equal("users"))
+		})
+	})
+
+	Context("when the directory does not exist", func() {
+		BeforeEach(func() {
+			provider.FileSystem = parcello.Dir(filepath.Join(dir, "seed"))
+		})
+
+		It("returns an error", func() {
+			seeds, err := provider.Seeds("dev")
+			Expect(seeds).To(BeEmpty())
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+})
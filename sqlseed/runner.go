@@ -0,0 +1,81 @@
+package sqlseed
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phogolabs/prana/sqlmigr"
+)
+
+// Runner applies a given seed and records it in the seeds table.
+type Runner struct {
+	// DB is a client to underlying database.
+	DB *sqlx.DB
+}
+
+// Setup creates the seeds table if it does not exist.
+func (r *Runner) Setup() error {
+	query := &bytes.Buffer{}
+	fmt.Fprintf(query, "CREATE TABLE IF NOT EXISTS %s (", table)
+	fmt.Fprintln(query)
+	fmt.Fprintln(query, " id          VARCHAR(255) NOT NULL,")
+	fmt.Fprintln(query, " environment VARCHAR(64)  NOT NULL,")
+	fmt.Fprintln(query, " checksum    VARCHAR(64)  NOT NULL,")
+	fmt.Fprintln(query, " created_at  TIMESTAMP    NOT NULL,")
+	fmt.Fprintln(query, " PRIMARY KEY (id, environment)")
+	fmt.Fprintln(query, ");")
+
+	_, err := r.DB.Exec(query.String())
+	return err
+}
+
+// Run applies a given seed. The seed statements and its record are executed
+// in a single transaction.
+func (r *Runner) Run(seed *Seed) error {
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	for _, query := range seed.Statements {
+		if _, err := tx.Exec(query); err != nil {
+			tx.Rollback()
+
+			return &sqlmigr.RunnerError{
+				Err:       err,
+				Statement: query,
+			}
+		}
+	}
+
+	if err := r.record(tx, seed); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *Runner) record(tx *sqlx.Tx, seed *Seed) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = ? AND environment = ?", table)
+
+	if _, err := tx.Exec(tx.Rebind(query), seed.ID, seed.Environment); err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	builder := &bytes.Buffer{}
+	fmt.Fprintf(builder, "INSERT INTO %s(id, environment, checksum, created_at) ", table)
+	builder.WriteString("VALUES (?, ?, ?, ?)")
+
+	if _, err := tx.Exec(tx.Rebind(builder.String()), seed.ID, seed.Environment, seed.Checksum, now); err != nil {
+		return err
+	}
+
+	seed.AppliedChecksum = seed.Checksum
+	seed.AppliedAt = now
+	return nil
+}
@@ -0,0 +1,89 @@
+package sqlseed_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/prana/sqlseed"
+)
+
+var _ = Describe("Runner", func() {
+	var (
+		runner *sqlseed.Runner
+		seed   *sqlseed.Seed
+	)
+
+	count := func(query string) int {
+		count := 0
+		Expect(runner.DB.Get(&count, query)).To(Succeed())
+		return count
+	}
+
+	BeforeEach(func() {
+		dir, err := ioutil.TempDir("", "prana_seed_runner")
+		Expect(err).To(BeNil())
+
+		db, err := sqlx.Open("sqlite3", filepath.Join(dir, "prana.db"))
+		Expect(err).To(BeNil())
+
+		_, err = db.Exec("CREATE TABLE users (id INT)")
+		Expect(err).To(BeNil())
+
+		runner = &sqlseed.Runner{DB: db}
+		Expect(runner.Setup()).To(Succeed())
+
+		seed = &sqlseed.Seed{
+			ID:          "users",
+			Environment: "dev",
+			Checksum:    "1234",
+			Statements: []string{
+				"INSERT INTO users VALUES (1);",
+				"INSERT INTO users VALUES (2);",
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(runner.DB.Close()).To(Succeed())
+	})
+
+	It("creates the seeds table once", func() {
+		Expect(runner.Setup()).To(Succeed())
+		Expect(count("SELECT count(*) FROM seeds")).To(BeZero())
+	})
+
+	It("runs the seed successfully", func() {
+		Expect(runner.Run(seed)).To(Succeed())
+		Expect(seed.Status()).To(Equal(sqlseed.StatusApplied))
+		Expect(count("SELECT count(*) FROM users")).To(Equal(2))
+		Expect(count("SELECT count(*) FROM seeds WHERE id = 'users' AND environment = 'dev'")).To(Equal(1))
+	})
+
+	It("records the seed once when it runs again", func() {
+		Expect(runner.Run(seed)).To(Succeed())
+
+		seed.Checksum = "4321"
+		Expect(runner.Run(seed)).To(Succeed())
+
+		checksum := ""
+		Expect(runner.DB.Get(&checksum, "SELECT checksum FROM seeds")).To(Succeed())
+		Expect(checksum).To(Equal("4321"))
+		Expect(count("SELECT count(*) FROM seeds")).To(Equal(1))
+	})
+
+	Context("when the statement fails", func() {
+		BeforeEach(func() {
+			seed.Statements = append(seed.Statements, "INSERT INTO roles VALUES (1);")
+		})
+
+		It("rollbacks the seed", func() {
+			Expect(runner.Run(seed)).To(MatchError("no such table: roles: INSERT INTO roles VALUES (1);"))
+			Expect(seed.Status()).To(Equal(sqlseed.StatusPending))
+			Expect(count("SELECT count(*) FROM users")).To(BeZero())
+			Expect(count("SELECT count(*) FROM seeds")).To(BeZero())
+		})
+	})
+})
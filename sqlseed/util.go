@@ -0,0 +1,19 @@
+package sqlseed
+
+import "github.com/jmoiron/sqlx"
+
+// RunAll applies all pending and changed seeds for given environment.
+func RunAll(db *sqlx.DB, fileSystem FileSystem, env string) error {
+	executor := &Executor{
+		Provider: &Provider{
+			FileSystem: fileSystem,
+			DB:         db,
+		},
+		Runner: &Runner{
+			DB: db,
+		},
+	}
+
+	_, err := executor.Run(env, false)
+	return err
+}
@@ -0,0 +1,41 @@
+package sqlseed_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/prana/sqlseed"
+)
+
+var _ = Describe("RunAll", func() {
+	It("applies all seeds", func() {
+		dir, err := ioutil.TempDir("", "prana_seed_util")
+		Expect(err).To(BeNil())
+
+		db, err := sqlx.Open("sqlite3", filepath.Join(dir, "prana.db"))
+		Expect(err).To(BeNil())
+		defer db.Close()
+
+		_, err = db.Exec("CREATE TABLE users (id INT)")
+		Expect(err).To(BeNil())
+
+		script := &bytes.Buffer{}
+		fmt.Fprintln(script, "-- name: seed")
+		fmt.Fprintln(script, "INSERT INTO users VALUES (1);")
+
+		path := filepath.Join(dir, "users.sql")
+		Expect(ioutil.WriteFile(path, script.Bytes(), 0700)).To(Succeed())
+
+		Expect(sqlseed.RunAll(db, parcello.Dir(dir), "")).To(Succeed())
+
+		count := 0
+		Expect(db.Get(&count, "SELECT count(*) FROM users")).To(Succeed())
+		Expect(count).To(Equal(1))
+	})
+})
@@ -0,0 +1,289 @@
+package sqltest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/phogolabs/prana/sqlexec"
+	"github.com/phogolabs/prana/sqlfixture"
+)
+
+// Harness runs the routines of the test specs and compares their results
+// with the golden files. The rows of a query are stored as JSON array of
+// objects and the result of a statement as JSON object with the number of
+// affected rows.
+type Harness struct {
+	// FileSystem represents the test directory file system, which contains
+	// the specs and the golden files.
+	FileSystem FileSystem
+	// Runner provides the tested routines and the database.
+	Runner *sqlexec.Runner
+	// Loader loads the fixtures of the tests. Each test runs in a
+	// transaction that is rolled back, so neither the fixtures nor the
+	// changes of the routine are kept in the database.
+	Loader *sqlfixture.Loader
+	// Update writes the actual results to the golden files instead of
+	// comparing them.
+	Update bool
+}
+
+// Run runs the tests of the specs with given names. If no names are
+// provided, it runs the specs in the file system root.
+func (h *Harness) Run(names ...string) ([]*Result, error) {
+	if len(names) == 0 {
+		var err error
+
+		if names, err = h.files(); err != nil {
+			return nil, err
+		}
+	}
+
+	provider := &sqlexec.Provider{
+		DriverName: h.Runner.DB.DriverName(),
+		Mapper:     h.Runner.DB.Mapper,
+	}
+
+	if err := provider.ReadDir(h.Runner.FileSystem); err != nil {
+		return nil, err
+	}
+
+	results := []*Result{}
+
+	for _, name := range names {
+		cases, err := h.read(name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range cases {
+			results = append(results, h.run(provider, name, item))
+		}
+	}
+
+	return results, nil
+}
+
+func (h *Harness) run(provider *sqlexec.Provider, spec string, item *Case) *Result {
+	result := &Result{
+		Spec:   spec,
+		Case:   item,
+		Golden: item.GoldenPath(spec),
+	}
+
+	if result.Actual, result.Err = h.execute(provider, item); result.Err != nil {
+		return result
+	}
+
+	if h.Update {
+		result.Err = h.write(result.Golden, result.Actual)
+		result.Updated = result.Err == nil
+		return result
+	}
+
+	expected, err := h.open(result.Golden)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = fmt.Errorf("golden file '%s' does not exist", result.Golden)
+		}
+
+		result.Err = err
+		return result
+	}
+
+	result.Expected = expected
+	result.Err = compare(result.Golden, expected, result.Actual)
+	return result
+}
+
+func (h *Harness) execute(provider *sqlexec.Provider, item *Case) ([]byte, error) {
+	args, arg, err := item.Args()
+	if err != nil {
+		return nil, err
+	}
+
+	routine, err := provider.Routine(item.Routine)
+	if err != nil {
+		return nil, err
+	}
+
+	loader := h.Loader
+
+	if loader == nil {
+		loader = &sqlfixture.Loader{DB: h.Runner.DB}
+	}
+
+	fixture := sqlfixture.Fixture{}
+
+	if len(item.Fixtures) > 0 {
+		if fixture, err = loader.Read(item.Fixtures...); err != nil {
+			return nil, err
+		}
+	}
+
+	var data []byte
+
+	// the fixtures and the changes of the routine are rolled back
+	err = loader.Try(fixture, func(tx *sqlx.Tx) error {
+		var query string
+
+		if arg != nil {
+			query, args, err = provider.BindNamed(item.Routine, arg)
+		} else {
+			query, args, err = provider.Bind(item.Routine, args...)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if routine.IsQuery() {
+			data, err = h.query(tx, query, args)
+		} else {
+			data, err = h.exec(tx, query, args)
+		}
+
+		return err
+	})
+
+	return data, err
+}
+
+func (h *Harness) exec(tx *sqlx.Tx, query string, args []sqlexec.Param) ([]byte, error) {
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the last insert id depends on the driver and the connection, so only
+	// the affected rows are compared
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf("{\"rows_affected\": %d}\n", affected)), nil
+}
+
+func (h *Harness) query(tx *sqlx.Tx, query string, args []sqlexec.Param) ([]byte, error) {
+	rows, err := tx.Queryx(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	buffer := &bytes.Buffer{}
+
+	formatter := &sqlexec.Formatter{
+		Format: sqlexec.FormatJSON,
+		Binary: sqlexec.BinaryHex,
+	}
+
+	if err := formatter.Write(buffer, rows); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func (h *Harness) files() ([]string, error) {
+	names := []string{}
+	root := true
+
+	err := h.FileSystem.Walk("/", func(path string, info os.FileInfo, err error) error {
+		if info == nil {
+			return os.ErrNotExist
+		}
+
+		if info.IsDir() {
+			if root {
+				root = false
+				return nil
+			}
+
+			return filepath.SkipDir
+		}
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yml", ".yaml", ".json":
+			names = append(names, path)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return []string{}, err
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func (h *Harness) read(name string) ([]*Case, error) {
+	data, err := h.open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(name, data)
+}
+
+func (h *Harness) open(name string) (data []byte, err error) {
+	file, err := h.FileSystem.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if ioErr := file.Close(); err == nil {
+			err = ioErr
+		}
+	}()
+
+	return ioutil.ReadAll(file)
+}
+
+func (h *Harness) write(name string, data []byte) (err error) {
+	file, err := h.FileSystem.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if ioErr := file.Close(); err == nil {
+			err = ioErr
+		}
+	}()
+
+	_, err = file.Write(data)
+	return err
+}
+
+// compare compares the JSON values, so the formatting of the golden file and
+// the order of the object keys do not matter
+func compare(golden string, expected, actual []byte) error {
+	var want, got interface{}
+
+	if err := json.Unmarshal(expected, &want); err != nil {
+		return fmt.Errorf("golden file '%s' is not valid JSON: %v", golden, err)
+	}
+
+	if err := json.Unmarshal(actual, &got); err != nil {
+		return err
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		return fmt.Errorf("result does not match golden file '%s'", golden)
+	}
+
+	return nil
+}
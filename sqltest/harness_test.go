@@ -0,0 +1,227 @@
+package sqltest_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/prana/sqlexec"
+	"github.com/phogolabs/prana/sqlfixture"
+	"github.com/phogolabs/prana/sqltest"
+)
+
+var _ = Describe("Harness", func() {
+	var (
+		harness *sqltest.Harness
+		dir     string
+	)
+
+	write := func(path, content string) {
+		path = filepath.Join(dir, path)
+		Expect(os.MkdirAll(filepath.Dir(path), 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(content), 0600)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "prana_test")
+		Expect(err).NotTo(HaveOccurred())
+
+		db, err := sqlx.Open("sqlite3", filepath.Join(dir, "prana.db"))
+		Expect(err).NotTo(HaveOccurred())
+		db.SetMaxOpenConns(1)
+
+		_, err = db.Exec("CREATE TABLE users (id INT PRIMARY KEY, name TEXT NOT NULL, active BOOLEAN)")
+		Expect(err).NotTo(HaveOccurred())
+
+		routine := &bytes.Buffer{}
+		fmt.Fprintln(routine, "-- name: select-users")
+		fmt.Fprintln(routine, "SELECT id, name FROM users WHERE active = ? ORDER BY id;")
+		fmt.Fprintln(routine)
+		fmt.Fprintln(routine, "-- name: select-user")
+		fmt.Fprintln(routine, "SELECT id, name FROM users WHERE id = :id;")
+		fmt.Fprintln(routine)
+		fmt.Fprintln(routine, "-- name: deactivate-users")
+		fmt.Fprintln(routine, "UPDATE users SET active = 0;")
+
+		write("routine/users.sql", routine.String())
+		write("fixture/users.yml", "users:\n  - id: 1\n    name: John\n    active: true\n  - id: 2\n    name: Jane\n    active: false\n")
+
+		spec := &bytes.Buffer{}
+		fmt.Fprintln(spec, "- name: active users")
+		fmt.Fprintln(spec, "  routine: select-users")
+		fmt.Fprintln(spec, "  fixtures: [users.yml]")
+		fmt.Fprintln(spec, "  params: [true]")
+		fmt.Fprintln(spec, "- name: user")
+		fmt.Fprintln(spec, "  routine: select-user")
+		fmt.Fprintln(spec, "  fixtures: [users.yml]")
+		fmt.Fprintln(spec, "  params: {id: 2}")
+		fmt.Fprintln(spec, "- name: deactivate users")
+		fmt.Fprintln(spec, "  routine: deactivate-users")
+		fmt.Fprintln(spec, "  fixtures: [users.yml]")
+
+		write("test/users.yml", spec.String())
+		write("test/users/active-users.json", `[{"name": "John", "id": 1}]`)
+		write("test/users/user.json", `[{"id": 2, "name": "Jane"}]`)
+		write("test/users/deactivate-users.json", `{"rows_affected": 2}`)
+
+		harness = &sqltest.Harness{
+			FileSystem: parcello.Dir(filepath.Join(dir, "test")),
+			Runner: &sqlexec.Runner{
+				FileSystem: parcello.Dir(filepath.Join(dir, "routine")),
+				DB:         db,
+			},
+			Loader: &sqlfixture.Loader{
+				FileSystem: parcello.Dir(filepath.Join(dir, "fixture")),
+				DB:         db,
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(harness.Runner.DB.Close()).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("runs the tests", func() {
+		results, err := harness.Run()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(HaveLen(3))
+
+		for _, result := range results {
+			Expect(result.Err).NotTo(HaveOccurred())
+			Expect(result.Status()).To(Equal(sqltest.StatusPassed))
+		}
+
+		Expect(results[0].Spec).To(Equal("users.yml"))
+		Expect(results[0].Golden).To(Equal("users/active-users.json"))
+	})
+
+	It("rolls back the fixtures and the changes after each test", func() {
+		_, err := harness.Runner.DB.Exec("INSERT INTO users VALUES (3, 'Peter', 1)")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = harness.Run("users.yml")
+		Expect(err).NotTo(HaveOccurred())
+
+		count := -1
+		Expect(harness.Runner.DB.Get(&count, "SELECT count(*) FROM users")).To(Succeed())
+		Expect(count).To(Equal(1))
+
+		active := false
+		Expect(harness.Runner.DB.Get(&active, "SELECT active FROM users WHERE id = 3")).To(Succeed())
+		Expect(active).To(BeTrue())
+	})
+
+	Context("when the loader is not provided", func() {
+		BeforeEach(func() {
+			harness.Loader = nil
+			write("test/users.yml", "- name: active users\n  routine: select-users\n  params: [true]\n")
+			write("test/users/active-users.json", `[]`)
+		})
+
+		It("runs the tests without fixtures", func() {
+			results, err := harness.Run("users.yml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Err).NotTo(HaveOccurred())
+			Expect(results[0].Status()).To(Equal(sqltest.StatusPassed))
+		})
+	})
+
+	Context("when the result does not match the golden file", func() {
+		BeforeEach(func() {
+			write("test/users/user.json", `[{"id": 1, "name": "John"}]`)
+		})
+
+		It("fails the test", func() {
+			results, err := harness.Run()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[1].Status()).To(Equal(sqltest.StatusFailed))
+			Expect(results[1].Err).To(MatchError("result does not match golden file 'users/user.json'"))
+			Expect(string(results[1].Expected)).To(ContainSubstring("John"))
+			Expect(string(results[1].Actual)).To(ContainSubstring("Jane"))
+		})
+
+		Context("when the golden files are updated", func() {
+			BeforeEach(func() {
+				harness.Update = true
+			})
+
+			It("writes the actual result", func() {
+				results, err := harness.Run()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(results[1].Status()).To(Equal(sqltest.StatusUpdated))
+
+				data, err := ioutil.ReadFile(filepath.Join(dir, "test/users/user.json"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).To(Equal("[\n  {\"id\":2,\"name\":\"Jane\"}\n]\n"))
+			})
+		})
+	})
+
+	Context("when the golden file does not exist", func() {
+		BeforeEach(func() {
+			Expect(os.Remove(filepath.Join(dir, "test/users/user.json"))).To(Succeed())
+		})
+
+		It("fails the test", func() {
+			results, err := harness.Run()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[1].Err).To(MatchError("golden file 'users/user.json' does not exist"))
+		})
+
+		Context("when the golden files are updated", func() {
+			BeforeEach(func() {
+				harness.Update = true
+			})
+
+			It("creates the golden file", func() {
+				results, err := harness.Run()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(results[1].Status()).To(Equal(sqltest.StatusUpdated))
+				Expect(filepath.Join(dir, "test/users/user.json")).To(BeAnExistingFile())
+			})
+		})
+	})
+
+	Context("when the golden file is not valid JSON", func() {
+		BeforeEach(func() {
+			write("test/users/user.json", "[")
+		})
+
+		It("fails the test", func() {
+			results, err := harness.Run()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[1].Err.Error()).To(HavePrefix("golden file 'users/user.json' is not valid JSON"))
+		})
+	})
+
+	Context("when the routine does not exist", func() {
+		BeforeEach(func() {
+			write("test/users.yml", "- name: unknown\n  routine: unknown\n")
+		})
+
+		It("fails the test", func() {
+			results, err := harness.Run()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Err).To(MatchError("query 'unknown' not found"))
+		})
+	})
+
+	Context("when the spec does not exist", func() {
+		It("returns an error", func() {
+			results, err := harness.Run("unknown.yml")
+			Expect(results).To(BeNil())
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+})
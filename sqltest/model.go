@@ -0,0 +1,141 @@
+// Package sqltest provides a harness that tests the SQL routines against
+// golden files.
+package sqltest
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/phogolabs/parcello"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// StatusPassed is the status of a test whose result matches the golden file
+	StatusPassed = "passed"
+	// StatusFailed is the status of a test that fails or whose result does
+	// not match the golden file
+	StatusFailed = "failed"
+	// StatusUpdated is the status of a test whose golden file is updated
+	StatusUpdated = "updated"
+)
+
+var separator = regexp.MustCompile(`[^a-z0-9]+`)
+
+// FileSystem provides with primitives to work with the underlying file system
+type FileSystem = parcello.FileSystem
+
+// Case is a test of a single routine execution.
+type Case struct {
+	// Name is the name of the test
+	Name string `json:"name" yaml:"name"`
+	// Routine is the name of the tested routine
+	Routine string `json:"routine" yaml:"routine"`
+	// Fixtures are the names of the fixtures loaded before the execution
+	Fixtures []string `json:"fixtures" yaml:"fixtures"`
+	// Params are the routine parameters. A list is passed as positional
+	// parameters and a map as named parameters.
+	Params interface{} `json:"params" yaml:"params"`
+	// Golden is the path of the golden file. It defaults to
+	// '<spec>/<name>.json' where the name is lower-cased and dashed.
+	Golden string `json:"golden" yaml:"golden"`
+}
+
+// Args returns the positional or the named parameters of the test.
+func (c *Case) Args() ([]interface{}, map[string]interface{}, error) {
+	switch params := c.Params.(type) {
+	case nil:
+		return nil, nil, nil
+	case []interface{}:
+		return params, nil, nil
+	case map[string]interface{}:
+		return nil, params, nil
+	case map[interface{}]interface{}:
+		arg := make(map[string]interface{}, len(params))
+
+		for key, value := range params {
+			arg[fmt.Sprint(key)] = value
+		}
+
+		return nil, arg, nil
+	default:
+		return nil, nil, fmt.Errorf("params of test '%s' should be a list or a map", c.Name)
+	}
+}
+
+// GoldenPath returns the path of the golden file for given spec.
+func (c *Case) GoldenPath(spec string) string {
+	if c.Golden != "" {
+		return c.Golden
+	}
+
+	name := separator.ReplaceAllString(strings.ToLower(c.Name), "-")
+	name = strings.Trim(name, "-")
+
+	dir := strings.TrimSuffix(spec, filepath.Ext(spec))
+	return filepath.ToSlash(filepath.Join(dir, name+".json"))
+}
+
+// Result is the result of a test.
+type Result struct {
+	// Spec is the name of the spec file
+	Spec string
+	// Case is the executed test
+	Case *Case
+	// Golden is the path of the golden file
+	Golden string
+	// Expected is the content of the golden file
+	Expected []byte
+	// Actual is the result of the routine as JSON
+	Actual []byte
+	// Updated is true when the golden file is updated
+	Updated bool
+	// Err is the error of a failed test
+	Err error
+}
+
+// Status returns the status of the test.
+func (r *Result) Status() string {
+	switch {
+	case r.Err != nil:
+		return StatusFailed
+	case r.Updated:
+		return StatusUpdated
+	default:
+		return StatusPassed
+	}
+}
+
+// Parse parses the test cases of a spec. The format is determined by the
+// file extension, which should be one of '.yml', '.yaml' or '.json'.
+func Parse(path string, data []byte) ([]*Case, error) {
+	cases := []*Case{}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yml", ".yaml":
+		if err := yaml.Unmarshal(data, &cases); err != nil {
+			return nil, err
+		}
+	case ".json":
+		if err := json.Unmarshal(data, &cases); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("spec '%s' has unsupported format '%s'", path, ext)
+	}
+
+	for index, item := range cases {
+		if item.Name == "" {
+			return nil, fmt.Errorf("test #%d of spec '%s' does not have a name", index+1, path)
+		}
+
+		if item.Routine == "" {
+			return nil, fmt.Errorf("test '%s' of spec '%s' does not have a routine", item.Name, path)
+		}
+	}
+
+	return cases, nil
+}
@@ -0,0 +1,112 @@
+package sqltest_test
+
+import (
+	"bytes"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/prana/sqltest"
+)
+
+var _ = Describe("Model", func() {
+	Describe("Parse", func() {
+		It("parses YAML spec", func() {
+			content := &bytes.Buffer{}
+			fmt.Fprintln(content, "- name: active users")
+			fmt.Fprintln(content, "  routine: select-users")
+			fmt.Fprintln(content, "  fixtures: [users.yml]")
+			fmt.Fprintln(content, "  params: [true]")
+
+			cases, err := sqltest.Parse("users.yml", content.Bytes())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cases).To(HaveLen(1))
+			Expect(cases[0].Name).To(Equal("active users"))
+			Expect(cases[0].Routine).To(Equal("select-users"))
+			Expect(cases[0].Fixtures).To(ConsistOf("users.yml"))
+		})
+
+		It("parses JSON spec", func() {
+			content := []byte(`[{"name": "user", "routine": "select-user", "params": {"id": 1}}]`)
+
+			cases, err := sqltest.Parse("users.json", content)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cases).To(HaveLen(1))
+			Expect(cases[0].Routine).To(Equal("select-user"))
+		})
+
+		Context("when the format is not supported", func() {
+			It("returns an error", func() {
+				cases, err := sqltest.Parse("users.xml", []byte{})
+				Expect(cases).To(BeNil())
+				Expect(err).To(MatchError("spec 'users.xml' has unsupported format '.xml'"))
+			})
+		})
+
+		Context("when the test does not have a name", func() {
+			It("returns an error", func() {
+				_, err := sqltest.Parse("users.json", []byte(`[{"routine": "select-user"}]`))
+				Expect(err).To(MatchError("test #1 of spec 'users.json' does not have a name"))
+			})
+		})
+
+		Context("when the test does not have a routine", func() {
+			It("returns an error", func() {
+				_, err := sqltest.Parse("users.json", []byte(`[{"name": "user"}]`))
+				Expect(err).To(MatchError("test 'user' of spec 'users.json' does not have a routine"))
+			})
+		})
+	})
+
+	Describe("Case", func() {
+		It("returns the positional params", func() {
+			item := &sqltest.Case{Params: []interface{}{1, "John"}}
+
+			args, arg, err := item.Args()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(Equal([]interface{}{1, "John"}))
+			Expect(arg).To(BeNil())
+		})
+
+		It("returns the named params", func() {
+			item := &sqltest.Case{Params: map[interface{}]interface{}{"id": 1}}
+
+			args, arg, err := item.Args()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(BeNil())
+			Expect(arg).To(Equal(map[string]interface{}{"id": 1}))
+		})
+
+		Context("when the params are not a list or a map", func() {
+			It("returns an error", func() {
+				item := &sqltest.Case{Name: "user", Params: 1}
+
+				_, _, err := item.Args()
+				Expect(err).To(MatchError("params of test 'user' should be a list or a map"))
+			})
+		})
+
+		It("returns the default golden path", func() {
+			item := &sqltest.Case{Name: "Active Users (2)"}
+			Expect(item.GoldenPath("users.yml")).To(Equal("users/active-users-2.json"))
+		})
+
+		It("returns the declared golden path", func() {
+			item := &sqltest.Case{Name: "user", Golden: "golden/user.json"}
+			Expect(item.GoldenPath("users.yml")).To(Equal("golden/user.json"))
+		})
+	})
+
+	Describe("Result", func() {
+		It("returns the status", func() {
+			result := &sqltest.Result{}
+			Expect(result.Status()).To(Equal(sqltest.StatusPassed))
+
+			result.Updated = true
+			Expect(result.Status()).To(Equal(sqltest.StatusUpdated))
+
+			result.Err = fmt.Errorf("oh no")
+			Expect(result.Status()).To(Equal(sqltest.StatusFailed))
+		})
+	})
+})
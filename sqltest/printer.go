@@ -0,0 +1,64 @@
+package sqltest
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/gosuri/uitable"
+)
+
+// Ftable prints the results as table
+func Ftable(w io.Writer, results []*Result) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+
+	table.AddRow("SPEC", "TEST", "ROUTINE", "STATUS")
+
+	for _, result := range results {
+		table.AddRow(result.Spec, result.Case.Name, result.Case.Routine, status(result))
+	}
+
+	fmt.Fprintln(w, table)
+}
+
+// Ffailures prints the errors of the failed tests together with the expected
+// and the actual results
+func Ffailures(w io.Writer, results []*Result) {
+	for _, result := range results {
+		if result.Err == nil {
+			continue
+		}
+
+		fmt.Fprintf(w, "--- FAIL: %s: %s (%s)\n", result.Spec, result.Case.Name, result.Case.Routine)
+		fmt.Fprintf(w, "    %v\n", result.Err)
+
+		if result.Expected != nil {
+			fmt.Fprintln(w, "    expected:")
+			indent(w, result.Expected)
+		}
+
+		if result.Actual != nil {
+			fmt.Fprintln(w, "    actual:")
+			indent(w, result.Actual)
+		}
+	}
+}
+
+func indent(w io.Writer, data []byte) {
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		fmt.Fprintf(w, "      %s\n", line)
+	}
+}
+
+func status(result *Result) string {
+	switch status := result.Status(); status {
+	case StatusPassed:
+		return color.GreenString(status)
+	case StatusUpdated:
+		return color.CyanString(status)
+	default:
+		return color.RedString(status)
+	}
+}
@@ -0,0 +1,60 @@
+package sqltest_test
+
+import (
+	"bytes"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/prana/sqltest"
+)
+
+var _ = Describe("Printer", func() {
+	var results []*sqltest.Result
+
+	BeforeEach(func() {
+		results = []*sqltest.Result{
+			{
+				Spec: "users.yml",
+				Case: &sqltest.Case{Name: "active users", Routine: "select-users"},
+			},
+			{
+				Spec:     "users.yml",
+				Case:     &sqltest.Case{Name: "user", Routine: "select-user"},
+				Expected: []byte("[\n  {\"id\": 1}\n]\n"),
+				Actual:   []byte("[\n  {\"id\": 2}\n]\n"),
+				Err:      fmt.Errorf("result does not match golden file 'users/user.json'"),
+			},
+		}
+	})
+
+	Describe("Ftable", func() {
+		It("prints the results", func() {
+			w := &bytes.Buffer{}
+			sqltest.Ftable(w, results)
+
+			content := w.String()
+			Expect(content).To(ContainSubstring("ROUTINE"))
+			Expect(content).To(MatchRegexp(`users.yml\s+active users\s+select-users\s+passed`))
+			Expect(content).To(MatchRegexp(`users.yml\s+user\s+select-user\s+failed`))
+		})
+	})
+
+	Describe("Ffailures", func() {
+		It("prints the failed results", func() {
+			w := &bytes.Buffer{}
+			sqltest.Ffailures(w, results)
+
+			Expect(w.String()).To(Equal("--- FAIL: users.yml: user (select-user)\n" +
+				"    result does not match golden file 'users/user.json'\n" +
+				"    expected:\n" +
+				"      [\n" +
+				"        {\"id\": 1}\n" +
+				"      ]\n" +
+				"    actual:\n" +
+				"      [\n" +
+				"        {\"id\": 2}\n" +
+				"      ]\n"))
+		})
+	})
+})